@@ -0,0 +1,512 @@
+// Package main implements the routepiler command line interface.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/backend/gosrc"
+	"github.com/cstockton/routepiler/internal/compile"
+	"github.com/cstockton/routepiler/internal/format"
+	"github.com/cstockton/routepiler/internal/graph"
+	"github.com/cstockton/routepiler/internal/introspect"
+	"github.com/cstockton/routepiler/internal/lsp"
+	"github.com/cstockton/routepiler/internal/match"
+	"github.com/cstockton/routepiler/internal/migrate"
+	"github.com/cstockton/routepiler/internal/openapi"
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/typescript"
+	"github.com/cstockton/routepiler/internal/vet"
+)
+
+const usage = `usage: routepiler <command> [flags] [args]
+
+commands:
+  gen [-dir dir] [-router name] [-o file] [-bench file]
+        generate the ServeHTTP method of the router struct, writing it to
+        file or standard output, and a test file benchmarking its routes
+  routes [-dir dir] [-router name] [-o file]
+        generate the Routes and RoutesHandler methods listing the route table
+        of the router struct at runtime, writing them to file or to
+        <router>_routes.go within dir
+  match [-dir dir] [-router name] [-H 'Name: value' ...] METHOD URL
+        print each route of the router struct considered for a request with
+        the given headers and why it did or did not match, followed by the
+        winner and its params or the response when no route serves it
+  graph [-dir dir] [-router name] [-format dot|mermaid]
+        print the prefix tree of the routes of the router struct as a
+        Graphviz DOT digraph or Mermaid flowchart, highlighting conflicts
+  openapi [-dir dir] [-router name] [-o file]
+        generate the OpenAPI 3.1 document of the routes of the router struct,
+        writing it to file as YAML when it ends in .yaml or .yml and as JSON
+        otherwise, or as JSON to standard output
+  ts [-dir dir] [-router name] [-o file]
+        generate a TypeScript client module with a typed function for each
+        route of the router struct, writing it to file or standard output
+  import [-pkg name] [-router name] [-o file] file
+        generate a router struct with a handler stub for each operation of
+        the OpenAPI 3 document in file, given as JSON or YAML, writing it to
+        file or standard output
+  migrate [-dir dir] [-router name] [-o file]
+        convert the httprouter, chi and gorilla/mux route registrations of
+        the package in dir into a router struct, writing it to file or
+        standard output, and print each registration which was not converted
+        to standard error
+  fmt [-l] [-d] [path ...]
+        rewrite the route patterns within the struct tags of each Go file,
+        or of the Go files below each directory, in canonical form; -l lists
+        the files which differ and -d prints their diffs instead
+  vet [dir ...]
+        report mistakes within the route struct tags of the package in each
+        directory, such as invalid patterns, params without a matching field
+        and handlers with an invalid signature
+  lsp
+        serve the language server protocol over standard input and output
+        for route struct tags, providing hover, go-to-definition, completion
+        and diagnostics
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "routepiler: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// stdin is read by the lsp command.
+var stdin io.Reader = os.Stdin
+
+// stderr is written by the migrate command.
+var stderr io.Writer = os.Stderr
+
+var errUsage = errors.New(`invalid usage, run routepiler help for usage`)
+
+func run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case `gen`:
+		return runGen(args[1:], w)
+	case `routes`:
+		return runRoutes(args[1:])
+	case `match`:
+		return runMatch(args[1:], w)
+	case `graph`:
+		return runGraph(args[1:], w)
+	case `openapi`:
+		return runOpenAPI(args[1:], w)
+	case `ts`:
+		return runTS(args[1:], w)
+	case `import`:
+		return runImport(args[1:], w)
+	case `migrate`:
+		return runMigrate(args[1:], w)
+	case `fmt`:
+		return runFmt(args[1:], w)
+	case `vet`:
+		return runVet(args[1:], w)
+	case `lsp`:
+		return runLSP(args[1:], w)
+	case `help`, `-h`, `-help`, `--help`:
+		_, err := io.WriteString(w, usage)
+		return err
+	}
+	return fmt.Errorf(`unknown command %q, run routepiler help for usage`, args[0])
+}
+
+func runGen(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`gen`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String(`dir`, `.`, `directory of the package declaring the router struct`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	out := fs.String(`o`, ``, `file to write, standard output when empty`)
+	bench := fs.String(`bench`, ``, `test file of benchmarks to write, none when empty`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	if *bench != `` {
+		r, err := analyze.Load(*dir, *router)
+		if err != nil {
+			return err
+		}
+		src, err := gosrc.Bench(r)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*bench, src, 0644); err != nil {
+			return err
+		}
+	}
+
+	src, err := compile.Load(*dir, *router, nil)
+	if err != nil {
+		return err
+	}
+	if *out != `` {
+		return ioutil.WriteFile(*out, src, 0644)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func runRoutes(args []string) error {
+	fs := flag.NewFlagSet(`routes`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String(`dir`, `.`, `directory of the package declaring the router struct`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	out := fs.String(`o`, ``, `file to write, <router>_routes.go within dir when empty`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	src, err := introspect.Load(*dir, *router)
+	if err != nil {
+		return err
+	}
+	if *out == `` {
+		*out = filepath.Join(*dir, strings.ToLower(*router)+`_routes.go`)
+	}
+	return ioutil.WriteFile(*out, src, 0644)
+}
+
+func runMatch(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`match`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String(`dir`, `.`, `directory of the package declaring the router struct`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	var header headers
+	fs.Var(&header, `H`, `header of the request in the form Name: value, may be repeated`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+
+	rt, err := match.Load(*dir, *router)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequest(strings.ToUpper(fs.Arg(0)), fs.Arg(1), nil)
+	if err != nil {
+		return err
+	}
+	r.Header = http.Header(header)
+	res, err := match.Trace(w, rt, r)
+	if err != nil {
+		return err
+	}
+	if res.Winner == nil && res.Redirect == `` {
+		return fmt.Errorf(`no route matched %v %v`, res.Method, res.Target)
+	}
+	return nil
+}
+
+// headers is the value of the repeatable header flag of the match command.
+type headers http.Header
+
+func (h *headers) String() string { return `` }
+
+func (h *headers) Set(v string) error {
+	i := strings.IndexByte(v, ':')
+	if i <= 0 {
+		return fmt.Errorf(`header %q must be of the form Name: value`, v)
+	}
+	if *h == nil {
+		*h = make(headers)
+	}
+	http.Header(*h).Add(strings.TrimSpace(v[:i]), strings.TrimSpace(v[i+1:]))
+	return nil
+}
+
+func runGraph(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`graph`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String(`dir`, `.`, `directory of the package declaring the router struct`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	format := fs.String(`format`, `dot`, `output format, dot or mermaid`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	tree, err := graph.Load(*dir, *router)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case `dot`:
+		return tree.DOT(w)
+	case `mermaid`:
+		return tree.Mermaid(w)
+	}
+	return fmt.Errorf(`unknown format %q, expected dot or mermaid`, *format)
+}
+
+func runOpenAPI(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`openapi`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String(`dir`, `.`, `directory of the package declaring the router struct`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	out := fs.String(`o`, ``, `file to write, standard output when empty`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	doc, err := openapi.Load(*dir, *router)
+	if err != nil {
+		return err
+	}
+	if *out != `` {
+		return openapi.WriteFile(*out, doc)
+	}
+	b, err := doc.JSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func runTS(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`ts`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String(`dir`, `.`, `directory of the package declaring the router struct`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	out := fs.String(`o`, ``, `file to write, standard output when empty`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	src, err := typescript.Load(*dir, *router)
+	if err != nil {
+		return err
+	}
+	if *out != `` {
+		return ioutil.WriteFile(*out, src, 0644)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func runImport(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`import`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	pkg := fs.String(`pkg`, `main`, `name of the package to generate`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	out := fs.String(`o`, ``, `file to write, standard output when empty`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	src, err := openapi.Import(data, *pkg, *router)
+	if err != nil {
+		return err
+	}
+	if *out != `` {
+		return ioutil.WriteFile(*out, src, 0644)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func runMigrate(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`migrate`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String(`dir`, `.`, `directory of the package registering the routes`)
+	router := fs.String(`router`, `Router`, `name of the router struct`)
+	out := fs.String(`o`, ``, `file to write, standard output when empty`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	src, diags, err := migrate.Load(*dir, *router)
+	if err != nil {
+		return err
+	}
+	for _, d := range diags {
+		fmt.Fprintln(stderr, d)
+	}
+	if *out != `` {
+		return ioutil.WriteFile(*out, src, 0644)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func runVet(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`vet`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{`.`}
+	}
+
+	var n int
+	for _, dir := range dirs {
+		fset := token.NewFileSet()
+		_, files, err := source.ParseDir(fset, dir, ``)
+		if err != nil {
+			return err
+		}
+		diags, err := vet.Check(fset, files)
+		if err != nil {
+			return err
+		}
+		for _, d := range diags {
+			fmt.Fprintf(w, "%v: %v\n", fset.Position(d.Pos), d.Message)
+		}
+		n += len(diags)
+	}
+	switch {
+	case n == 1:
+		return errors.New(`found 1 problem in route struct tags`)
+	case n > 1:
+		return fmt.Errorf(`found %d problems in route struct tags`, n)
+	}
+	return nil
+}
+
+func runLSP(args []string, w io.Writer) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	return lsp.NewServer().Serve(stdin, w)
+}
+
+func runFmt(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(`fmt`, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	list := fs.Bool(`l`, false, `list the files whose formatting differs`)
+	diff := fs.Bool(`d`, false, `print the diffs of files whose formatting differs`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{`.`}
+	}
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(name string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() || name != path && !strings.HasSuffix(name, `.go`) {
+				return err
+			}
+			return fmtFile(w, name, fi.Mode(), *list, *diff)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fmtFile formats the route patterns of a single Go file, rewriting it in
+// place unless the differences are only to be listed or printed.
+func fmtFile(w io.Writer, name string, mode os.FileMode, list, diff bool) error {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	out, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf(`%v:%v`, name, err)
+	}
+	if bytes.Equal(src, out) {
+		return nil
+	}
+	if list {
+		fmt.Fprintln(w, name)
+	}
+	if diff {
+		_, err = io.WriteString(w, lineDiff(name, src, out))
+		return err
+	}
+	if list {
+		return nil
+	}
+	return ioutil.WriteFile(name, out, mode)
+}
+
+// lineDiff returns a unified diff of the lines of a and b without context,
+// where formatting changes only struct tags and so lines pair up one to one.
+// Any other change is shown as a single hunk between the common prefix and
+// suffix of lines.
+func lineDiff(name string, a, b []byte) string {
+	al := strings.SplitAfter(string(a), "\n")
+	bl := strings.SplitAfter(string(b), "\n")
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %v\n+++ %v\n", name, name)
+	hunk := func(ai, aj, bi, bj int) {
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", ai+1, aj-ai, bi+1, bj-bi)
+		for _, l := range al[ai:aj] {
+			buf.WriteString(`-` + strings.TrimSuffix(l, "\n") + "\n")
+		}
+		for _, l := range bl[bi:bj] {
+			buf.WriteString(`+` + strings.TrimSuffix(l, "\n") + "\n")
+		}
+	}
+	if len(al) != len(bl) {
+		beg, end := 0, 0
+		for beg < len(al) && beg < len(bl) && al[beg] == bl[beg] {
+			beg++
+		}
+		for end < len(al)-beg && end < len(bl)-beg &&
+			al[len(al)-1-end] == bl[len(bl)-1-end] {
+			end++
+		}
+		hunk(beg, len(al)-end, beg, len(bl)-end)
+		return buf.String()
+	}
+	for i := 0; i < len(al); i++ {
+		j := i
+		for j < len(al) && al[j] != bl[j] {
+			j++
+		}
+		if j > i {
+			hunk(i, j, i, j)
+			i = j
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	const (
+		dir      = `../../internal/match/testdata/router`
+		graphDir = `../../internal/graph/testdata/router`
+		genDir   = `../../internal/backend/gosrc/testdata/router`
+		docDir   = `../../internal/testdata/router`
+		oldDir   = `../../internal/migrate/testdata/routes`
+	)
+	benchFile := filepath.Join(t.TempDir(), `routes_test.go`)
+	docFile := filepath.Join(t.TempDir(), `openapi.yaml`)
+	importFile := filepath.Join(t.TempDir(), `router.go`)
+	tsFile := filepath.Join(t.TempDir(), `client.ts`)
+
+	vetDir := t.TempDir()
+	const vetSrc = "package main\n\ntype Router struct {\n\tUser Users `get:\"/users/:id\"`\n}\n\n" +
+		"type Users struct{ Name string }\n"
+	if err := ioutil.WriteFile(filepath.Join(vetDir, `router.go`), []byte(vetSrc), 0644); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	routesDir := t.TempDir()
+	routerSrc, err := ioutil.ReadFile(filepath.Join(docDir, `router.go`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(routesDir, `router.go`), routerSrc, 0644); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	routesFile := filepath.Join(t.TempDir(), `routes.go`)
+
+	const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	defer func(r io.Reader) { stdin = r }(stdin)
+	defer func(w io.Writer) { stderr = w }(stderr)
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	stdin = strings.NewReader(fmt.Sprintf("Content-Length: %d\r\n\r\n%s",
+		len(initialize), initialize))
+
+	tests := []struct {
+		args []string
+		exp  string // contained by the output, or the error when prefixed by !
+	}{
+		{[]string{`help`}, `match [-dir dir] [-router name] [-H 'Name: value' ...] METHOD URL`},
+		{[]string{`help`}, `fmt [-l] [-d] [path ...]`},
+		{[]string{`fmt`, `-bogus`}, `!flag provided but not defined: -bogus`},
+		{[]string{`lsp`}, `"result":{"capabilities":{`},
+		{[]string{`lsp`, `x`}, `!invalid usage`},
+		{[]string{`routes`, `-dir`, routesDir}, ``},
+		{[]string{`routes`, `-dir`, routesDir}, ``}, // regenerated alongside the router
+		{[]string{`routes`, `-dir`, docDir, `-o`, routesFile}, ``},
+		{[]string{`routes`, `-dir`, docDir, `-router`, `Bogus`},
+			`!router struct Bogus not found in ` + docDir},
+		{[]string{`routes`, `-dir`, docDir, `x`}, `!invalid usage`},
+		{[]string{`match`, `-dir`, dir, `GET`, `/users/7`},
+			`winner: GET /users/:id([0-9]+) (Users.Get)`},
+		{[]string{`match`, `-dir`, dir, `-router`, `Router`, `GET`, `/files/a`},
+			`║   2 │ GET /files/:path*`},
+		{[]string{`match`, `-dir`, dir, `get`, `/items`}, `winner: GET /items (Items.Get)`},
+		{[]string{`match`, `-dir`, dir, `-H`, `X-Api-Version: 2`, `GET`, `/items`},
+			`winner: GET /items (GetV2)`},
+		{[]string{`match`, `-dir`, dir, `-H`, `Accept: text/html`, `GET`, `/items`},
+			`!no route matched GET /items`},
+		{[]string{`match`, `-dir`, dir, `-H`, `Accept`, `GET`, `/items`},
+			`!invalid value "Accept" for flag -H: header "Accept" must be of the form Name: value`},
+		{[]string{`match`, `-dir`, dir, `PUT`, `/files/a`}, `!no route matched PUT /files/a`},
+		{[]string{`match`, `-dir`, dir, `-router`, `Bogus`, `GET`, `/`},
+			`!router struct Bogus not found in ` + dir},
+		{[]string{`match`, `GET`}, `!invalid usage`},
+		{[]string{`match`, `-bogus`}, `!flag provided but not defined: -bogus`},
+		{[]string{`gen`, `-dir`, genDir},
+			"func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {\n"},
+		{[]string{`gen`, `-dir`, genDir, `-bench`, benchFile},
+			"func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {\n"},
+		{[]string{`gen`, `-dir`, genDir, `-router`, `Bogus`},
+			`!router struct Bogus not found in ` + genDir},
+		{[]string{`gen`, `-dir`, genDir, `x`}, `!invalid usage`},
+		{[]string{`graph`, `-dir`, graphDir}, "digraph routes {\n"},
+		{[]string{`graph`, `-dir`, graphDir, `-format`, `mermaid`}, "flowchart LR\n"},
+		{[]string{`graph`, `-dir`, graphDir, `-format`, `svg`}, `!unknown format "svg"`},
+		{[]string{`graph`, `-dir`, graphDir, `x`}, `!invalid usage`},
+		{[]string{`openapi`, `-dir`, docDir}, `"/archive/{year}/{month}": {`},
+		{[]string{`openapi`, `-dir`, docDir, `-o`, docFile}, ``},
+		{[]string{`openapi`, `-dir`, docDir, `-router`, `Bogus`},
+			`!router struct Bogus not found in ` + docDir},
+		{[]string{`openapi`, `-dir`, docDir, `x`}, `!invalid usage`},
+		{[]string{`ts`, `-dir`, docDir}, `export class ValidationError extends Error {`},
+		{[]string{`ts`, `-dir`, docDir, `-o`, tsFile}, ``},
+		{[]string{`ts`, `-dir`, docDir, `-router`, `Bogus`},
+			`!router struct Bogus not found in ` + docDir},
+		{[]string{`ts`, `-dir`, docDir, `x`}, `!invalid usage`},
+		{[]string{`import`, `../../internal/openapi/testdata/openapi.yaml`},
+			"`get:\"/archive/:year/:month/:day\"`"},
+		{[]string{`import`, `-pkg`, `api`, `-router`, `API`, `-o`, importFile, docFile}, ``},
+		{[]string{`import`, `bogus.json`}, `!open bogus.json: no such file or directory`},
+		{[]string{`import`}, `!invalid usage`},
+		{[]string{`migrate`, `-dir`, oldDir},
+			"List http.HandlerFunc `get:\"/orgs\"`"},
+		{[]string{`migrate`, `-dir`, oldDir, `-router`, `Bogus`}, `type Bogus struct {`},
+		{[]string{`migrate`, `-dir`, oldDir, `x`}, `!invalid usage`},
+		{[]string{`vet`, genDir, dir}, ``},
+		{[]string{`vet`, vetDir}, `!found 1 problem in route struct tags`},
+		{[]string{`vet`, `-bogus`}, `!flag provided but not defined: -bogus`},
+		{[]string{`bogus`}, `!unknown command "bogus"`},
+		{nil, `!invalid usage`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp run of %v to produce %q`, idx, test.args, test.exp)
+
+		var buf bytes.Buffer
+		err := run(test.args, &buf)
+		got := buf.String()
+		if strings.HasPrefix(test.exp, `!`) {
+			if err == nil {
+				t.Fatal(`exp non-nil err`)
+			}
+			got = `!` + err.Error()
+		} else if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if !strings.Contains(got, test.exp) {
+			t.Fatalf("exp output to contain:\n%v\ngot:\n%v", test.exp, got)
+		}
+	}
+
+	src, err := ioutil.ReadFile(benchFile)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if exp := `func BenchmarkRouter(b *testing.B) {`; !strings.Contains(string(src), exp) {
+		t.Fatalf("exp bench file to contain:\n%v\ngot:\n%s", exp, src)
+	}
+
+	doc, err := ioutil.ReadFile(docFile)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if exp := "  /archive/{year}:\n"; !strings.Contains(string(doc), exp) {
+		t.Fatalf("exp openapi file to contain:\n%v\ngot:\n%s", exp, doc)
+	}
+
+	table, err := ioutil.ReadFile(`../../internal/introspect/testdata/routes.golden`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	for _, name := range []string{filepath.Join(routesDir, `router_routes.go`), routesFile} {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if string(table) != string(src) {
+			t.Fatalf("exp routes file %v:\n%s\ngot:\n%s", name, table, src)
+		}
+	}
+
+	ts, err := ioutil.ReadFile(tsFile)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	want, err := ioutil.ReadFile(`../../internal/typescript/testdata/client.ts`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if string(want) != string(ts) {
+		t.Fatalf("exp ts file:\n%s\ngot:\n%s", want, ts)
+	}
+
+	exp := oldDir + "/gorilla.go:20:2: Host matchers are not converted\n"
+	if got := errBuf.String(); !strings.Contains(got, exp) {
+		t.Fatalf("exp migrate diagnostics to contain:\n%v\ngot:\n%v", exp, got)
+	}
+
+	src, err = ioutil.ReadFile(importFile)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if exp := "package api\n"; !strings.Contains(string(src), exp) {
+		t.Fatalf("exp import file to contain:\n%v\ngot:\n%s", exp, src)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	const (
+		src = "package main\n\n// Router routes.\ntype Router struct {\n" +
+			"\tUsers Users `get:\"/users/{ id }\"` // users\n" +
+			"\tTeams Teams `get:\"/teams/:team\"`\n" +
+			"\tFiles Files `get:\"/files/:name{min:2}\"`\n}\n"
+		exp = "package main\n\n// Router routes.\ntype Router struct {\n" +
+			"\tUsers Users `get:\"/users/{id}\"` // users\n" +
+			"\tTeams Teams `get:\"/teams/:team\"`\n" +
+			"\tFiles Files `get:\"/files/:name{min: 2}\"`\n}\n"
+	)
+	dir := t.TempDir()
+	name := filepath.Join(dir, `router.go`)
+	if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	tests := []struct {
+		args []string
+		exp  string
+	}{
+		{[]string{`fmt`, `-l`, dir}, name + "\n"},
+		{[]string{`fmt`, `-d`, name}, "--- " + name + "\n+++ " + name + "\n" +
+			"@@ -5,1 +5,1 @@\n" +
+			"-\tUsers Users `get:\"/users/{ id }\"` // users\n" +
+			"+\tUsers Users `get:\"/users/{id}\"` // users\n" +
+			"@@ -7,1 +7,1 @@\n" +
+			"-\tFiles Files `get:\"/files/:name{min:2}\"`\n" +
+			"+\tFiles Files `get:\"/files/:name{min: 2}\"`\n"},
+		{[]string{`fmt`, dir}, ``},
+		{[]string{`fmt`, `-l`, dir}, ``},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp run of %v to produce %q`, idx, test.args, test.exp)
+
+		var buf bytes.Buffer
+		if err := run(test.args, &buf); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp, got := test.exp, buf.String(); exp != got {
+			t.Fatalf("exp output:\n%v\ngot:\n%v", exp, got)
+		}
+	}
+
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if string(got) != exp {
+		t.Fatalf("exp formatted file:\n%v\ngot:\n%s", exp, got)
+	}
+
+	bad := filepath.Join(dir, `bad.go`)
+	if err := ioutil.WriteFile(bad, []byte(strings.Replace(src, `{ id }`, `:a:b`, 1)), 0644); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	err = run([]string{`fmt`, bad}, ioutil.Discard)
+	if exp := bad + `:5:14: get tag: adjacent`; err == nil || !strings.HasPrefix(err.Error(), exp) {
+		t.Fatalf(`exp err with prefix %v; got %v`, exp, err)
+	}
+}
@@ -0,0 +1,1287 @@
+// Package analyze runs the validation & scoring heuristics of each route
+// compiler to select the best code generation method for that route.
+//
+// Analysis resolves the handler of each route of a router struct along with the
+// struct field each param is bound to, verifying the param values may be
+// converted to the field types. Routes are then ordered by precedence, where the
+// first route to match a request serves it. Segments are compared from the
+// left, where a static segment beats one containing a literal or regexp, which
+// beats a lone param, which beats a wildcard. Segments of the same rank are
+// ordered by their shape, so routes sharing a prefix are adjacent, then routes
+// with a http method beat those without, then routes with predicates on the
+// headers of a request beat those without, and otherwise the first declared
+// wins.
+//
+// A pattern with optional params, such as /reports/:year/:month?, is expanded
+// into a route for the path as declared followed by a route omitting each
+// trailing segment holding an optional param in turn. The field of an omitted
+// param is given the default of the param, such as :month?{default: 1}, and is
+// otherwise left unset. An expanded route matching the same requests as another
+// route is an error reported at the position of the pattern it expanded from.
+//
+// Middleware are declared by the use key of a tag, such as use:"auth,audit",
+// naming methods of the router struct of type func(http.Handler) http.Handler.
+// The use key of a blank field of the router struct applies to each route, of
+// a blank field of a handler struct to each route of that type, and of a route
+// field to its own routes, wrapping the handler in that order outermost first.
+//
+// Predicates are declared by the accept, consumes and header keys of a route
+// field tag, such as accept:"application/json" or header:"X-API-Version: 2",
+// and are evaluated once the method and path of a route match. When each route
+// matching the path rejects a request by its Content-Type or Accept header the
+// response is 415 Unsupported Media Type or 406 Not Acceptable respectively.
+//
+// Routes are found by trying each in order of precedence, or once a router has
+// RadixRoutes routes by walking a prefix tree of their static segments which
+// selects the routes that are tried. The strategy key of the options of the
+// router struct, such as strategy:"radix", selects one explicitly.
+//
+// Paths are compared byte for byte unless the match key of the options of the
+// router struct names runes or nfc, such as match:"runes,nfc". Runes counts the
+// min and max of params in runes and requires the value of a param with bounds
+// or a regexp to be valid UTF-8, while nfc normalizes each segment of the path
+// to Unicode NFC before matching. The generated router then imports the package
+// golang.org/x/text/unicode/norm, so the module of the router must require the
+// golang.org/x/text module.
+//
+// The case key is exact, fold or redirect, where static segments match the same
+// bytes when exact or absent, match regardless of case, or also redirect the
+// request to the casing of the route. The case key of the options of the router
+// struct applies to each route, and of a route field to its own routes. Static
+// segments of ASCII fold only ASCII letters, while others fold under Unicode.
+//
+// The percent-encodings of a path are made canonical before it is matched, as
+// the literals of routes are by Escape. The clean key of the options of the
+// router struct is strict, redirect or lenient, where a path with repeated
+// slashes, dot segments or a trailing slash is matched as is when strict or
+// absent, redirected to its clean form, or matched in its clean form. Routes
+// with a trailing slash may not be declared unless strict. The slash key is
+// keep, reject or decode, where a percent-encoded slash is part of its segment
+// when keep or absent, rejects the request, or separates segments.
+//
+// The value of the struct type of a route field is the zero value for each
+// request, or taken from a sync.Pool when the pool key of the options of the
+// router struct or the struct type is true, such as pool:"true". Pooled values
+// are set to their zero value once served, or given to their Reset method when
+// the struct type declares one.
+//
+// Errors returned by handlers, along with param values which can not be
+// converted to their fields, are given to the ErrorHandler method or func field
+// of the router struct when it declares one.
+//
+// Handler funcs may declare params following the http.ResponseWriter and
+// *http.Request, which are injected by type. A context.Context is the context
+// of the request and a pointer to the router struct is the router itself, any
+// other type must be provided by exactly one field of the router struct which
+// is of that type or implements that interface, or by a method of the router
+// struct named with a New prefix which is called for each request.
+package analyze
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"math"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cstockton/routepiler/internal/backend"
+	"github.com/cstockton/routepiler/internal/parser"
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+// Load parses the non-test Go files within dir and returns the analyzed routes
+// of the named router struct.
+func Load(dir, router string) (*backend.Router, error) {
+	fset := token.NewFileSet()
+	pkg, files, err := source.ParseDir(fset, dir, router)
+	if err != nil {
+		return nil, err
+	}
+	return Analyze(fset, files, pkg, router)
+}
+
+// Analyze returns the analyzed routes of the named router struct declared within
+// the given files of the Go package named pkg.
+func Analyze(fset *token.FileSet, files []*ast.File, pkg, router string) (*backend.Router, error) {
+	a := &analyzer{fset: fset, pkg: source.New(files), router: router,
+		omitted: make(map[*backend.Route][]string)}
+	st := a.pkg.Structs[router]
+	if st == nil {
+		return nil, fmt.Errorf(`router struct %v not found`, router)
+	}
+	use, err := a.uses(st)
+	if err != nil {
+		return nil, err
+	}
+	unset, err := a.unset(st)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.pool(st, &a.pooled); err != nil {
+		return nil, err
+	}
+	err = a.options(st, func(fd *ast.Field, ps tag.Pairs) error {
+		return a.casing(fd, ps, &a.folded)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	eh, err := a.errorHandler(st)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := a.match(st)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &backend.Router{
+		Package: pkg, Name: router, Unset: unset, ErrorHandler: eh, Match: match}
+	cleaned := match.Clean != backend.Strict
+	strategy, err := a.strategy(st)
+	if err != nil {
+		return nil, err
+	}
+	for _, fd := range st.Fields.List {
+		routes, err := a.routes(fd)
+		if err != nil {
+			return nil, err
+		}
+		for _, rt := range routes {
+			rt.Use = append(append([]string(nil), use...), rt.Use...)
+			if n := len(rt.Path); cleaned && n > 1 && len(rt.Path[n-1]) == 0 {
+				return nil, fmt.Errorf(`%v: route %v ends with a slash, which is removed `+
+					`from each path by the clean tag`, rt.Pos, rt.Pattern)
+			}
+		}
+		out.Routes = append(out.Routes, routes...)
+	}
+	if err := a.conflicts(out.Routes); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out.Routes, func(i, j int) bool {
+		return Less(out.Routes[i], out.Routes[j])
+	})
+
+	out.Strategy = backend.Linear
+	switch {
+	case strategy != ``:
+		out.Strategy = strategies[strategy]
+	case len(out.Routes) >= RadixRoutes:
+		out.Strategy = backend.Radix
+	}
+	return out, nil
+}
+
+// RadixRoutes is the number of routes at which a router without a strategy
+// option uses the Radix strategy.
+const RadixRoutes = 64
+
+var strategies = map[string]backend.Strategy{
+	`linear`: backend.Linear,
+	`radix`:  backend.Radix,
+}
+
+type analyzer struct {
+	fset   *token.FileSet
+	pkg    *source.Package
+	router string
+	pooled bool         // the pool option of the router struct
+	folded backend.Case // the case option of the router struct
+
+	// omitted holds the names of the optional params each route expanded from
+	// a pattern omits.
+	omitted map[*backend.Route][]string
+}
+
+// routes returns the routes declared by the tag of a single router field.
+func (a *analyzer) routes(fd *ast.Field) ([]*backend.Route, error) {
+	if fd.Tag == nil {
+		return nil, nil
+	}
+	pos := a.fset.Position(fd.Tag.Pos())
+	str, err := tag.Unquote(fd.Tag.Value)
+	if err != nil {
+		return nil, fmt.Errorf(`%v: %v`, pos, err)
+	}
+	ps, err := tag.Parse(str)
+	if err != nil {
+		return nil, nil // tags which are not for routes are not our concern
+	}
+
+	name := source.TypeName(fd.Type)
+	if len(fd.Names) > 0 {
+		name = fd.Names[0].Name
+	}
+
+	preds, err := a.predicates(fd, ps)
+	if err != nil {
+		return nil, err
+	}
+	casing := a.folded
+	if err := a.casing(fd, ps, &casing); err != nil {
+		return nil, err
+	}
+	var use []string
+	if len(ps.Routes()) > 0 {
+		if st := a.pkg.Structs[a.pkg.Struct(fd.Type)]; st != nil {
+			if use, err = a.uses(st); err != nil {
+				return nil, err
+			}
+		}
+		names, err := a.use(fd, ps)
+		if err != nil {
+			return nil, err
+		}
+		use = append(use, names...)
+	}
+
+	var out []*backend.Route
+	for _, p := range ps.Routes() {
+		at := a.at(fd, p)
+		pr, err := parser.Parse(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf(`%v: invalid %v pattern: %v`, pos, p.Key, err)
+		}
+		if err := supported(pr); err != nil {
+			return nil, fmt.Errorf(`%v: %v`, at, err)
+		}
+		params, err := a.params(fd, pr)
+		if err != nil {
+			return nil, fmt.Errorf(`%v: %v`, at, err)
+		}
+		srs, err := a.pkg.Routes(fd, ps, p)
+		if err != nil {
+			return nil, fmt.Errorf(`%v: %v`, pos, err)
+		}
+
+		for _, sr := range srs {
+			h, err := a.handler(fd, sr.Handler)
+			if err != nil {
+				return nil, fmt.Errorf(`%v: %v`, pos, err)
+			}
+			if h.Kind == backend.CallMethod {
+				h.Pool = a.pooled
+				if err := a.pool(a.pkg.Structs[h.Type], &h.Pool); err != nil {
+					return nil, err
+				}
+				if d := a.pkg.Methods[h.Type][`Reset`]; d != nil {
+					h.Reset = d.Type.Params.NumFields() == 0 && d.Type.Results.NumFields() == 0
+				}
+			}
+			routes, omits := expand(pr, params)
+			for j, rt := range routes {
+				rt.Field, rt.Method, rt.Pattern, rt.Pos = name, strings.ToUpper(sr.Method), p.Value, at
+				rt.Handler, rt.Predicates, rt.Use, rt.Case = h, preds, use, casing
+				if omits[j] != nil {
+					a.omitted[rt] = omits[j]
+				}
+			}
+			out = append(out, routes...)
+		}
+	}
+	return out, nil
+}
+
+// expand returns the routes of a pattern along with the names of the optional
+// params each omits, the path as declared followed by the path omitting each
+// trailing segment holding an optional param in turn. An omitted param is given
+// its default when it has one, and is otherwise left unbound.
+func expand(pr *parser.Route, params []*backend.Param) (out []*backend.Route, omits [][]string) {
+	path := escape(pr.Path)
+	out, omits = append(out, &backend.Route{Path: path, Params: params}), append(omits, nil)
+	var omitted []string
+	for n := len(path); n > 0 && optional(path[n-1]); n-- {
+		prm := path[n-1][0].Param
+		omitted = append([]string{prm.Name}, omitted...)
+
+		rt := &backend.Route{Path: path[:n-1]}
+		if n == 1 {
+			rt.Path = []parser.Segment{nil} // the root
+		}
+		for _, p := range params {
+			if p.Query || !omit(omitted, p.Name) || p.Default != `` {
+				rt.Params = append(rt.Params, p)
+			}
+		}
+		out, omits = append(out, rt), append(omits, omitted)
+	}
+	return out, omits
+}
+
+// Expand returns the escaped paths of a pattern along with the names of the
+// optional params each omits, as the routes of the pattern are expanded. The
+// first is the path as declared, followed by the path omitting each trailing
+// segment holding an optional param in turn.
+func Expand(pr *parser.Route) (paths [][]parser.Segment, omits [][]string) {
+	routes, omits := expand(pr, nil)
+	for _, rt := range routes {
+		paths = append(paths, rt.Path)
+	}
+	return paths, omits
+}
+
+// optional returns true if a path segment holds a lone optional param.
+func optional(seg parser.Segment) bool {
+	return len(seg) == 1 && seg[0].Param != nil && seg[0].Param.Optional
+}
+
+// omit returns true if name is within names.
+func omit(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// conflicts returns an error when a route expanded from a pattern by omitting
+// its optional params matches the same requests as another route, reported at
+// the position of the pattern it was expanded from.
+func (a *analyzer) conflicts(routes []*backend.Route) error {
+	for _, rt := range routes {
+		omitted := a.omitted[rt]
+		if omitted == nil {
+			continue
+		}
+		for _, o := range routes {
+			if o == rt || !same(rt, o) {
+				continue
+			}
+			var names []string
+			for _, name := range omitted {
+				names = append(names, strconv.Quote(name))
+			}
+			params := `param`
+			if len(names) > 1 {
+				params += `s`
+			}
+			return fmt.Errorf(`%v: route %v omitting the optional %v %v matches the same `+
+				`requests as the route %v at %v`,
+				rt.Pos, rt, params, strings.Join(names, ` and `), o, o.Pos)
+		}
+	}
+	return nil
+}
+
+// same returns true if the routes a and b match the same requests.
+func same(a, b *backend.Route) bool {
+	if a.Method != b.Method || len(a.Path) != len(b.Path) ||
+		fmt.Sprint(a.Predicates) != fmt.Sprint(b.Predicates) {
+		return false
+	}
+	for i := range a.Path {
+		if Shape(a.Path[i]) != Shape(b.Path[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// predicates returns the predicates declared by the header, consumes and accept
+// keys of the tag of a route field in that order, which apply to each of its
+// routes.
+func (a *analyzer) predicates(fd *ast.Field, ps tag.Pairs) ([]backend.Predicate, error) {
+	var out []backend.Predicate
+	for _, p := range ps {
+		at := a.at(fd, p)
+		switch p.Key {
+		case `consumes`, `accept`:
+			pred := backend.Predicate{Kind: backend.Consumes, Header: `Content-Type`}
+			if p.Key == `accept` {
+				pred.Kind, pred.Header = backend.Accept, `Accept`
+			}
+			for _, v := range strings.Split(p.Value, `,`) {
+				mt, params, err := mime.ParseMediaType(strings.TrimSpace(v))
+				switch {
+				case err != nil:
+					return nil, fmt.Errorf(`%v: invalid media type %q in %v tag: %v`,
+						at, strings.TrimSpace(v), p.Key, err)
+				case len(params) > 0, strings.Contains(mt, `*`), !strings.Contains(mt, `/`):
+					return nil, fmt.Errorf(`%v: media type %q in %v tag must be a type `+
+						`and subtype without wildcards or parameters`, at, strings.TrimSpace(v), p.Key)
+				}
+				pred.Values = append(pred.Values, mt)
+			}
+			out = append(out, pred)
+		case `header`:
+			for _, v := range strings.Split(p.Value, `,`) {
+				i := strings.IndexByte(v, ':')
+				name := strings.TrimSpace(v)
+				if i >= 0 {
+					name = strings.TrimSpace(v[:i])
+				}
+				if i < 0 || !httpToken(name) {
+					return nil, fmt.Errorf(`%v: header %q in header tag must be of the `+
+						`form Name: value`, at, strings.TrimSpace(v))
+				}
+				out = append(out, backend.Predicate{
+					Kind:   backend.Header,
+					Header: textproto.CanonicalMIMEHeaderKey(name),
+					Values: []string{strings.TrimSpace(v[i+1:])},
+				})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out, nil
+}
+
+// httpToken returns true if s is a non-empty http token, such as a header name.
+func httpToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if unreserved(c) && !strings.ContainsRune(`()[]=,;:@`, rune(c)) || c == '#' ||
+			c == '%' || c == '^' || c == '`' || c == '|' {
+			continue
+		}
+		return false
+	}
+	return s != ``
+}
+
+// options calls fn with the tag of each field of a struct which does not
+// declare routes, such as a blank field of type struct{}.
+func (a *analyzer) options(st *ast.StructType, fn func(*ast.Field, tag.Pairs) error) error {
+	for _, fd := range st.Fields.List {
+		if fd.Tag == nil {
+			continue
+		}
+		str, err := tag.Unquote(fd.Tag.Value)
+		if err != nil {
+			continue
+		}
+		ps, err := tag.Parse(str)
+		if err != nil || len(ps.Routes()) > 0 {
+			continue
+		}
+		if err := fn(fd, ps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uses returns the middleware named by the use key of the options of a struct.
+func (a *analyzer) uses(st *ast.StructType) (out []string, err error) {
+	err = a.options(st, func(fd *ast.Field, ps tag.Pairs) error {
+		names, err := a.use(fd, ps)
+		out = append(out, names...)
+		return err
+	})
+	return out, err
+}
+
+// unset returns the status of the response when the handler of a route is nil
+// given by the unset key of the options of the router struct, which may be 500
+// or 501 and is 501 Not Implemented when absent.
+func (a *analyzer) unset(st *ast.StructType) (int, error) {
+	status := http.StatusNotImplemented
+	err := a.options(st, func(fd *ast.Field, ps tag.Pairs) error {
+		p, ok := ps.Lookup(`unset`)
+		if !ok {
+			return nil
+		}
+		n, err := strconv.Atoi(p.Value)
+		if err != nil || n != http.StatusInternalServerError && n != http.StatusNotImplemented {
+			return fmt.Errorf(`%v: unset tag must be 500 or 501, got %q`, a.at(fd, p), p.Value)
+		}
+		status = n
+		return nil
+	})
+	return status, err
+}
+
+// strategy returns the strategy option of the router struct, which may be
+// linear or radix and is empty when absent.
+func (a *analyzer) strategy(st *ast.StructType) (out string, err error) {
+	err = a.options(st, func(fd *ast.Field, ps tag.Pairs) error {
+		p, ok := ps.Lookup(`strategy`)
+		if !ok {
+			return nil
+		}
+		if _, ok := strategies[p.Value]; !ok {
+			return fmt.Errorf(`%v: strategy tag must be linear or radix, got %q`,
+				a.at(fd, p), p.Value)
+		}
+		out = p.Value
+		return nil
+	})
+	return out, err
+}
+
+// match returns the match, clean and slash options of the router struct, where
+// match is a comma separated list of runes and nfc.
+func (a *analyzer) match(st *ast.StructType) (out backend.Match, err error) {
+	err = a.options(st, func(fd *ast.Field, ps tag.Pairs) error {
+		if p, ok := ps.Lookup(`match`); ok {
+			for _, v := range strings.Split(p.Value, `,`) {
+				switch strings.TrimSpace(v) {
+				case `runes`:
+					out.Runes = true
+				case `nfc`:
+					out.NFC = true
+				default:
+					return fmt.Errorf(`%v: match tag must list runes or nfc, got %q`,
+						a.at(fd, p), p.Value)
+				}
+			}
+		}
+		if p, ok := ps.Lookup(`clean`); ok {
+			c, ok := cleans[p.Value]
+			if !ok {
+				return fmt.Errorf(`%v: clean tag must be strict, redirect or lenient, got %q`,
+					a.at(fd, p), p.Value)
+			}
+			out.Clean = c
+		}
+		if p, ok := ps.Lookup(`slash`); ok {
+			sl, ok := slashes[p.Value]
+			if !ok {
+				return fmt.Errorf(`%v: slash tag must be keep, reject or decode, got %q`,
+					a.at(fd, p), p.Value)
+			}
+			out.Slash = sl
+		}
+		return nil
+	})
+	return out, err
+}
+
+// casing sets c to the case key of a tag, which may be exact, fold or redirect
+// and leaves c unchanged when absent.
+func (a *analyzer) casing(fd *ast.Field, ps tag.Pairs, c *backend.Case) error {
+	p, ok := ps.Lookup(`case`)
+	if !ok {
+		return nil
+	}
+	v, ok := cases[p.Value]
+	if !ok {
+		return fmt.Errorf(`%v: case tag must be exact, fold or redirect, got %q`,
+			a.at(fd, p), p.Value)
+	}
+	*c = v
+	return nil
+}
+
+var (
+	cases = map[string]backend.Case{
+		`exact`: backend.Exact, `fold`: backend.Fold, `redirect`: backend.FoldRedirect}
+	cleans = map[string]backend.Clean{
+		`strict`: backend.Strict, `redirect`: backend.Redirect, `lenient`: backend.Lenient}
+	slashes = map[string]backend.Slash{
+		`keep`: backend.Keep, `reject`: backend.Reject, `decode`: backend.Decode}
+)
+
+// pool sets pooled to the pool option of a struct, which may be true or false
+// and is left unchanged when absent.
+func (a *analyzer) pool(st *ast.StructType, pooled *bool) error {
+	if st == nil {
+		return nil
+	}
+	return a.options(st, func(fd *ast.Field, ps tag.Pairs) error {
+		p, found := ps.Lookup(`pool`)
+		if !found {
+			return nil
+		}
+		v, err := strconv.ParseBool(p.Value)
+		if err != nil {
+			return fmt.Errorf(`%v: pool tag must be true or false, got %q`, a.at(fd, p), p.Value)
+		}
+		*pooled = v
+		return nil
+	})
+}
+
+// errorHandler returns how the method or func field of the router struct named
+// ErrorHandler is called, which must be a func(http.ResponseWriter,
+// *http.Request, error).
+func (a *analyzer) errorHandler(st *ast.StructType) (*backend.Handler, error) {
+	const name = `ErrorHandler`
+	out := &backend.Handler{Name: name, Ident: name}
+	var ft *ast.FuncType
+	var pos token.Pos
+	if d := a.pkg.Methods[a.router][name]; d != nil {
+		out.Kind, ft, pos = backend.CallMethod, d.Type, d.Pos()
+	}
+	for _, fd := range st.Fields.List {
+		for _, id := range fd.Names {
+			if id.Name != name {
+				continue
+			}
+			out.Kind, out.Nil, pos = backend.CallField, true, fd.Pos()
+			if ft, _ = fd.Type.(*ast.FuncType); ft == nil {
+				return nil, fmt.Errorf(`%v: %v.%v has type %v, want `+
+					`func(http.ResponseWriter, *http.Request, error)`,
+					a.fset.Position(pos), a.router, name, types.ExprString(fd.Type))
+			}
+		}
+	}
+	if ft == nil {
+		return nil, nil
+	}
+	params, results := fieldTypes(ft.Params), fieldTypes(ft.Results)
+	if len(results) > 0 || strings.Join(params, `, `) !=
+		`http.ResponseWriter, *http.Request, error` {
+		return nil, fmt.Errorf(`%v: %v.%v has signature func(%v)%v, want `+
+			`func(http.ResponseWriter, *http.Request, error)`, a.fset.Position(pos),
+			a.router, name, strings.Join(params, `, `), resultString(results))
+	}
+	return out, nil
+}
+
+// at returns the position of the value of a pair within the tag of a field.
+func (a *analyzer) at(fd *ast.Field, p tag.Pair) token.Position {
+	if off := tag.ValueOffset(fd.Tag.Value, p); off >= 0 {
+		return a.fset.Position(fd.Tag.Pos() + token.Pos(off))
+	}
+	return a.fset.Position(fd.Tag.Pos())
+}
+
+// use returns the names of the methods of the router struct named by the comma
+// separated use key of a tag, which are resolved by name or by their exported
+// name and must be a func(http.Handler) http.Handler.
+func (a *analyzer) use(fd *ast.Field, ps tag.Pairs) ([]string, error) {
+	p, ok := ps.Lookup(`use`)
+	if !ok {
+		return nil, nil
+	}
+	at := a.at(fd, p)
+	var out []string
+	for _, name := range strings.Split(p.Value, `,`) {
+		name = strings.TrimSpace(name)
+		d := a.pkg.Methods[a.router][name]
+		if d == nil {
+			d = a.pkg.Methods[a.router][source.ExportedName(name)]
+		}
+		if d == nil {
+			return nil, fmt.Errorf(`%v: use tag names %v which is not a method of %v`,
+				at, name, a.router)
+		}
+		params, results := fieldTypes(d.Type.Params), fieldTypes(d.Type.Results)
+		if len(params) != 1 || params[0] != `http.Handler` ||
+			len(results) != 1 || results[0] != `http.Handler` {
+			return nil, fmt.Errorf(`%v: middleware %v.%v has signature func(%v)%v, `+
+				`want func(http.Handler) http.Handler`, at, a.router, d.Name.Name,
+				strings.Join(params, `, `), resultString(results))
+		}
+		out = append(out, d.Name.Name)
+	}
+	return out, nil
+}
+
+// supported returns an error if the route uses a feature code generation does
+// not support.
+func supported(r *parser.Route) error {
+	var wild *parser.Param
+	for _, prm := range r.Params() {
+		switch {
+		case prm.Wild && wild != nil:
+			return fmt.Errorf(`param %q is a wildcard following the wildcard %q, `+
+				`which code generation does not support`, prm.Name, wild.Name)
+		case prm.Wild:
+			wild = prm
+		}
+	}
+	return nil
+}
+
+// handler returns how the handler of a route field is called.
+func (a *analyzer) handler(fd *ast.Field, h source.Handler) (backend.Handler, error) {
+	out := backend.Handler{Name: h.Name, Ident: h.Ident.Name}
+	var ft *ast.FuncType
+	switch {
+	case h.Field != nil:
+		out.Kind, out.Nil = backend.ServeField, nilable(fd.Type)
+		if ft, _ = fd.Type.(*ast.FuncType); ft != nil {
+			out.Kind = backend.CallField
+		}
+	case h.Func != nil && h.Func.Recv != nil:
+		out.Kind, out.Type, ft = backend.CallMethod, a.pkg.Struct(fd.Type), h.Func.Type
+	case h.Func != nil:
+		out.Kind, ft = backend.CallFunc, h.Func.Type
+	default:
+		out.Kind, out.Nil = backend.ServeVar, unassigned(h.Ident)
+		if ft = a.varFunc(h.Ident); ft != nil {
+			out.Kind = backend.CallFunc
+		}
+	}
+	if ft == nil {
+		return out, nil
+	}
+
+	var err error
+	out.Err, out.Args, err = a.signature(h.Name, ft)
+	return out, err
+}
+
+// varFunc returns the func type of a var declared with a func type, a func
+// literal or the name of a func, otherwise nil.
+func (a *analyzer) varFunc(id *ast.Ident) *ast.FuncType {
+	if id.Obj == nil {
+		return nil
+	}
+	spec, ok := id.Obj.Decl.(*ast.ValueSpec)
+	if !ok {
+		return nil
+	}
+	if spec.Type != nil {
+		ft, _ := spec.Type.(*ast.FuncType)
+		return ft
+	}
+	for i, name := range spec.Names {
+		if name != id || i >= len(spec.Values) {
+			continue
+		}
+		switch v := spec.Values[i].(type) {
+		case *ast.FuncLit:
+			return v.Type
+		case *ast.Ident:
+			if d := a.pkg.Funcs[v.Name]; d != nil {
+				return d.Type
+			}
+		}
+	}
+	return nil
+}
+
+// nilable returns true if a value of the type expr may be nil, where types of
+// other packages such as http.Handler are assumed to be interfaces or funcs.
+func nilable(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.StarExpr, *ast.FuncType, *ast.InterfaceType, *ast.MapType,
+		*ast.ChanType, *ast.SelectorExpr:
+		return true
+	case *ast.ArrayType:
+		return t.Len == nil
+	case *ast.ParenExpr:
+		return nilable(t.X)
+	case *ast.Ident:
+		if t.Obj == nil {
+			return false
+		}
+		if ts, ok := t.Obj.Decl.(*ast.TypeSpec); ok && ts.Type != expr {
+			return nilable(ts.Type)
+		}
+	}
+	return false
+}
+
+// unassigned returns true if a var is declared with a type which may be nil and
+// without a value, so it is nil until assigned.
+func unassigned(id *ast.Ident) bool {
+	if id.Obj == nil {
+		return false
+	}
+	spec, ok := id.Obj.Decl.(*ast.ValueSpec)
+	return ok && spec.Type != nil && len(spec.Values) == 0 && nilable(spec.Type)
+}
+
+// Signature returns an error when the func type of the handler with the given
+// name is not a func(http.ResponseWriter, *http.Request, ...) returning nothing
+// or an error.
+func Signature(name string, ft *ast.FuncType) error {
+	params, results := fieldTypes(ft.Params), fieldTypes(ft.Results)
+	switch {
+	case len(params) < 2,
+		params[0] != `http.ResponseWriter`,
+		params[1] != `*http.Request`,
+		len(results) > 1,
+		len(results) == 1 && results[0] != `error`:
+		return fmt.Errorf(`handler %v has signature func(%v)%v, want `+
+			`func(http.ResponseWriter, *http.Request, ...) with no result or error`,
+			name, strings.Join(params, `, `), resultString(results))
+	}
+	return nil
+}
+
+// signature returns true if a handler returns an error along with each param
+// injected after the http.ResponseWriter and *http.Request, or the error of
+// Signature.
+func (a *analyzer) signature(name string, ft *ast.FuncType) (bool, []backend.Arg, error) {
+	if err := Signature(name, ft); err != nil {
+		return false, nil, err
+	}
+	params, results := fieldTypes(ft.Params), fieldTypes(ft.Results)
+	var args []backend.Arg
+	for _, typ := range params[2:] {
+		arg, err := a.inject(typ)
+		if err != nil {
+			return false, nil, fmt.Errorf(`handler %v param of type %v %v`, name, typ, err)
+		}
+		args = append(args, arg)
+	}
+	return len(results) == 1, args, nil
+}
+
+// inject returns how a handler param of the Go type typ is provided, which is
+// the context.Context of the request, the router struct itself, the field of
+// the router struct of that type or implementing that interface, or the New
+// method of the router struct returning that type. Exactly one field or method
+// must provide a type which is not a context.Context or the router struct.
+func (a *analyzer) inject(typ string) (backend.Arg, error) {
+	switch typ {
+	case `context.Context`:
+		return backend.Arg{Kind: backend.Context, Type: typ}, nil
+	case `*` + a.router:
+		return backend.Arg{Kind: backend.Self, Type: typ}, nil
+	}
+
+	var out []backend.Arg
+	for _, fd := range a.pkg.Structs[a.router].Fields.List {
+		if routeField(fd) {
+			continue
+		}
+		if ft := types.ExprString(fd.Type); ft != typ && !a.implements(fd.Type, typ) {
+			continue
+		}
+		names := []string{source.TypeName(fd.Type)}
+		if len(fd.Names) > 0 {
+			names = names[:0]
+			for _, id := range fd.Names {
+				names = append(names, id.Name)
+			}
+		}
+		for _, name := range names {
+			if name != `_` {
+				out = append(out, backend.Arg{Kind: backend.RouterField, Name: name, Type: typ})
+			}
+		}
+	}
+
+	methods := a.pkg.Methods[a.router]
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		params, results := fieldTypes(methods[name].Type.Params), fieldTypes(methods[name].Type.Results)
+		if !strings.HasPrefix(name, `New`) || len(results) == 0 || results[0] != typ {
+			continue
+		}
+		if len(params) > 1 || len(params) == 1 && params[0] != `*http.Request` &&
+			params[0] != `context.Context` || len(results) > 2 ||
+			len(results) == 2 && results[1] != `error` {
+			return backend.Arg{}, fmt.Errorf(`is returned by the constructor %v.%v `+
+				`which has signature func(%v)%v, want a func() %v, func(*http.Request) %v `+
+				`or func(context.Context) %v which may also return an error`, a.router, name,
+				strings.Join(params, `, `), resultString(results), typ, typ, typ)
+		}
+		arg := backend.Arg{Kind: backend.Constructor, Name: name, Type: typ, Err: len(results) == 2}
+		if len(params) == 1 {
+			arg.In = params[0]
+		}
+		out = append(out, arg)
+	}
+
+	switch len(out) {
+	case 0:
+		return backend.Arg{}, fmt.Errorf(`is not provided by a field or New method of %v`,
+			a.router)
+	case 1:
+		return out[0], nil
+	}
+	var provided []string
+	for _, arg := range out {
+		provided = append(provided, a.router+`.`+arg.Name)
+	}
+	return backend.Arg{}, fmt.Errorf(`is ambiguous, provided by %v`,
+		strings.Join(provided, ` and `))
+}
+
+// implements returns true if the type expr of a router field has each method of
+// the interface named iface declared within the package, where methods with a
+// pointer receiver are only in the method set of a pointer. Interfaces
+// embedding interfaces of other packages are never implemented.
+func (a *analyzer) implements(expr ast.Expr, iface string) bool {
+	it := a.pkg.Interfaces[iface]
+	if it == nil {
+		return false
+	}
+	typ := expr
+	star, ptr := expr.(*ast.StarExpr)
+	if ptr {
+		typ = star.X
+	}
+	id, ok := typ.(*ast.Ident)
+	if !ok || a.pkg.Interfaces[id.Name] != nil {
+		return false
+	}
+	for _, m := range it.Methods.List {
+		if len(m.Names) == 0 {
+			embed, ok := m.Type.(*ast.Ident)
+			if !ok || !a.implements(expr, embed.Name) {
+				return false
+			}
+			continue
+		}
+		for _, name := range m.Names {
+			d := a.pkg.Methods[id.Name][name.Name]
+			if d == nil {
+				return false
+			}
+			if _, recv := d.Recv.List[0].Type.(*ast.StarExpr); recv && !ptr {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// routeField returns true if the tag of a field declares routes.
+func routeField(fd *ast.Field) bool {
+	if fd.Tag == nil {
+		return false
+	}
+	str, err := tag.Unquote(fd.Tag.Value)
+	if err != nil {
+		return false
+	}
+	ps, err := tag.Parse(str)
+	return err == nil && len(ps.Routes()) > 0
+}
+
+func fieldTypes(fl *ast.FieldList) (out []string) {
+	if fl == nil {
+		return nil
+	}
+	for _, fd := range fl.List {
+		for n := 0; n == 0 || n < len(fd.Names); n++ {
+			out = append(out, types.ExprString(fd.Type))
+		}
+	}
+	return
+}
+
+func resultString(results []string) string {
+	switch len(results) {
+	case 0:
+		return ``
+	case 1:
+		return ` ` + results[0]
+	default:
+		return ` (` + strings.Join(results, `, `) + `)`
+	}
+}
+
+// params returns each param of a route along with the field of the struct type
+// of the route field it is bound to. Params of a route field which is not a
+// struct are not bound.
+func (a *analyzer) params(fd *ast.Field, r *parser.Route) ([]*backend.Param, error) {
+	typ := a.pkg.Struct(fd.Type)
+	var out []*backend.Param
+	for _, prm := range r.Params() {
+		p := &backend.Param{Param: prm}
+		if typ != `` {
+			f, err := a.field(typ, prm)
+			if err != nil {
+				return nil, err
+			}
+			p.Field = f
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// field returns the field of the struct type typ bound to a param, which is the
+// field with a name equal under Unicode case-folding declared by typ or promoted
+// from its embedded structs, where fields of an outer struct shadow those of the
+// structs it embeds. It is an error for more than one field to be a candidate.
+func (a *analyzer) field(typ string, prm *parser.Param) (*backend.Field, error) {
+	cands := a.pkg.Candidates(typ, prm.Name)
+	switch len(cands) {
+	case 0:
+		return nil, fmt.Errorf(`param %q has no matching field in %v`, prm.Name, typ)
+	case 1:
+	default:
+		var names []string
+		for _, c := range cands {
+			names = append(names, typ+`.`+c.String())
+		}
+		return nil, fmt.Errorf(`param %q is ambiguous, matching the fields %v`,
+			prm.Name, strings.Join(names, ` and `))
+	}
+
+	c := cands[0]
+	f, err := a.convert(c.Name, c.Field.Field, prm)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range c.Embeds {
+		f.Embeds = append(f.Embeds, backend.Embed{Name: e.Name, Type: e.Type, Ptr: e.Ptr})
+	}
+	return f, nil
+}
+
+// convert returns the field a param is bound to, verifying the param value may
+// be converted to its type and that its bounds and the default of the param are
+// valid for its type.
+func (a *analyzer) convert(name string, fd *ast.Field, prm *parser.Param) (*backend.Field, error) {
+	f := &backend.Field{Name: name, Type: types.ExprString(fd.Type)}
+	typ := f.Type
+	if strings.HasPrefix(typ, `*`) {
+		f.Ptr, typ = true, typ[1:]
+	}
+	switch typ {
+	case `string`:
+		f.Kind = backend.String
+	case `[]byte`:
+		f.Kind = backend.Bytes
+	case `bool`:
+		f.Kind = backend.Bool
+	case `int`, `int8`, `int16`, `int32`, `int64`, `rune`:
+		f.Kind, f.Bits = backend.Int, bits(typ)
+	case `uint`, `uint8`, `uint16`, `uint32`, `uint64`, `byte`:
+		f.Kind, f.Bits = backend.Uint, bits(typ)
+	case `float32`, `float64`:
+		f.Kind, f.Bits = backend.Float, bits(typ)
+	case `time.Duration`:
+		f.Kind = backend.Duration
+	case `time.Time`:
+		f.Kind = backend.Time
+	default:
+		if a.pkg.Methods[typ][`UnmarshalText`] == nil {
+			return nil, fmt.Errorf(`param %q is bound to the field %v of type %v, `+
+				`which is not a basic type, time.Duration, time.Time or an `+
+				`encoding.TextUnmarshaler`, prm.Name, name, f.Type)
+		}
+		f.Kind = backend.Text
+	}
+	if f.Ptr && f.Kind == backend.Bytes {
+		return nil, fmt.Errorf(`param %q is bound to the field %v of type %v, `+
+			`which is not supported`, prm.Name, name, f.Type)
+	}
+
+	if fd.Tag != nil {
+		str, err := tag.Unquote(fd.Tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		ps, _ := tag.Parse(str)
+		for _, key := range []string{`min`, `max`} {
+			p, ok := ps.Lookup(key)
+			if !ok {
+				continue
+			}
+			v, err := bound(f, p.Value)
+			if err != nil {
+				return nil, fmt.Errorf(`field %v has an invalid %v tag: %v`, name, key, err)
+			}
+			if key == `min` {
+				f.Min = v
+			} else {
+				f.Max = v
+			}
+		}
+	}
+	if prm.Default != `` {
+		if _, err := value(f, prm.Default); err != nil {
+			return nil, fmt.Errorf(`param %q has an invalid default for the field %v: %v`,
+				prm.Name, name, err)
+		}
+	}
+	return f, nil
+}
+
+func bits(typ string) int {
+	switch typ {
+	case `int8`, `uint8`, `byte`:
+		return 8
+	case `int16`, `uint16`:
+		return 16
+	case `int32`, `uint32`, `rune`, `float32`:
+		return 32
+	case `int64`, `uint64`, `float64`:
+		return 64
+	}
+	return 0
+}
+
+// bound returns a min or max tag as a Go constant for the kind of a field, which
+// bounds the value of numbers and durations and the length of strings.
+func bound(f *backend.Field, s string) (string, error) {
+	switch f.Kind {
+	case backend.String, backend.Bytes:
+		n, err := strconv.Atoi(s)
+		if err == nil && n < 0 {
+			err = fmt.Errorf(`length %v is negative`, n)
+		}
+		return strconv.Itoa(n), err
+	case backend.Int, backend.Uint, backend.Float, backend.Duration:
+		return value(f, s)
+	}
+	return ``, fmt.Errorf(`bounds are not supported for type %v`, f.Type)
+}
+
+// value returns s as a Go constant for the kind of a field, or an error if it
+// can not be converted.
+func value(f *backend.Field, s string) (string, error) {
+	bits := f.Bits
+	if bits == 0 {
+		bits = 64
+	}
+	switch f.Kind {
+	case backend.Bool:
+		v, err := strconv.ParseBool(s)
+		return strconv.FormatBool(v), err
+	case backend.Int:
+		v, err := strconv.ParseInt(s, 10, bits)
+		return strconv.FormatInt(v, 10), err
+	case backend.Uint:
+		v, err := strconv.ParseUint(s, 10, bits)
+		return strconv.FormatUint(v, 10), err
+	case backend.Float:
+		v, err := strconv.ParseFloat(s, bits)
+		if err == nil && (math.IsInf(v, 0) || math.IsNaN(v)) {
+			err = fmt.Errorf(`%v is not a finite number`, s)
+		}
+		return strconv.FormatFloat(v, 'g', -1, bits), err
+	case backend.Duration:
+		v, err := time.ParseDuration(s)
+		return strconv.FormatInt(int64(v), 10), err
+	case backend.Time:
+		_, err := time.Parse(time.RFC3339, s)
+		return s, err
+	}
+	return s, nil
+}
+
+// escape returns a copy of the given path segments with each literal in its
+// escaped form.
+func escape(path []parser.Segment) []parser.Segment {
+	out := make([]parser.Segment, len(path))
+	for i, seg := range path {
+		out[i] = make(parser.Segment, len(seg))
+		for j, part := range seg {
+			if part.Param == nil {
+				part.Lit = Escape(part.Lit)
+			}
+			out[i][j] = part
+		}
+	}
+	return out
+}
+
+// Escape returns a literal of a route pattern in the form it takes within the
+// escaped path of a request, where each byte which may not appear unescaped
+// within a path segment is percent-encoded with upper case hex digits. Percent
+// encodings within the literal are decoded first, so "a b" and "a%20b" are the
+// same literal.
+func Escape(lit string) string {
+	if s, err := url.PathUnescape(lit); err == nil {
+		lit = s
+	}
+	const hex = `0123456789ABCDEF`
+	var b strings.Builder
+	for i := 0; i < len(lit); i++ {
+		if c := lit[i]; unreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(`%` + hex[c>>4:c>>4+1] + hex[c&15:c&15+1])
+		}
+	}
+	return b.String()
+}
+
+// unreserved returns true if c may appear unescaped within a path segment.
+func unreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte(`-._~!$&'()*+,;=:@[]`, c) >= 0
+}
+
+// Less returns true if route a takes precedence over route b.
+func Less(a, b *backend.Route) bool {
+	for i := 0; i < len(a.Path) && i < len(b.Path); i++ {
+		ra, rb := Rank(a.Path[i]), Rank(b.Path[i])
+		if ra != rb {
+			return ra > rb
+		}
+		if sa, sb := Shape(a.Path[i]), Shape(b.Path[i]); sa != sb {
+			return sa < sb
+		}
+	}
+	if na, nb := len(a.Path), len(b.Path); na != nb {
+		// a trailing wildcard may consume the segments following it, so the
+		// longer route must be tried first for it to ever match
+		short := a.Path
+		if nb < na {
+			short = b.Path
+		}
+		if n := len(short); n > 0 && Wild(short[n-1]) != nil {
+			return na > nb
+		}
+		return na < nb
+	}
+	if (a.Method != ``) != (b.Method != ``) {
+		return a.Method != ``
+	}
+	return len(a.Predicates) > 0 && len(b.Predicates) == 0
+}
+
+// Rank returns the specificity of a path segment, where a static segment ranks
+// 3, a segment containing a literal or a regexp ranks 2, a lone param ranks 1
+// and a segment containing a wildcard ranks 0.
+func Rank(seg parser.Segment) int {
+	switch {
+	case seg.Static():
+		return 3
+	case Wild(seg) != nil:
+		return 0
+	case len(seg) > 1 || seg[0].Param.Regexp != ``:
+		return 2
+	}
+	return 1
+}
+
+// Shape returns the segment with each param replaced by its bounds, so that two
+// segments which match the same requests have the same shape regardless of the
+// names of their params.
+func Shape(seg parser.Segment) string {
+	var b strings.Builder
+	for _, part := range seg {
+		prm := part.Param
+		if prm == nil {
+			b.WriteString(part.Lit)
+			continue
+		}
+		b.WriteString(`{`)
+		if prm.Wild {
+			fmt.Fprintf(&b, `*%d`, prm.Depth)
+		}
+		if prm.Min > 0 || prm.Max > 0 {
+			fmt.Fprintf(&b, `{%d-%d}`, prm.Min, prm.Max)
+		}
+		if prm.Regexp != `` {
+			b.WriteString(strconv.Quote(prm.Regexp))
+		}
+		b.WriteString(`}`)
+	}
+	return b.String()
+}
+
+// Wild returns the wildcard param of a segment, or nil.
+func Wild(seg parser.Segment) *parser.Param {
+	for _, part := range seg {
+		if part.Param != nil && part.Param.Wild {
+			return part.Param
+		}
+	}
+	return nil
+}
@@ -0,0 +1,645 @@
+package analyze
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cstockton/routepiler/internal/backend"
+)
+
+const testSrc = `package main
+
+import (
+	"net/http"
+	"time"
+)
+
+type Router struct {
+	_     struct{} ` + "`use:\"log\"`" + `
+	Users Users ` + "`%v`" + `
+}
+
+func (rt *Router) Log(next http.Handler) http.Handler    { return next }
+func (rt *Router) Audit(next http.Handler) http.Handler  { return next }
+func (rt *Router) Bogus(next http.HandlerFunc) http.Handler { return next }
+
+type Users struct {
+	_ struct{} ` + "`use:\"audit\"`" + `
+	*Base
+	Name  string
+	User  string ` + "`max:\"20\"`" + `
+	Age   *uint8 ` + "`min:\"18\"`" + `
+	Since time.Time
+	Wait  time.Duration
+	Data  map[string]string
+	Color Color
+}
+
+type Base struct {
+	Org, Name string
+	UID, UId  string
+}
+
+type Color string
+
+func (c *Color) UnmarshalText(text []byte) error { return nil }
+
+func (h *Users) Get(w http.ResponseWriter, r *http.Request)         {}
+func (h *Users) Post(w http.ResponseWriter, r *http.Request) error  {}
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request) int { return 0 }
+`
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{`get:"/users"`, `GET /users Users.Get use Log,Audit`},
+		{`path:"/users"`,
+			"GET /users Users.Get use Log,Audit\nPOST /users Users.Post err use Log,Audit"},
+		{`get:"/users" accept:"application/json,text/csv" header:"x-api-version: 2"`,
+			`GET /users Users.Get header X-Api-Version 2, accept application/json text/csv ` +
+				`use Log,Audit`},
+		{`get:"/users/:user/:age?since&wait{default: 1m}&color"`,
+			`GET /users/:user/:age?since&wait{default: 1m}&color Users.Get ` +
+				`User string max 20, Age *uint8 min 18, Since time.Time, ` +
+				`Wait time.Duration, Color Color use Log,Audit`},
+		{`get:"/orgs/:org/:name"`, `GET /orgs/:org/:name Users.Get Base.Org string, ` +
+			`Name string use Log,Audit`},
+		{`get:"/users" use:"audit, Log"`, `GET /users Users.Get use Log,Audit,Audit,Log`},
+
+		{`get:"/users/:user?"`, "GET /users/:user? Users.Get use Log,Audit\n" +
+			`GET /users/:user? Users.Get User string max 20 use Log,Audit`},
+		{`get:"/:name?/:user?{default: me}"`,
+			"GET /:name?/:user?{default: me} Users.Get User string max 20 use Log,Audit\n" +
+				"GET /:name?/:user?{default: me} Users.Get Name string, User string max 20 " +
+				"use Log,Audit\n" +
+				`GET /:name?/:user?{default: me} Users.Get Name string, User string max 20 ` +
+				`use Log,Audit`},
+
+		// errors
+		{`get:"/users/:user?/:name?" path:"GET /users/:org"`, `router.go:10:20: route ` +
+			`GET /users/:user?/:name? omitting the optional param "name" matches the same ` +
+			`requests as the route GET /users/:org at router.go:10:48`},
+		{`get:"/users/:user?/:name?" path:"GET /users"`, `router.go:10:20: route ` +
+			`GET /users/:user?/:name? omitting the optional params "user" and "name" ` +
+			`matches the same requests as the route GET /users at router.go:10:48`},
+		{`path:"GET /users" get:"/users/:user?"`, `router.go:10:38: route ` +
+			`GET /users/:user? omitting the optional param "user" matches the same ` +
+			`requests as the route GET /users at router.go:10:21`},
+		{`get:"/:a*/:b*"`, `router.go:10:20: param "b" is a wildcard following the ` +
+			`wildcard "a", which code generation does not support`},
+		{`get:"/users/:bogus"`, `router.go:10:20: param "bogus" has no matching field in Users`},
+		{`get:"/users/:uid"`, `router.go:10:20: param "uid" is ambiguous, matching ` +
+			`the fields Users.Base.UID and Users.Base.UId`},
+		{`get:"/users/:data"`, `router.go:10:20: param "data" is bound to the field Data ` +
+			`of type map[string]string, which is not a basic type, time.Duration, ` +
+			`time.Time or an encoding.TextUnmarshaler`},
+		{`get:"/users?age{default: old}"`, `router.go:10:20: param "age" has an invalid ` +
+			`default for the field Age: strconv.ParseUint: parsing "old": invalid syntax`},
+		{`get:"/users" consumes:"application/xml; charset=utf-8"`, `router.go:10:38: media type ` +
+			`"application/xml; charset=utf-8" in consumes tag must be a type and subtype ` +
+			`without wildcards or parameters`},
+		{`get:"/users" accept:"text/*"`, `router.go:10:36: media type "text/*" in accept ` +
+			`tag must be a type and subtype without wildcards or parameters`},
+		{`get:"/users" accept:"json"`, `router.go:10:36: media type "json" in accept tag ` +
+			`must be a type and subtype without wildcards or parameters`},
+		{`get:"/users" accept:"text/"`, `router.go:10:36: invalid media type "text/" in ` +
+			`accept tag: mime: expected token after slash`},
+		{`get:"/users" header:"X-Version 2"`, `router.go:10:36: header "X-Version 2" in ` +
+			`header tag must be of the form Name: value`},
+		{`get:"/users" use:"auth"`, `router.go:10:33: use tag names auth which is not ` +
+			`a method of Router`},
+		{`get:"/users" use:"log,bogus"`, `router.go:10:33: middleware Router.Bogus has ` +
+			`signature func(http.HandlerFunc) http.Handler, want func(http.Handler) http.Handler`},
+		{`get:"/users" func:"GetUser"`, `router.go:10:14: handler GetUser has signature ` +
+			`func(http.ResponseWriter, *http.Request) int, want ` +
+			`func(http.ResponseWriter, *http.Request, ...) with no result or error`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %v exp %v`, idx, test.tag, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testSrc, test.tag), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		var got string
+		r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		if err != nil {
+			got = err.Error()
+		} else {
+			var lines []string
+			for _, rt := range r.Routes {
+				line := fmt.Sprintf(`%v %v`, rt.String(), rt.Handler.Name)
+				if rt.Handler.Err {
+					line += ` err`
+				}
+				var fs []string
+				for _, p := range rt.Params {
+					fs = append(fs, fieldString(p.Field))
+				}
+				if len(fs) > 0 {
+					line += ` ` + strings.Join(fs, `, `)
+				}
+				var preds []string
+				for _, pred := range rt.Predicates {
+					kind := [...]string{`header ` + pred.Header, `consumes`, `accept`}[pred.Kind]
+					preds = append(preds, kind+` `+strings.Join(pred.Values, ` `))
+				}
+				if len(preds) > 0 {
+					line += ` ` + strings.Join(preds, `, `)
+				}
+				line += ` use ` + strings.Join(rt.Use, `,`)
+				lines = append(lines, line)
+			}
+			got = strings.Join(lines, "\n")
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf("exp:\n%v\ngot:\n%v", exp, got)
+		}
+	}
+}
+
+func fieldString(f *backend.Field) string {
+	s := f.Selector() + ` ` + f.Type
+	if f.Min != `` {
+		s += ` min ` + f.Min
+	}
+	if f.Max != `` {
+		s += ` max ` + f.Max
+	}
+	return s
+}
+
+func TestLess(t *testing.T) {
+	const testRouter = `package main
+
+import "net/http"
+
+type Router struct {
+	A http.Handler ` + "`path:\"/files/:path*\"`" + `
+	B http.Handler ` + "`path:\"/:id\"`" + `
+	C http.Handler ` + "`path:\"/users/:user\"`" + `
+	D http.Handler ` + "`path:\"/users/me\"`" + `
+	E http.Handler ` + "`path:\"/users/:user([0-9]+)\"`" + `
+	F http.Handler ` + "`get:\"/users/:user\"`" + `
+	G http.Handler ` + "`path:\"/users/:user.json\"`" + `
+	H http.Handler ` + "`path:\"/users\"`" + `
+	I http.Handler ` + "`path:\"/users/:uid\"`" + `
+	J http.Handler ` + "`path:\"/users/:user/orgs\"`" + `
+	K http.Handler ` + "`get:\"/users/:user\" accept:\"text/csv\"`" + `
+	L http.Handler ` + "`path:\"/files/:path*/raw\"`" + `
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, testRouter, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	var got []string
+	for _, rt := range r.Routes {
+		got = append(got, rt.Field)
+	}
+	if exp := `L A H D E G K F C I J B`; exp != strings.Join(got, ` `) {
+		t.Fatalf(`exp order %v; got %v`, exp, strings.Join(got, ` `))
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		lit string
+		exp string
+	}{
+		{`users`, `users`},
+		{`a b`, `a%20b`},
+		{`a%20b`, `a%20b`},
+		{`a%2fb`, `a%2Fb`},
+		{`~user's-(1)`, `~user's-(1)`},
+		{`ü`, `%C3%BC`},
+		{`100%`, `100%25`},
+		{`a?b#c`, `a%3Fb%23c`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from lit %q exp %q`, idx, test.lit, test.exp)
+		if exp, got := test.exp, Escape(test.lit); exp != got {
+			t.Fatalf(`exp %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestInject(t *testing.T) {
+	const testRouter = `package main
+
+import (
+	"context"
+	"net/http"
+)
+
+type Router struct {
+	Root func(http.ResponseWriter, *http.Request, %v) ` + "`get:\"/\"`" + `
+	Page Page ` + "`get:\"/page\"`" + `
+
+	app        *App
+	log        *prefixLog
+	main, copy Store
+	Conf
+}
+
+type App struct{}
+
+type Conf struct{}
+
+type Store struct{}
+
+type Logger interface {
+	Log(msg string)
+}
+
+type Closer interface {
+	Logger
+	Close() error
+}
+
+type prefixLog struct{}
+
+func (l *prefixLog) Log(msg string) {}
+
+type Tx struct{}
+
+type Span struct{}
+
+type Page struct{}
+
+func (h *Page) Get(w http.ResponseWriter, r *http.Request) {}
+
+func (rt *Router) NewTx(r *http.Request) (*Tx, error)         { return nil, nil }
+func (rt *Router) NewSpan(ctx context.Context) *Span          { return nil }
+func (rt *Router) NewConf() (Conf, int)                        { return Conf{}, 0 }
+`
+	tests := []struct {
+		params string
+		exp    string
+	}{
+		{`context.Context, *Router`, `context.Context, *Router`},
+		{`*App, Logger`, `*App app, Logger log`},
+		{`*Tx, *Span`, `*Tx NewTx(*http.Request) err, *Span NewSpan(context.Context)`},
+
+		// errors
+		{`Store`, `handler Root param of type Store is ambiguous, ` +
+			`provided by Router.main and Router.copy`},
+		{`Closer`, `handler Root param of type Closer is not provided ` +
+			`by a field or New method of Router`},
+		{`*Page`, `handler Root param of type *Page is not provided ` +
+			`by a field or New method of Router`},
+		{`Conf`, `handler Root param of type Conf is returned by the ` +
+			`constructor Router.NewConf which has signature func() (Conf, int), want a ` +
+			`func() Conf, func(*http.Request) Conf or func(context.Context) Conf which ` +
+			`may also return an error`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from params %v exp %v`, idx, test.params, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testRouter, test.params), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		var got string
+		r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		if err != nil {
+			// the position of the tag follows the params of the field type
+			if exp := `router.go:9:`; !strings.HasPrefix(err.Error(), exp) {
+				t.Fatalf(`exp err at %v; got %v`, exp, err)
+			}
+			got = err.Error()[strings.Index(err.Error(), ` `)+1:]
+		} else {
+			var args []string
+			var h backend.Handler
+			for _, rt := range r.Routes {
+				if rt.Field == `Root` {
+					h = rt.Handler
+				}
+			}
+			for _, arg := range h.Args {
+				s := arg.Type
+				switch arg.Kind {
+				case backend.RouterField:
+					s += ` ` + arg.Name
+				case backend.Constructor:
+					s += ` ` + arg.Name + `(` + arg.In + `)`
+					if arg.Err {
+						s += ` err`
+					}
+				}
+				args = append(args, s)
+			}
+			got = strings.Join(args, `, `)
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf("exp:\n%v\ngot:\n%v", exp, got)
+		}
+	}
+}
+
+func TestUnset(t *testing.T) {
+	const testRouter = `package main
+
+import "net/http"
+
+type Router struct {
+	_    struct{}     ` + "`%v`" + `
+	Root http.Handler ` + "`get:\"/\"`" + `
+	Time http.Handler ` + "`get:\"/time\" func:\"handleTime\"`" + `
+	Date http.Handler ` + "`get:\"/date\" func:\"handleDate\"`" + `
+	Echo func(http.ResponseWriter, *http.Request) ` + "`get:\"/echo\"`" + `
+	Page Page         ` + "`get:\"/page\"`" + `
+}
+
+var handleTime http.Handler
+
+var handleDate = http.NotFoundHandler()
+
+type Page struct{}
+
+func (h *Page) Get(w http.ResponseWriter, r *http.Request) {}
+`
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{``, `501 Root Time Echo`},
+		{`unset:"501"`, `501 Root Time Echo`},
+		{`unset:"500"`, `500 Root Time Echo`},
+		{`unset:"404"`, `router.go:6:28: unset tag must be 500 or 501, got "404"`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %v exp %v`, idx, test.tag, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testRouter, test.tag), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		if err != nil {
+			if exp, got := test.exp, err.Error(); exp != got {
+				t.Fatalf(`exp err %v; got %v`, exp, got)
+			}
+			continue
+		}
+		got := fmt.Sprint(r.Unset)
+		for _, name := range []string{`Root`, `Time`, `Date`, `Echo`, `Page`} {
+			for _, rt := range r.Routes {
+				if rt.Field == name && rt.Handler.Nil {
+					got += ` ` + name
+				}
+			}
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	const testRouter = `package main
+
+import "net/http"
+
+type Router struct {
+	Root http.Handler ` + "`get:\"/\"`" + `
+	%v
+}
+`
+	tests := []struct {
+		decl string
+		exp  string
+	}{
+		{``, `none`},
+		{`ErrorHandler func(http.ResponseWriter, *http.Request, error)`, `ErrorHandler nil`},
+		{"}\n\nfunc (rt *Router) ErrorHandler(w http.ResponseWriter, r *http.Request, err error) {",
+			`ErrorHandler`},
+
+		// errors
+		{`ErrorHandler http.Handler`, `router.go:7:2: Router.ErrorHandler has type ` +
+			`http.Handler, want func(http.ResponseWriter, *http.Request, error)`},
+		{`ErrorHandler func(http.ResponseWriter, error) bool`, `router.go:7:2: ` +
+			`Router.ErrorHandler has signature func(http.ResponseWriter, error) bool, ` +
+			`want func(http.ResponseWriter, *http.Request, error)`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from decl %v exp %v`, idx, test.decl, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testRouter, test.decl), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		var got string
+		r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		switch {
+		case err != nil:
+			got = err.Error()
+		case r.ErrorHandler == nil:
+			got = `none`
+		default:
+			got = r.ErrorHandler.Ident
+			if r.ErrorHandler.Nil {
+				got += ` nil`
+			}
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestStrategy(t *testing.T) {
+	const testRouter = `package main
+
+import "net/http"
+
+type Router struct {
+	_    struct{}     ` + "`%v`" + `
+	Root http.Handler ` + "`get:\"/\"`" + `
+}
+`
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{``, `linear`},
+		{`strategy:"linear"`, `linear`},
+		{`strategy:"radix"`, `radix`},
+		{`strategy:"trie"`, `router.go:6:31: strategy tag must be linear or radix, got "trie"`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %v exp %v`, idx, test.tag, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testRouter, test.tag), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		got := `linear`
+		r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		switch {
+		case err != nil:
+			got = err.Error()
+		case r.Strategy == backend.Radix:
+			got = `radix`
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	const testRouter = `package main
+
+import "net/http"
+
+type Router struct {
+	_    struct{}     ` + "`%v`" + `
+	Root http.Handler ` + "`get:\"/\"`" + `
+}
+`
+	tests := []struct {
+		tag string
+		exp backend.Match
+		err string
+	}{
+		{``, backend.Match{}, ``},
+		{`match:"runes"`, backend.Match{Runes: true}, ``},
+		{`match:"nfc, runes"`, backend.Match{Runes: true, NFC: true}, ``},
+		{`clean:"redirect" slash:"decode"`,
+			backend.Match{Clean: backend.Redirect, Slash: backend.Decode}, ``},
+		{`match:"nfc" clean:"lenient" slash:"reject"`,
+			backend.Match{NFC: true, Clean: backend.Lenient, Slash: backend.Reject}, ``},
+		{`match:"bytes"`, backend.Match{},
+			`router.go:6:28: match tag must list runes or nfc, got "bytes"`},
+		{`clean:"true"`, backend.Match{},
+			`router.go:6:28: clean tag must be strict, redirect or lenient, got "true"`},
+		{`slash:"split"`, backend.Match{},
+			`router.go:6:28: slash tag must be keep, reject or decode, got "split"`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %v exp %+v`, idx, test.tag, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testRouter, test.tag), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		if test.err != `` {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf(`exp err %v; got %v`, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp, got := test.exp, r.Match; exp != got {
+			t.Fatalf(`exp %+v; got %+v`, exp, got)
+		}
+	}
+
+	// a trailing slash is removed from each path unless strict
+	const slashRouter = `package main
+
+import "net/http"
+
+type Router struct {
+	_    struct{}     ` + "`clean:\"%v\"`" + `
+	Root http.Handler ` + "`get:\"/\"`" + `
+	Orgs http.Handler ` + "`get:\"/orgs/\"`" + `
+}
+`
+	for _, clean := range []string{`strict`, `redirect`, `lenient`} {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(slashRouter, clean), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		_, err = Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		if clean == `strict` {
+			if err != nil {
+				t.Fatalf(`exp nil err; got %v`, err)
+			}
+			continue
+		}
+		exp := `router.go:8:26: route /orgs/ ends with a slash, which is removed from ` +
+			`each path by the clean tag`
+		if err == nil || err.Error() != exp {
+			t.Fatalf(`exp err %v; got %v`, exp, err)
+		}
+	}
+}
+
+func TestCase(t *testing.T) {
+	const testRouter = `package main
+
+import "net/http"
+
+type Router struct {
+	_     struct{}     ` + "`%v`" + `
+	Root  http.Handler ` + "`get:\"/\"`" + `
+	Users http.Handler ` + "`get:\"/users\" %v`" + `
+}
+`
+	tests := []struct {
+		router, route string
+		exp           []backend.Case // of the routes Root and Users
+		err           string
+	}{
+		{``, ``, []backend.Case{backend.Exact, backend.Exact}, ``},
+		{`case:"fold"`, ``, []backend.Case{backend.Fold, backend.Fold}, ``},
+		{``, `case:"redirect"`, []backend.Case{backend.Exact, backend.FoldRedirect}, ``},
+		{`case:"redirect"`, `case:"exact"`, []backend.Case{backend.FoldRedirect, backend.Exact}, ``},
+		{`case:"upper"`, ``, nil,
+			`router.go:6:28: case tag must be exact, fold or redirect, got "upper"`},
+		{``, `case:"lower"`, nil,
+			`router.go:8:41: case tag must be exact, fold or redirect, got "lower"`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tags %v and %v exp %v`, idx, test.router, test.route, test.exp)
+		fset := token.NewFileSet()
+		src := fmt.Sprintf(testRouter, test.router, test.route)
+		f, err := parser.ParseFile(fset, `router.go`, src, 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		r, err := Analyze(fset, []*ast.File{f}, `main`, `Router`)
+		if test.err != `` {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf(`exp err %v; got %v`, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		var got []backend.Case
+		for _, rt := range r.Routes {
+			got = append(got, rt.Case)
+		}
+		if exp := test.exp; !reflect.DeepEqual(exp, got) {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package backend defines the common interface which all backends must
+// implement, along with the analyzed routes of a router struct which are given
+// to a backend for code generation.
+package backend
+
+import (
+	"go/token"
+	"io"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/parser"
+)
+
+// Backend generates the source of a router from its analyzed routes.
+type Backend interface {
+	Generate(w io.Writer, r *Router) error
+}
+
+// Router is a router struct along with each of its analyzed routes.
+type Router struct {
+	Package string   // name of the Go package declaring the router struct
+	Name    string   // name of the router struct
+	Routes  []*Route // in order of precedence
+	Unset   int      // status of the response when the handler of a route is nil
+
+	// ErrorHandler is the method or func field of the router struct named
+	// ErrorHandler which responds to the errors of handlers and param values,
+	// nil when the router struct has none.
+	ErrorHandler *Handler
+
+	// Strategy is how the generated router finds the route matching a request.
+	Strategy Strategy
+
+	// Match is how the path of a request is compared to the segments of routes.
+	Match Match
+}
+
+// Match are the options of how the path of a request is compared to the
+// segments of routes. Percent-encodings of the path are always made canonical
+// as the literals of routes are, so the zero value compares the canonical
+// escaped path exactly.
+type Match struct {
+	Runes bool // min and max count runes, and checked param values must be valid UTF-8
+	NFC   bool // each segment of the path is normalized to Unicode NFC first
+	Clean Clean
+	Slash Slash
+}
+
+// Clean is how a path which is not clean is served, where the clean form of a
+// path has no repeated slashes, dot segments or trailing slash.
+type Clean int
+
+// Policies of paths which are not clean.
+const (
+	Strict   Clean = iota // the path is matched as is
+	Redirect              // the request is redirected to the clean path
+	Lenient               // the clean path is matched
+)
+
+// Slash is how a percent-encoded slash within the path of a request is served.
+type Slash int
+
+// Policies of percent-encoded slashes.
+const (
+	Keep   Slash = iota // the slash is part of the segment, unescaped within a param value
+	Reject              // the request is not matched by any route
+	Decode              // the slash separates segments, as within the Path of a url.URL
+)
+
+// Strategy is how a generated router finds the route matching a request.
+type Strategy int
+
+// Strategies of generated routers.
+const (
+	Linear Strategy = iota // each route is tried in order of precedence
+	Radix                  // a prefix tree of static segments selects the routes tried
+)
+
+// Route is a single route of a router struct for one http method. A pattern
+// with optional params is expanded into a route for its path as declared
+// followed by a route omitting each trailing segment holding an optional param
+// in turn, where Params holds each omitted param which has a default.
+type Route struct {
+	Field   string         // name of the route field
+	Method  string         // upper case http method, empty for any method
+	Pattern string         // route pattern as written within the route tag
+	Pos     token.Position // position of the pattern within the route tag
+	Path    []parser.Segment
+	Params  []*Param // each path param followed by each query param
+	Handler Handler
+
+	// Predicates are evaluated in order once the method and path match.
+	Predicates []Predicate
+
+	// Use are the names of the middleware methods of the router struct which
+	// wrap the handler, outermost first. They are called once per router value
+	// to wrap the handler, rather than per request.
+	Use []string
+
+	// Case is how the static segments of the route compare to a path.
+	Case Case
+}
+
+// Case is how the static segments of a route compare to the path of a request,
+// where params always keep the casing of the request.
+type Case int
+
+// Kinds of case sensitivity.
+const (
+	Exact        Case = iota // static segments match the same bytes
+	Fold                     // static segments match regardless of case
+	FoldRedirect             // as Fold, redirecting requests to the casing of the route
+)
+
+// String returns the upper case method and pattern of the route.
+func (r *Route) String() string {
+	if r.Method == `` || strings.HasPrefix(r.Pattern, r.Method+` `) {
+		return r.Pattern
+	}
+	return r.Method + ` ` + r.Pattern
+}
+
+// Param returns the index of the given param within Params, or -1.
+func (r *Route) Param(prm *parser.Param) int {
+	for i, p := range r.Params {
+		if p.Param == prm {
+			return i
+		}
+	}
+	return -1
+}
+
+// Param is a path or query param along with the struct field it is bound to.
+type Param struct {
+	*parser.Param
+	Field *Field // nil when the param is not bound to a field
+}
+
+// Predicate is a condition on the headers of a request which must hold for a
+// route to serve it once its method and path match.
+type Predicate struct {
+	Kind   PredicateKind
+	Header string   // canonical name of the header
+	Values []string // lower case media types, or the value of a Header
+}
+
+// PredicateKind is the header a predicate is evaluated against and the status
+// of the response when no route which matched the path accepts a request.
+type PredicateKind int
+
+// Kinds of predicates.
+const (
+	Header   PredicateKind = iota // any header, else 404 Not Found
+	Consumes                      // Content-Type, else 415 Unsupported Media Type
+	Accept                        // Accept, else 406 Not Acceptable
+)
+
+// Field is a struct field which a param value is converted to and assigned.
+type Field struct {
+	Name     string // name of the field within the struct type it is declared by
+	Type     string // Go type of the field such as int64 or *time.Time
+	Kind     Kind
+	Bits     int     // bit size of an Int, Uint or Float, zero for int and uint
+	Ptr      bool    // the field is a pointer to its kind
+	Min, Max string  // bounds from the min and max tags, empty when unset
+	Embeds   []Embed // embedded fields the field is promoted through, outermost first
+}
+
+// Selector returns the selector of the field relative to the struct type of the
+// route, such as Orgs.Org.
+func (f *Field) Selector() string {
+	var b strings.Builder
+	for _, e := range f.Embeds {
+		b.WriteString(e.Name + `.`)
+	}
+	return b.String() + f.Name
+}
+
+// Embed is an embedded field which a promoted field is reached through.
+type Embed struct {
+	Name string // name of the embedded field
+	Type string // name of the struct type of the embedded field
+	Ptr  bool   // the embedded field is a pointer, allocated before assignment
+}
+
+// Kind is the conversion of a param value to the type of a field.
+type Kind int
+
+// Kinds of field conversions.
+const (
+	String   Kind = iota // string
+	Bytes                // []byte
+	Bool                 // strconv.ParseBool
+	Int                  // strconv.ParseInt
+	Uint                 // strconv.ParseUint
+	Float                // strconv.ParseFloat
+	Duration             // time.ParseDuration
+	Time                 // time.Parse with time.RFC3339
+	Text                 // encoding.TextUnmarshaler
+)
+
+// Handler is how the handler of a route is called.
+type Handler struct {
+	Kind  HandlerKind
+	Name  string // such as GetOrg, Users.Get or the field name
+	Ident string // name of the field, func, var or method
+	Type  string // struct type of the route field for a CallMethod
+	Err   bool   // returns an error
+	Nil   bool   // may be nil, such as a func or http.Handler field
+	Args  []Arg  // injected params following the http.ResponseWriter and *http.Request
+
+	// Pool is true when the value of the struct type of a CallMethod is taken
+	// from a sync.Pool for each request and returned once served, after calling
+	// its Reset method when Reset is true or setting it to its zero value.
+	Pool, Reset bool
+}
+
+// Arg is an injected param of a handler func.
+type Arg struct {
+	Kind ArgKind
+	Name string // name of the router field or constructor method
+	Type string // Go type of the param
+	In   string // Go type of the param of a constructor, empty if it has none
+	Err  bool   // the constructor returns an error
+}
+
+// ArgKind is the source of the value of an injected param.
+type ArgKind int
+
+// Kinds of injected params.
+const (
+	Context     ArgKind = iota // the context.Context of the request
+	Self                       // the router struct itself
+	RouterField                // a field of the router struct of the same type or which implements it
+	Constructor                // a New method of the router struct called for each request
+)
+
+// HandlerKind describes the declaration which serves a route.
+type HandlerKind int
+
+// Kinds of handlers.
+const (
+	ServeField HandlerKind = iota // the route field is a http.Handler
+	CallField                     // the route field is a func
+	ServeVar                      // a var named by a func tag is a http.Handler
+	CallFunc                      // a func or func var named by a func tag
+	CallMethod                    // a method of the struct type of the route field
+)
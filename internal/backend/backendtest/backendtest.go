@@ -0,0 +1,146 @@
+// Package backendtest builds the source generated by a backend into a program
+// which serves requests, so generated routers may be tested end to end.
+package backendtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// Request is a request to serve, where Target is the request target such as
+// /users?page=2.
+type Request struct {
+	Method string
+	Target string
+	Header map[string]string `json:",omitempty"`
+	Body   string            `json:",omitempty"`
+}
+
+// Response is the response to a Request, with the first value of each header.
+type Response struct {
+	Code   int
+	Header map[string]string
+	Body   string
+}
+
+// Program is a built program which serves requests with the http.Handler
+// returned by the newHandler func of its main package.
+type Program struct {
+	path string
+}
+
+// Build writes the given files to a main package within a temporary module and
+// builds it, where the package must declare a func newHandler() http.Handler.
+// A go.mod may be given among the files to require the modules the package
+// imports, otherwise one without requirements is written. The test is skipped
+// when the go command is not available.
+func Build(t testing.TB, files map[string][]byte) *Program {
+	t.Helper()
+	gocmd, err := exec.LookPath(`go`)
+	if err != nil {
+		gocmd = filepath.Join(runtime.GOROOT(), `bin`, `go`)
+		if _, err := os.Stat(gocmd); err != nil {
+			t.Skip(`go command not found`)
+		}
+	}
+
+	dir := t.TempDir()
+	if _, ok := files[`go.mod`]; !ok {
+		files[`go.mod`] = []byte("module backendtest\n\ngo 1.18\n")
+	}
+	files[`backendtest_main.go`] = []byte(mainSrc)
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), src, 0600); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+	}
+
+	prog := &Program{path: filepath.Join(dir, `prog`)}
+	cmd := exec.Command(gocmd, `build`, `-o`, prog.path, `.`)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), `GO111MODULE=on`, `GOWORK=off`, `GOFLAGS=-mod=mod`)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("exp nil err building program; got %v\n%s", err, out)
+	}
+	return prog
+}
+
+// Serve serves each request with the program, returning each response.
+func (p *Program) Serve(t testing.TB, reqs []Request) []Response {
+	t.Helper()
+	var in bytes.Buffer
+	enc := json.NewEncoder(&in)
+	for _, req := range reqs {
+		if err := enc.Encode(req); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+	}
+
+	cmd := exec.Command(p.path)
+	cmd.Stdin = &in
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("exp nil err serving requests; got %v\n%s", err, stderr.Bytes())
+	}
+
+	res := make([]Response, len(reqs))
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for i := range res {
+		if err := dec.Decode(&res[i]); err != nil {
+			t.Fatalf(`exp nil err decoding response #%d; got %v`, i, err)
+		}
+	}
+	return res
+}
+
+const mainSrc = `package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+)
+
+func main() {
+	h := newHandler()
+	dec, enc := json.NewDecoder(os.Stdin), json.NewEncoder(os.Stdout)
+	for {
+		var req struct {
+			Method, Target, Body string
+			Header               map[string]string
+		}
+		if err := dec.Decode(&req); err == io.EOF {
+			return
+		} else if err != nil {
+			panic(err)
+		}
+
+		r := httptest.NewRequest(req.Method, req.Target, strings.NewReader(req.Body))
+		for k, v := range req.Header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		header := make(map[string]string)
+		for k := range w.Header() {
+			header[k] = w.Header().Get(k)
+		}
+		if err := enc.Encode(map[string]interface{}{
+			"Code": w.Code, "Header": header, "Body": w.Body.String(),
+		}); err != nil {
+			panic(err)
+		}
+	}
+}
+`
@@ -0,0 +1,283 @@
+package gosrc
+
+import (
+	"fmt"
+	gofmt "go/format"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cstockton/routepiler/internal/backend"
+)
+
+// Bench returns the formatted Go source of a test file benchmarking a request
+// for each route of a router served by a method of a handler struct, reporting
+// the allocations of each. Routes of a pooled handler struct are benchmarked in
+// pooled and unpooled sub-benchmarks so the pool option may be compared. Routes
+// with injected params other than the context or the router itself, or with a
+// param no sample value satisfies, are not benchmarked.
+func Bench(r *backend.Router) ([]byte, error) {
+	g := &gen{router: r, prefix: strings.ToLower(r.Name[:1]) + r.Name[1:]}
+
+	type bench struct {
+		rt             *backend.Route
+		method, target string
+	}
+	var benches []bench
+	var pooled bool
+	for _, rt := range r.Routes {
+		target, ok := benchTarget(rt)
+		if !ok {
+			continue
+		}
+		benches = append(benches, bench{rt, benchMethod(r, rt), target})
+		pooled = pooled || rt.Handler.Pool
+	}
+
+	fmt.Fprintf(&g.buf, "// Code generated by routepiler from %v. DO NOT EDIT.\n\n", r.Name)
+	fmt.Fprintf(&g.buf, "package %v\n\n", r.Package)
+	g.p(`import (`)
+	g.p(`"net/http"`)
+	g.p(`"net/http/httptest"`)
+	g.p(`"testing"`)
+	g.p(`)`)
+	g.p(``)
+	g.p(`// Benchmark%v serves a request matching each route of %v which is served`,
+		r.Name, r.Name)
+	g.p(`// by a method of a handler struct, reporting the allocations of each.`)
+	if pooled {
+		g.p(`// Routes of a pooled handler struct are served both pooled and unpooled.`)
+	}
+	g.p(`func Benchmark%v(b *testing.B) {`, r.Name)
+	g.p(`rt := new(%v)`, r.Name)
+	if pooled {
+		g.p(`defer func() { %vUnpooled = false }()`, g.prefix)
+	}
+	g.p(`for _, bench := range []struct {`)
+	g.p(`name, method, target string`)
+	g.p(`header               []string // pairs of header names and values`)
+	if pooled {
+		g.p(`pooled               bool`)
+	}
+	g.p(`}{`)
+	for _, bench := range benches {
+		rt := bench.rt
+		header := `nil`
+		if len(rt.Predicates) > 0 {
+			var pairs []string
+			for _, pred := range rt.Predicates {
+				pairs = append(pairs, strconv.Quote(pred.Header), strconv.Quote(pred.Values[0]))
+			}
+			header = `[]string{` + strings.Join(pairs, `, `) + `}`
+		}
+		if pooled {
+			g.p(`{%q, %q, %q, %v, %v},`, rt.String(), bench.method, bench.target, header,
+				rt.Handler.Pool)
+		} else {
+			g.p(`{%q, %q, %q, %v},`, rt.String(), bench.method, bench.target, header)
+		}
+	}
+	g.p(`} {`)
+	g.p(`bench := bench`)
+	g.p(`serve := func(b *testing.B) {`)
+	g.p(`r := httptest.NewRequest(bench.method, bench.target, nil)`)
+	g.p(`for i := 0; i < len(bench.header); i += 2 {`)
+	g.p(`r.Header.Set(bench.header[i], bench.header[i+1])`)
+	g.p(`}`)
+	g.p(`w := %vBenchWriter{header: make(http.Header)}`, g.prefix)
+	g.p(`b.ReportAllocs()`)
+	g.p(`b.ResetTimer()`)
+	g.p(`for i := 0; i < b.N; i++ {`)
+	g.p(`rt.ServeHTTP(w, r)`)
+	g.p(`}`)
+	g.p(`}`)
+	if pooled {
+		g.p(`if !bench.pooled {`)
+		g.p(`b.Run(bench.name, serve)`)
+		g.p(`continue`)
+		g.p(`}`)
+		g.p(`b.Run(bench.name+"/pooled", func(b *testing.B) {`)
+		g.p(`%vUnpooled = false`, g.prefix)
+		g.p(`serve(b)`)
+		g.p(`})`)
+		g.p(`b.Run(bench.name+"/unpooled", func(b *testing.B) {`)
+		g.p(`%vUnpooled = true`, g.prefix)
+		g.p(`serve(b)`)
+		g.p(`})`)
+	} else {
+		g.p(`b.Run(bench.name, serve)`)
+	}
+	g.p(`}`)
+	g.p(`}`)
+	g.p(``)
+	g.p(`// %vBenchWriter is a http.ResponseWriter which discards each response, so`,
+		g.prefix)
+	g.p(`// only the allocations of the router are reported.`)
+	g.p(`type %vBenchWriter struct {`, g.prefix)
+	g.p(`header http.Header`)
+	g.p(`}`)
+	g.p(``)
+	g.p(`func (w %vBenchWriter) Header() http.Header         { return w.header }`, g.prefix)
+	g.p(`func (w %vBenchWriter) Write(p []byte) (int, error) { return len(p), nil }`, g.prefix)
+	g.p(`func (w %vBenchWriter) WriteHeader(int)             {}`, g.prefix)
+
+	src, err := gofmt.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf(`unable to format generated source: %v`, err)
+	}
+	return src, nil
+}
+
+// benchMethod returns the method of a request for a route, which for a route
+// of any method is the first method no other route of the same path claims.
+func benchMethod(r *backend.Router, rt *backend.Route) string {
+	if rt.Method != `` {
+		return rt.Method
+	}
+	claimed := make(map[string]bool)
+	for _, other := range r.Routes {
+		if other.Method != `` && benchShape(other) == benchShape(rt) {
+			claimed[other.Method] = true
+		}
+	}
+	for _, method := range []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions,
+	} {
+		if !claimed[method] {
+			return method
+		}
+	}
+	return `PURGE`
+}
+
+// benchShape returns the path of a route with each param replaced by a colon,
+// so routes differing only in the names of their params are the same shape.
+func benchShape(rt *backend.Route) string {
+	var b strings.Builder
+	for _, seg := range rt.Path {
+		b.WriteByte('/')
+		for _, part := range seg {
+			if part.Param == nil {
+				b.WriteString(part.Lit)
+			} else if part.Param.Wild {
+				b.WriteString(`*`)
+			} else {
+				b.WriteString(`:`)
+			}
+		}
+	}
+	return b.String()
+}
+
+// benchTarget returns the target of a request matching a route, or false if
+// the route is not benchmarked.
+func benchTarget(rt *backend.Route) (string, bool) {
+	if rt.Handler.Kind != backend.CallMethod {
+		return ``, false
+	}
+	for _, arg := range rt.Handler.Args {
+		if arg.Kind != backend.Context && arg.Kind != backend.Self {
+			return ``, false
+		}
+	}
+
+	var b strings.Builder
+	var query []string
+	for _, seg := range rt.Path {
+		b.WriteByte('/')
+		for _, part := range seg {
+			if part.Param == nil {
+				b.WriteString(part.Lit)
+				continue
+			}
+			v, ok := sample(rt.Params[rt.Param(part.Param)])
+			if !ok {
+				return ``, false
+			}
+			b.WriteString(url.PathEscape(v))
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteByte('/')
+	}
+	for _, p := range rt.Params {
+		if !p.Query || !p.Required {
+			continue
+		}
+		v, ok := sample(p)
+		if !ok {
+			return ``, false
+		}
+		query = append(query, url.QueryEscape(p.Name)+`=`+url.QueryEscape(v))
+	}
+	if len(query) > 0 {
+		b.WriteString(`?` + strings.Join(query, `&`))
+	}
+	return b.String(), true
+}
+
+// sample returns a value of a param which matches its regexp and bounds and
+// converts to the field it is bound to, or false if no candidate does.
+func sample(p *backend.Param) (string, bool) {
+	cands := []string{`a`, `abc`, `a1`, `1`, `12`}
+	if f := p.Field; f != nil {
+		switch f.Kind {
+		case backend.Bool:
+			cands = []string{`true`}
+		case backend.Int, backend.Uint, backend.Float:
+			cands = []string{`1`, `12`, `100`}
+		case backend.Duration:
+			cands = []string{`1s`, `1m`}
+		case backend.Time:
+			cands = []string{`2006-01-02T15:04:05Z`}
+		case backend.Text:
+			cands = nil
+		}
+	}
+	var re *regexp.Regexp
+	if p.Regexp != `` {
+		var err error
+		if re, err = regexp.Compile(`^(?:` + p.Regexp + `)$`); err != nil {
+			return ``, false
+		}
+	}
+	for _, v := range cands {
+		n := len(v)
+		switch {
+		case re != nil && !re.MatchString(v),
+			p.Min > 0 && n < p.Min,
+			p.Max > 0 && n > p.Max,
+			p.Field != nil && !inBounds(p.Field, v):
+			continue
+		}
+		return v, true
+	}
+	return ``, false
+}
+
+// inBounds returns true if the value v of a field is within its min and max.
+func inBounds(f *backend.Field, v string) bool {
+	var n float64
+	switch f.Kind {
+	case backend.String, backend.Bytes:
+		n = float64(len(v))
+	case backend.Int, backend.Uint, backend.Float:
+		n, _ = strconv.ParseFloat(v, 64)
+	case backend.Duration:
+		d, _ := time.ParseDuration(v)
+		n = float64(d)
+	default:
+		return true
+	}
+	if min, err := strconv.ParseFloat(f.Min, 64); err == nil && n < min {
+		return false
+	}
+	if max, err := strconv.ParseFloat(f.Max, 64); err == nil && n > max {
+		return false
+	}
+	return true
+}
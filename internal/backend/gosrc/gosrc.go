@@ -0,0 +1,1083 @@
+// Package gosrc implements the backend interface by generating Go source code
+// from one or more analyzed routes.
+//
+// The generated file declares a ServeHTTP method for the router struct which
+// tries each route in order of precedence, serving the request with the handler
+// of the first route to match. With the Radix strategy a prefix tree of the
+// route segments is walked instead, switching on each static segment of the
+// path, so only the routes below it are tried. The escaped path of the request
+// is first given canonical percent-encodings, then normalized by the options of
+// the router. Each route is matched by a func which walks the path one segment
+// at a time, so literals are compared without unescaping the path and a
+// percent-encoded slash within a param value does not separate segments. Param
+// values are unescaped once matched, then converted to the types of the struct
+// fields they are bound to. Query params are read from the raw query without
+// allocating a url.Values. Errors returned by handlers and values which can not
+// be converted, as a <Router>ParamError, are responded to by the ErrorHandler
+// of the router struct when it has one.
+//
+// Each declaration of the generated file other than ServeHTTP is prefixed with
+// the name of the router struct, so more than one router struct may be generated
+// within a single package.
+package gosrc
+
+import (
+	"bytes"
+	"fmt"
+	gofmt "go/format"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/backend"
+	"github.com/cstockton/routepiler/internal/parser"
+)
+
+// Backend generates Go source code.
+type Backend struct{}
+
+// New returns a new Go source backend.
+func New() *Backend { return &Backend{} }
+
+// Generate writes the Go source of the ServeHTTP method of a router to w.
+func (b *Backend) Generate(w io.Writer, r *backend.Router) error {
+	src, err := Source(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// Source returns the formatted Go source of the ServeHTTP method of a router.
+func Source(r *backend.Router) ([]byte, error) {
+	g := &gen{
+		router:  r,
+		prefix:  strings.ToLower(r.Name[:1]) + r.Name[1:],
+		imports: map[string]bool{`net/http`: true},
+	}
+	g.file()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by routepiler from %v. DO NOT EDIT.\n\n", r.Name)
+	fmt.Fprintf(&buf, "package %v\n\nimport (\n", r.Package)
+	var imports []string
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		if si, sj := std(imports[i]), std(imports[j]); si != sj {
+			return si
+		}
+		return imports[i] < imports[j]
+	})
+	for i, path := range imports {
+		if i > 0 && std(path) != std(imports[i-1]) {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "%q\n", path)
+	}
+	buf.WriteString(")\n")
+	buf.Write(g.buf.Bytes())
+
+	src, err := gofmt.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf(`unable to format generated source: %v`, err)
+	}
+	return src, nil
+}
+
+// std returns true if an import path is of the standard library, which has no
+// dot within its first element.
+func std(path string) bool {
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[:i]
+	}
+	return !strings.Contains(path, `.`)
+}
+
+type gen struct {
+	router  *backend.Router
+	prefix  string // prefix of each generated declaration
+	imports map[string]bool
+	helpers map[string]bool // helper funcs which are used
+	errs    bool            // an error is responded to by the error method
+	params  bool            // a param value may fail to convert to its field
+	pooled  map[string]bool // struct types of handlers which are pooled
+	chains  []int           // routes which are served through their middleware
+	decls   []string        // package level declarations of the current func
+	buf     bytes.Buffer
+}
+
+func (g *gen) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *gen) use(pkgs ...string) {
+	for _, pkg := range pkgs {
+		g.imports[pkg] = true
+	}
+}
+
+func (g *gen) helper(name string) string {
+	if g.helpers == nil {
+		g.helpers = make(map[string]bool)
+	}
+	g.helpers[name] = true
+	return g.prefix + name
+}
+
+// file writes each declaration of the generated file.
+func (g *gen) file() {
+	r := g.router
+	var max int
+	var status bool
+	for _, rt := range r.Routes {
+		if len(rt.Params) > max {
+			max = len(rt.Params)
+		}
+		for _, pred := range rt.Predicates {
+			status = status || pred.Kind != backend.Header
+		}
+	}
+
+	g.p(``)
+	g.p(`// ServeHTTP serves each request with the handler of the first route of %v`, r.Name)
+	g.p(`// which matches it, in order of precedence, or responds 404 Not Found.`)
+	g.p(`func (rt *%v) ServeHTTP(w http.ResponseWriter, r *http.Request) {`, r.Name)
+	if len(r.Routes) > 0 {
+		g.p(`var v %vValues`, g.prefix)
+		g.path()
+	}
+	if status {
+		g.p(`status := http.StatusNotFound`)
+	}
+	if r.Strategy == backend.Radix {
+		g.radix()
+	} else {
+		for i, rt := range r.Routes {
+			g.try(i, rt)
+		}
+	}
+	if status {
+		g.p(`if status != http.StatusNotFound {`)
+		g.p(`http.Error(w, http.StatusText(status), status)`)
+		g.p(`return`)
+		g.p(`}`)
+	}
+	g.p(`http.NotFound(w, r)`)
+	g.p(`}`)
+	g.validate()
+
+	g.p(``)
+	g.p(`// %vValues holds the param values of the route matching a request.`, g.prefix)
+	g.p(`type %vValues struct {`, g.prefix)
+	g.p(`vs  [%d]string // unescaped value of each path param then each query param`, max)
+	g.p(`set uint64 // bitmask of the query params which are present or have a default`)
+	g.p(`}`)
+
+	for i, rt := range r.Routes {
+		g.match(i, rt)
+		if rt.Case == backend.FoldRedirect {
+			g.canonical(i, rt)
+		}
+		g.serve(i, rt)
+	}
+	g.chain()
+	g.pools()
+	g.errors()
+	g.helpersFile()
+}
+
+// path writes the statements of ServeHTTP declaring the escaped path matched
+// by routes, with canonical percent-encodings and normalized by the match,
+// slash and clean options of the router.
+func (g *gen) path() {
+	m := g.router.Match
+	g.helper(`Escape`)
+	g.p(`path := %v(r.URL.EscapedPath())`, g.helper(`Canonical`))
+	if m.NFC {
+		g.p(`path = %v(path)`, g.helper(`NFC`))
+	}
+	switch m.Slash {
+	case backend.Reject:
+		g.p(`if strings.Contains(path, "%%2F") {`)
+		g.p(`http.NotFound(w, r)`)
+		g.p(`return`)
+		g.p(`}`)
+	case backend.Decode:
+		g.p(`path = strings.ReplaceAll(path, "%%2F", "/")`)
+	}
+	switch m.Clean {
+	case backend.Redirect:
+		g.p(`if clean, ok := %v(path); ok {`, g.helper(`Clean`))
+		g.p(`%v(w, r, clean)`, g.helper(`Redirect`))
+		g.p(`return`)
+		g.p(`}`)
+	case backend.Lenient:
+		g.p(`path, _ = %v(path)`, g.helper(`Clean`))
+	}
+}
+
+// try writes the statements serving a request with route i and returning when
+// its method, header predicates and path match.
+func (g *gen) try(i int, rt *backend.Route) {
+	var conds []string
+	if rt.Method != `` {
+		conds = append(conds, fmt.Sprintf(`r.Method == %q`, rt.Method))
+	}
+	var negotiate []backend.Predicate
+	for _, pred := range rt.Predicates {
+		if pred.Kind == backend.Header {
+			conds = append(conds, fmt.Sprintf(`r.Header.Get(%q) == %q`,
+				pred.Header, pred.Values[0]))
+		} else {
+			negotiate = append(negotiate, pred)
+		}
+	}
+	conds = append(conds, fmt.Sprintf(`%vMatch%d(path, r.URL.RawQuery, &v)`, g.prefix, i))
+	g.p(`if %v {`, strings.Join(conds, ` && `))
+	if rt.Case == backend.FoldRedirect {
+		g.p(`if p, ok := %vCase%d(path); ok {`, g.prefix, i)
+		g.p(`%v(w, r, p)`, g.helper(`Redirect`))
+		g.p(`return`)
+		g.p(`}`)
+	}
+	if len(negotiate) > 0 {
+		g.p(`switch {`)
+		for _, pred := range negotiate {
+			g.negotiate(pred)
+		}
+		g.p(`default:`)
+	}
+	g.p(`rt.%vServe%d(w, r, &v)`, g.prefix, i)
+	g.p(`return`)
+	if len(negotiate) > 0 {
+		g.p(`}`)
+	}
+	g.p(`}`)
+}
+
+// validate writes the Validate and MustValidate methods of the router, which
+// report each route field with a nil handler in order of declaration.
+func (g *gen) validate() {
+	r := g.router
+	routes := append([]*backend.Route(nil), r.Routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Pos.Offset < routes[j].Pos.Offset
+	})
+
+	g.p(``)
+	g.p(`// Validate returns an error naming each route field of %v with a nil`, r.Name)
+	g.p(`// handler, which responds %d %v until it is set.`, r.Unset, http.StatusText(r.Unset))
+	g.p(`func (rt *%v) Validate() error {`, r.Name)
+	seen := make(map[string]bool)
+	var unset bool
+	for _, rt := range routes {
+		h := rt.Handler
+		if !h.Nil || seen[rt.Field+` `+rt.Pattern] {
+			continue
+		}
+		if !unset {
+			g.p(`var unset []string`)
+			unset = true
+		}
+		seen[rt.Field+` `+rt.Pattern] = true
+		msg := rt.Field + ` for ` + rt.String()
+		if h.Kind == backend.ServeVar || h.Kind == backend.CallFunc {
+			msg += ` (var ` + h.Ident + `)`
+		}
+		g.p(`if %v == nil {`, value(h))
+		g.p(`unset = append(unset, %q)`, msg)
+		g.p(`}`)
+	}
+	if unset {
+		g.use(`errors`, `strings`)
+		g.p(`if len(unset) > 0 {`)
+		g.p(`return errors.New(%q + strings.Join(unset, ", "))`, r.Name+` has unset handlers: `)
+		g.p(`}`)
+	}
+	g.p(`return nil`)
+	g.p(`}`)
+
+	g.p(``)
+	g.p(`// MustValidate panics if Validate returns an error, for use once a %v is`, r.Name)
+	g.p(`// initialized.`)
+	g.p(`func (rt *%v) MustValidate() {`, r.Name)
+	g.p(`if err := rt.Validate(); err != nil {`)
+	g.p(`panic(err)`)
+	g.p(`}`)
+	g.p(`}`)
+}
+
+// value returns the expression of the field or var holding a handler.
+func value(h backend.Handler) string {
+	if h.Kind == backend.ServeField || h.Kind == backend.CallField {
+		return `rt.` + h.Ident
+	}
+	return h.Ident
+}
+
+// negotiate writes the case of a switch statement which is true when a request
+// fails a Consumes or Accept predicate, recording the status of the response
+// unless a route of higher precedence already has.
+func (g *gen) negotiate(pred backend.Predicate) {
+	var values []string
+	for _, v := range pred.Values {
+		values = append(values, strconv.Quote(v))
+	}
+	name, status := `Consumes`, `http.StatusUnsupportedMediaType`
+	if pred.Kind == backend.Accept {
+		name, status = `Accepts`, `http.StatusNotAcceptable`
+	}
+	g.p(`case !%v(r, %v):`, g.helper(name), strings.Join(values, `, `))
+	g.p(`if status == http.StatusNotFound {`)
+	g.p(`status = %v`, status)
+	g.p(`}`)
+}
+
+// match writes the func which returns true if route i matches a request.
+func (g *gen) match(i int, rt *backend.Route) {
+	g.p(``)
+	g.p(`// %vMatch%d matches %v.`, g.prefix, i, comment(rt.String()))
+	g.p(`func %vMatch%d(path, query string, v *%vValues) bool {`, g.prefix, i, g.prefix)
+
+	wild := -1
+	for si, seg := range rt.Path {
+		if analyze.Wild(seg) != nil {
+			wild = si
+		}
+	}
+	n := len(rt.Path)
+	g.use(`strings`)
+	if wild < 0 {
+		g.p(`if strings.Count(path, "/") != %d {`, n)
+	} else {
+		g.p(`if strings.Count(path, "/") < %d {`, n)
+	}
+	g.p(`return false`)
+	g.p(`}`)
+
+	g.p(`var seg string`)
+	for si, seg := range rt.Path {
+		if si == wild {
+			g.p(`seg, path = %v(path, %d)`, g.helper(`Wild`), n-si-1)
+		} else {
+			g.p(`seg, path = %v(path)`, g.helper(`Next`))
+		}
+		g.segment(i, si, rt, seg)
+	}
+	for j, p := range rt.Params {
+		if !p.Query && !present(rt, p) {
+			g.p(`v.vs[%d] = %q`, j, p.Default)
+		}
+	}
+	g.query(i, rt)
+	g.p(`return true`)
+	g.p(`}`)
+	g.flush()
+}
+
+// canonical writes the func which returns the path matched by route i with each
+// static segment in the casing of the route, along with true if it differs.
+func (g *gen) canonical(i int, rt *backend.Route) {
+	g.p(``)
+	g.p(`// %vCase%d returns a path matching %v in the casing of the route.`,
+		g.prefix, i, comment(rt.String()))
+	g.p(`func %vCase%d(path string) (string, bool) {`, g.prefix, i)
+
+	wild := -1
+	for si, seg := range rt.Path {
+		if analyze.Wild(seg) != nil {
+			wild = si
+		}
+	}
+	n := len(rt.Path)
+	g.p(`var b strings.Builder`)
+	g.p(`b.Grow(len(path))`)
+	g.p(`p := path`)
+	for _, seg := range rt.Path {
+		if !seg.Static() {
+			g.p(`var seg string`)
+			break
+		}
+	}
+	for si, seg := range rt.Path {
+		src := `_`
+		if !seg.Static() {
+			src = `seg`
+		}
+		if si == wild {
+			g.p(`%v, p = %v(p, %d)`, src, g.helper(`Wild`), n-si-1)
+		} else {
+			g.p(`%v, p = %v(p)`, src, g.helper(`Next`))
+		}
+		if seg.Static() {
+			g.p(`b.WriteString(%q)`, `/`+seg.String())
+		} else {
+			g.p(`b.WriteString("/" + seg)`)
+		}
+	}
+	g.p(`return b.String(), b.String() != path`)
+	g.p(`}`)
+}
+
+// present returns true if the path of a route holds a path param, which is
+// otherwise an omitted optional param with a default.
+func present(rt *backend.Route, p *backend.Param) bool {
+	for _, seg := range rt.Path {
+		for _, part := range seg {
+			if part.Param == p.Param {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flush writes the package level declarations deferred while writing a func.
+func (g *gen) flush() {
+	for _, decl := range g.decls {
+		g.p(``)
+		g.p(`%v`, decl)
+	}
+	g.decls = g.decls[:0]
+}
+
+// segment writes the statements matching the path segment si of route i, held
+// within the variable seg.
+func (g *gen) segment(i, si int, rt *backend.Route, seg parser.Segment) {
+	switch {
+	case seg.Static() && rt.Case != backend.Exact:
+		g.p(`if !%v {`, g.fold(`seg`, seg.String()))
+		g.p(`return false`)
+		g.p(`}`)
+	case seg.Static():
+		g.p(`if seg != %q {`, seg.String())
+		g.p(`return false`)
+		g.p(`}`)
+	case len(seg) == 1:
+		prm := seg[0].Param
+		dst := fmt.Sprintf(`v.vs[%d]`, rt.Param(prm))
+		g.p(`if seg == "" {`)
+		g.p(`return false`)
+		g.p(`}`)
+		if prm.Depth > 0 {
+			g.p(`if strings.Count(seg, "/") >= %d {`, prm.Depth)
+			g.p(`return false`)
+			g.p(`}`)
+		}
+		g.p(`%v = %v(seg)`, dst, g.helper(`Unescape`))
+		if cond := g.check(i, prm, dst); cond != `` {
+			g.p(`if %v {`, cond)
+			g.p(`return false`)
+			g.p(`}`)
+		}
+	default:
+		g.helper(`Part`)
+		g.helper(`Unescape`) // called by the match method of Part
+		g.p(`if !%v(seg, %vParts%d_%d[:], v.vs[:]) {`, g.helper(`Parts`), g.prefix, i, si)
+		g.p(`return false`)
+		g.p(`}`)
+		g.parts(i, si, rt, seg)
+	}
+}
+
+// parts writes the parts of a path segment containing literals and params as
+// a package level array, deferred until the enclosing func is written.
+func (g *gen) parts(i, si int, rt *backend.Route, seg parser.Segment) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "var %vParts%d_%d = [...]%vPart{\n", g.prefix, i, si, g.prefix)
+	for _, part := range seg {
+		prm := part.Param
+		if prm == nil {
+			fmt.Fprintf(&b, "{lit: %q},\n", part.Lit)
+			continue
+		}
+		fmt.Fprintf(&b, "{param: %d", rt.Param(prm))
+		if prm.Depth > 0 {
+			fmt.Fprintf(&b, ", depth: %d", prm.Depth)
+		}
+		if cond := g.check(i, prm, `v`); cond != `` {
+			fmt.Fprintf(&b, ", check: func(v string) bool { return !(%v) }", cond)
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}")
+	g.decls = append(g.decls, b.String())
+}
+
+// fold returns a condition which is true when the escaped segment held by the
+// expression v equals the escaped literal lit regardless of case. Literals of
+// ASCII are compared without unescaping v and fold only ASCII letters.
+func (g *gen) fold(v, lit string) string {
+	u := unescape(lit)
+	for i := 0; i < len(u); i++ {
+		if u[i] >= utf8.RuneSelf {
+			g.helper(`Unescape`)
+			return fmt.Sprintf(`%v(%v, %q)`, g.helper(`Fold`), v, u)
+		}
+	}
+	return fmt.Sprintf(`%v(%v, %q)`, g.helper(`FoldASCII`), v, lit)
+}
+
+// unescape returns the unescaped form of an escaped literal, or the literal
+// when it is not validly escaped.
+func unescape(lit string) string {
+	if v, err := url.PathUnescape(lit); err == nil {
+		return v
+	}
+	return lit
+}
+
+// check returns a condition which is true when the unescaped value held by the
+// expression v does not meet the bounds of a param, or an empty string. When
+// the Runes option of the router is set the bounds count runes and the value
+// must be valid UTF-8.
+func (g *gen) check(i int, prm *parser.Param, v string) string {
+	var conds []string
+	if g.router.Match.Runes && (prm.Min > 0 || prm.Max > 0 || prm.Regexp != ``) {
+		g.use(`unicode/utf8`)
+		conds = append(conds, fmt.Sprintf(`!utf8.ValidString(%v)`, v))
+	}
+	switch n := g.length(v); {
+	case prm.Min > 0 && prm.Max > 0:
+		conds = append(conds, fmt.Sprintf(`%v < %d || %v > %d`, n, prm.Min, n, prm.Max))
+	case prm.Min > 0:
+		conds = append(conds, fmt.Sprintf(`%v < %d`, n, prm.Min))
+	case prm.Max > 0:
+		conds = append(conds, fmt.Sprintf(`%v > %d`, n, prm.Max))
+	}
+	if prm.Regexp != `` {
+		conds = append(conds, fmt.Sprintf(`!%v.MatchString(%v)`, g.regexp(i, prm), v))
+	}
+	return strings.Join(conds, ` || `)
+}
+
+// length returns the expression of the length of the string held by the
+// expression v, in runes when the Runes option of the router is set, in which
+// case the caller imports unicode/utf8 once it is used.
+func (g *gen) length(v string) string {
+	if !g.router.Match.Runes {
+		return `len(` + v + `)`
+	}
+	return `utf8.RuneCountInString(` + v + `)`
+}
+
+// regexp returns the name of the package level var holding the anchored regexp
+// of a param of route i.
+func (g *gen) regexp(i int, prm *parser.Param) string {
+	g.use(`regexp`)
+	name := fmt.Sprintf(`%vRegexp%d_%v`, g.prefix, i, prm.Name)
+	g.decls = append(g.decls, fmt.Sprintf(`var %v = regexp.MustCompile(%v)`,
+		name, strconv.Quote(`^(?:`+prm.Regexp+`)$`)))
+	return name
+}
+
+// query writes the statements reading the query params of a route into v.
+func (g *gen) query(i int, rt *backend.Route) {
+	first := -1
+	for j, p := range rt.Params {
+		if p.Query && first < 0 {
+			first = j
+		}
+	}
+	if first < 0 {
+		return
+	}
+
+	var names []string
+	for _, p := range rt.Params[first:] {
+		names = append(names, strconv.Quote(p.Name))
+	}
+	g.p(`set := %v(query, []string{%v}, v.vs[%d:%d])`, g.helper(`Query`),
+		strings.Join(names, `, `), first, len(rt.Params))
+	for j, p := range rt.Params[first:] {
+		dst, bit := fmt.Sprintf(`v.vs[%d]`, first+j), uint64(1)<<uint(j)
+		if cond := g.check(i, p.Param, dst); cond != `` {
+			g.p(`if set&%#x != 0 && (%v) {`, bit, cond)
+			g.p(`return false`)
+			g.p(`}`)
+		}
+		switch {
+		case p.Required:
+			g.p(`if set&%#x == 0 {`, bit)
+			g.p(`return false`)
+			g.p(`}`)
+		case p.Default != ``:
+			g.p(`if set&%#x == 0 {`, bit)
+			g.p(`%v, set = %q, set|%#x`, dst, p.Default, bit)
+			g.p(`}`)
+		}
+	}
+	g.p(`v.set = set`)
+}
+
+// serve writes the method which serves a request matching route i. A route
+// with middleware is served through the handler wrapped once per router by the
+// chain method, which reads the param values from the request context.
+func (g *gen) serve(i int, rt *backend.Route) {
+	g.p(``)
+	if len(rt.Use) == 0 {
+		g.p(`// %vServe%d serves %v with %v.`, g.prefix, i, comment(rt.String()), rt.Handler.Name)
+	} else {
+		g.p(`// %vServe%d serves %v with %v through the %v middleware.`, g.prefix, i,
+			comment(rt.String()), rt.Handler.Name, strings.Join(rt.Use, `, `))
+	}
+	g.p(`func (rt *%v) %vServe%d(w http.ResponseWriter, r *http.Request, v *%vValues) {`,
+		g.router.Name, g.prefix, i, g.prefix)
+
+	h := rt.Handler
+	if h.Nil {
+		status := `http.StatusNotImplemented`
+		if g.router.Unset == http.StatusInternalServerError {
+			status = `http.StatusInternalServerError`
+		}
+		g.p(`if %v == nil {`, value(h))
+		g.p(`http.Error(w, http.StatusText(%v), %v)`, status, status)
+		g.p(`return`)
+		g.p(`}`)
+	}
+	if len(rt.Use) == 0 {
+		g.handle(rt)
+		g.p(`}`)
+		return
+	}
+
+	// the values are copied so only the requests of routes binding params pay
+	// for the context they are carried by
+	bound := false
+	for _, p := range rt.Params {
+		bound = bound || h.Kind == backend.CallMethod && p.Field != nil
+	}
+	if bound {
+		g.use(`context`)
+		g.p(`vs := *v`)
+		g.p(`r = r.WithContext(context.WithValue(r.Context(), %vValuesKey{}, &vs))`, g.prefix)
+	}
+	g.p(`rt.%vChain().serve%d.ServeHTTP(w, r)`, g.prefix, i)
+	g.p(`}`)
+	g.chains = append(g.chains, i)
+
+	g.p(``)
+	g.p(`// %vHandle%d serves %v with %v once its middleware has run.`,
+		g.prefix, i, comment(rt.String()), rt.Handler.Name)
+	g.p(`func (rt *%v) %vHandle%d(w http.ResponseWriter, r *http.Request) {`,
+		g.router.Name, g.prefix, i)
+	if bound {
+		g.p(`v := r.Context().Value(%vValuesKey{}).(*%vValues)`, g.prefix, g.prefix)
+	}
+	g.handle(rt)
+	g.p(`}`)
+}
+
+// handle writes the statements binding the param values v of a request to the
+// handler of rt and calling it.
+func (g *gen) handle(rt *backend.Route) {
+	h := rt.Handler
+	if h.Kind == backend.CallMethod {
+		g.alloc(h)
+		g.embeds(rt)
+		var j int
+		for pi, p := range rt.Params {
+			bit := uint64(1) << uint(j)
+			if p.Query {
+				j++
+			}
+			if p.Field == nil {
+				continue
+			}
+			src, scope := fmt.Sprintf(`v.vs[%d]`, pi), ``
+			if p.Query {
+				scope = fmt.Sprintf(`if v.set&%#x != 0`, bit)
+			}
+			g.bind(p, scope, `h.`+p.Field.Selector(), src)
+		}
+	}
+
+	switch h.Kind {
+	case backend.ServeField:
+		g.p(`rt.%v.ServeHTTP(w, r)`, h.Ident)
+	case backend.CallField:
+		g.call(h, `rt.`+h.Ident)
+	case backend.ServeVar:
+		g.p(`%v.ServeHTTP(w, r)`, h.Ident)
+	case backend.CallFunc:
+		g.call(h, h.Ident)
+	case backend.CallMethod:
+		g.call(h, `h.`+h.Ident)
+	}
+}
+
+// chain writes the chain method of the router, which wraps the handler of each
+// route with middleware in its middleware once per router and holds it for the
+// requests that follow.
+func (g *gen) chain() {
+	if len(g.chains) == 0 {
+		return
+	}
+	r := g.router
+	routes := r.Routes
+	g.use(`sync`)
+
+	g.p(``)
+	g.p(`// %vChains holds the handlers of each %v which has served a request,`, g.prefix, r.Name)
+	g.p(`// for as long as the program runs.`)
+	g.p(`var %vChains sync.Map // map[*%v]*%vChainSet`, g.prefix, r.Name, g.prefix)
+
+	g.p(``)
+	g.p(`// %vChainSet holds the handler of each route with middleware, wrapped in its`, g.prefix)
+	g.p(`// middleware once.`)
+	g.p(`type %vChainSet struct {`, g.prefix)
+	g.p(`once sync.Once`)
+	for _, i := range g.chains {
+		g.p(`serve%d http.Handler // %v`, i, comment(routes[i].String()))
+	}
+	g.p(`}`)
+
+	g.p(``)
+	g.p(`// %vValuesKey is the context key of the param values of a route with`, g.prefix)
+	g.p(`// middleware.`)
+	g.p(`type %vValuesKey struct{}`, g.prefix)
+
+	g.p(``)
+	g.p(`// %vChain returns the handlers of each route with middleware of rt, wrapping`, g.prefix)
+	g.p(`// them in the middleware of rt on first use.`)
+	g.p(`func (rt *%v) %vChain() *%vChainSet {`, r.Name, g.prefix, g.prefix)
+	g.p(`c, ok := %vChains.Load(rt)`, g.prefix)
+	g.p(`if !ok {`)
+	g.p(`c, _ = %vChains.LoadOrStore(rt, new(%vChainSet))`, g.prefix, g.prefix)
+	g.p(`}`)
+	g.p(`set := c.(*%vChainSet)`, g.prefix)
+	g.p(`set.once.Do(func() {`)
+	for _, i := range g.chains {
+		var b strings.Builder
+		for _, name := range routes[i].Use {
+			fmt.Fprintf(&b, `rt.%v(`, name)
+		}
+		g.p(`set.serve%d = %vhttp.HandlerFunc(rt.%vHandle%d)%v`,
+			i, b.String(), g.prefix, i, strings.Repeat(`)`, len(routes[i].Use)))
+	}
+	g.p(`})`)
+	g.p(`return set`)
+	g.p(`}`)
+}
+
+// call writes the statements calling the func fn of a handler with each of its
+// injected params, responding 500 Internal Server Error when a constructor of a
+// param or the handler returns an error.
+func (g *gen) call(h backend.Handler, fn string) {
+	args := []string{`w`, `r`}
+	for i, arg := range h.Args {
+		switch arg.Kind {
+		case backend.Context:
+			args = append(args, `r.Context()`)
+		case backend.Self:
+			args = append(args, `rt`)
+		case backend.RouterField:
+			args = append(args, `rt.`+arg.Name)
+		case backend.Constructor:
+			var in string
+			switch arg.In {
+			case `*http.Request`:
+				in = `r`
+			case `context.Context`:
+				in = `r.Context()`
+			}
+			if !arg.Err {
+				args = append(args, fmt.Sprintf(`rt.%v(%v)`, arg.Name, in))
+				continue
+			}
+			name := fmt.Sprintf(`a%d`, i)
+			g.p(`%v, err := rt.%v(%v)`, name, arg.Name, in)
+			g.p(`if err != nil {`)
+			g.fail(`err`)
+			g.p(`return`)
+			g.p(`}`)
+			args = append(args, name)
+		}
+	}
+	call := fn + `(` + strings.Join(args, `, `) + `)`
+	if !h.Err {
+		g.p(`%v`, call)
+		return
+	}
+	g.p(`if err := %v; err != nil {`, call)
+	g.fail(`err`)
+	g.p(`}`)
+}
+
+// alloc writes the statements declaring the value h of the struct type of a
+// CallMethod handler, which is taken from its pool and returned to it once the
+// handler returns when the handler is pooled, unless the generated benchmarks
+// have turned pooling off to compare.
+func (g *gen) alloc(h backend.Handler) {
+	if !h.Pool {
+		g.p(`var h %v`, h.Type)
+		return
+	}
+	if g.pooled == nil {
+		g.pooled = make(map[string]bool)
+	}
+	g.pooled[h.Type] = true
+	g.p(`var h *%v`, h.Type)
+	g.p(`if %vUnpooled {`, g.prefix)
+	g.p(`h = new(%v)`, h.Type)
+	g.p(`} else {`)
+	g.p(`h = %vPool%v.Get().(*%v)`, g.prefix, h.Type, h.Type)
+	g.p(`defer func() {`)
+	if h.Reset {
+		g.p(`h.Reset()`)
+	} else {
+		g.p(`*h = %v{}`, h.Type)
+	}
+	g.p(`%vPool%v.Put(h)`, g.prefix, h.Type)
+	g.p(`}()`)
+	g.p(`}`)
+}
+
+// pools writes the sync.Pool of each struct type of a pooled handler.
+func (g *gen) pools() {
+	var types []string
+	for typ := range g.pooled {
+		types = append(types, typ)
+	}
+	if len(types) == 0 {
+		return
+	}
+	sort.Strings(types)
+	g.use(`sync`)
+	g.p(``)
+	g.p(`// %vUnpooled allocates the values of pooled handlers for each request when`, g.prefix)
+	g.p(`// true, so the generated benchmarks may compare both.`)
+	g.p(`var %vUnpooled bool`, g.prefix)
+	for _, typ := range types {
+		g.p(``)
+		g.p(`// %vPool%v holds the values of %v between requests.`, g.prefix, typ, typ)
+		g.p(`var %vPool%v = sync.Pool{New: func() interface{} { return new(%v) }}`,
+			g.prefix, typ, typ)
+	}
+}
+
+// fail writes the statement responding to the error held by the expression err
+// with the error method of the router.
+func (g *gen) fail(err string) {
+	g.errs = true
+	g.p(`rt.%vError(w, r, %v)`, g.prefix, err)
+}
+
+// errors writes the error method of the router when a route responds to an
+// error, along with the ParamError type when a param value may fail to convert.
+func (g *gen) errors() {
+	r := g.router
+	if g.params {
+		g.use(`strconv`)
+		g.p(``)
+		g.p(`// %vParamError is the error of a param value which could not be converted to`, r.Name)
+		g.p(`// the type of its field or is out of bounds, responding 400 Bad Request.`)
+		g.p(`type %vParamError struct {`, r.Name)
+		g.p(`Param string // name of the param`)
+		g.p(`Value string // unescaped value of the param`)
+		g.p(`Err   error`)
+		g.p(`}`)
+		g.p(``)
+		g.p(`func (e *%vParamError) Error() string {`, r.Name)
+		g.p(`return "invalid value " + strconv.Quote(e.Value) + " for param " +`)
+		g.p(`strconv.Quote(e.Param) + ": " + e.Err.Error()`)
+		g.p(`}`)
+		g.p(``)
+		g.p(`// Unwrap returns the error converting the param value.`)
+		g.p(`func (e *%vParamError) Unwrap() error { return e.Err }`, r.Name)
+		g.p(``)
+		g.p(`// StatusCode returns 400 Bad Request.`)
+		g.p(`func (e *%vParamError) StatusCode() int { return http.StatusBadRequest }`, r.Name)
+	}
+	if !g.errs {
+		return
+	}
+
+	eh := r.ErrorHandler
+	g.p(``)
+	g.p(`// %vError responds to an error returned by a handler or a *%vParamError`,
+		g.prefix, r.Name)
+	switch {
+	case eh == nil:
+		g.p(`// with the status of its StatusCode method, or 500 Internal Server Error.`)
+	case eh.Nil:
+		g.p(`// with the %v of %v, or when it is nil with the status of the`,
+			eh.Ident, r.Name)
+		g.p(`// StatusCode method of the error, or 500 Internal Server Error.`)
+	default:
+		g.p(`// with the %v of %v.`, eh.Ident, r.Name)
+	}
+	g.p(`func (rt *%v) %vError(w http.ResponseWriter, r *http.Request, err error) {`,
+		r.Name, g.prefix)
+	if eh != nil && !eh.Nil {
+		g.p(`rt.%v(w, r, err)`, eh.Ident)
+		g.p(`}`)
+		return
+	}
+	if eh != nil {
+		g.p(`if rt.%v != nil {`, eh.Ident)
+		g.p(`rt.%v(w, r, err)`, eh.Ident)
+		g.p(`return`)
+		g.p(`}`)
+	}
+	g.use(`errors`)
+	g.p(`status := http.StatusInternalServerError`)
+	g.p(`var sc interface{ StatusCode() int }`)
+	g.p(`if errors.As(err, &sc) {`)
+	g.p(`status = sc.StatusCode()`)
+	g.p(`}`)
+	g.p(`http.Error(w, err.Error(), status)`)
+	g.p(`}`)
+}
+
+// embeds writes the statements allocating each embedded pointer which a bound
+// field of a route is promoted through, outermost first.
+func (g *gen) embeds(rt *backend.Route) {
+	seen := make(map[string]bool)
+	for _, p := range rt.Params {
+		if p.Field == nil {
+			continue
+		}
+		var sel string
+		for _, e := range p.Field.Embeds {
+			sel += `.` + e.Name
+			if e.Ptr && !seen[sel] {
+				seen[sel] = true
+				g.p(`h%v = new(%v)`, sel, e.Type)
+			}
+		}
+	}
+}
+
+// bind writes the statements converting the value held by the expression src
+// and assigning it to the field dst within a block opened by scope, responding
+// 400 Bad Request when the value can not be converted or is out of bounds.
+func (g *gen) bind(p *backend.Param, scope, dst, src string) {
+	f := p.Field
+	if (f.Kind == backend.String || f.Kind == backend.Bytes) &&
+		!f.Ptr && f.Min == `` && f.Max == `` {
+		if f.Kind == backend.Bytes {
+			src = `[]byte(` + src + `)`
+		}
+		if scope == `` {
+			g.p(`%v = %v`, dst, src)
+			return
+		}
+		g.p(`%v {`, scope)
+		g.p(`%v = %v`, dst, src)
+		g.p(`}`)
+		return
+	}
+
+	g.p(`%v {`, scope)
+	val, typ := `x`, strings.TrimPrefix(f.Type, `*`)
+	switch f.Kind {
+	case backend.String:
+		g.p(`x, err := %v, error(nil)`, src)
+	case backend.Bytes:
+		g.p(`x, err := []byte(%v), error(nil)`, src)
+	case backend.Bool:
+		g.use(`strconv`)
+		g.p(`x, err := strconv.ParseBool(%v)`, src)
+	case backend.Int:
+		g.use(`strconv`)
+		g.p(`n, err := strconv.ParseInt(%v, 10, %d)`, src, f.Bits)
+		val = convert(typ, `int64`)
+	case backend.Uint:
+		g.use(`strconv`)
+		g.p(`n, err := strconv.ParseUint(%v, 10, %d)`, src, f.Bits)
+		val = convert(typ, `uint64`)
+	case backend.Float:
+		g.use(`strconv`)
+		bits := f.Bits
+		if bits == 0 {
+			bits = 64
+		}
+		g.p(`n, err := strconv.ParseFloat(%v, %d)`, src, bits)
+		val = convert(typ, `float64`)
+	case backend.Duration:
+		g.use(`time`)
+		g.p(`x, err := time.ParseDuration(%v)`, src)
+	case backend.Time:
+		g.use(`time`)
+		g.p(`x, err := time.Parse(time.RFC3339, %v)`, src)
+	case backend.Text:
+		g.p(`var x %v`, typ)
+		g.p(`err := x.UnmarshalText([]byte(%v))`, src)
+	}
+
+	n := val
+	switch f.Kind {
+	case backend.String:
+		n = g.length(`x`)
+		if g.router.Match.Runes && (f.Min != `` || f.Max != ``) {
+			g.use(`unicode/utf8`)
+		}
+	case backend.Bytes:
+		n = `len(x)`
+	case backend.Duration:
+		n = `x`
+	case backend.Int, backend.Uint, backend.Float:
+		n = `n`
+	}
+	g.bound(f, n, `<`, f.Min, `less than the min`)
+	g.bound(f, n, `>`, f.Max, `greater than the max`)
+
+	g.p(`if err != nil {`)
+	g.params = true
+	g.fail(fmt.Sprintf(`&%vParamError{Param: %q, Value: %v, Err: err}`,
+		g.router.Name, p.Name, src))
+	g.p(`return`)
+	g.p(`}`)
+	switch {
+	case f.Ptr && val == `x`:
+		val = `&x`
+	case f.Ptr:
+		g.p(`y := %v`, val)
+		val = `&y`
+	}
+	g.p(`%v = %v`, dst, val)
+	g.p(`}`)
+}
+
+// convert returns the expression converting the parsed number n of type from
+// to the type typ.
+func convert(typ, from string) string {
+	if typ == from {
+		return `n`
+	}
+	return typ + `(n)`
+}
+
+// bound writes the statement setting err when the value n of a field is beyond
+// a bound.
+func (g *gen) bound(f *backend.Field, n, op, bound, msg string) {
+	if bound == `` {
+		return
+	}
+	show := bound
+	if f.Kind == backend.Duration {
+		d, _ := strconv.ParseInt(bound, 10, 64)
+		show = time.Duration(d).String()
+	}
+	g.use(`errors`)
+	g.p(`if err == nil && %v %v %v {`, n, op, bound)
+	g.p(`err = errors.New(%q)`, `value is `+msg+` of `+show)
+	g.p(`}`)
+}
+
+// comment returns s with each newline replaced so it may be written within a
+// line comment.
+func comment(s string) string {
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,918 @@
+package gosrc
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/backend"
+	"github.com/cstockton/routepiler/internal/backend/backendtest"
+)
+
+const testDir = `testdata/router`
+
+func testSource(t *testing.T) []byte {
+	r, err := analyze.Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	src, err := Source(r)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	return src
+}
+
+func TestSource(t *testing.T) {
+	src := testSource(t)
+	exp, err := ioutil.ReadFile(filepath.Join(testDir, `routes.golden`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if string(exp) != string(src) {
+		t.Fatalf("exp source:\n%s\ngot:\n%s", exp, src)
+	}
+
+	// the generated file must type check alongside the router
+	fset := token.NewFileSet()
+	router, err := parser.ParseFile(fset, filepath.Join(testDir, `router.go`), nil, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	routes, err := parser.ParseFile(fset, `routes.go`, src, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, `source`, nil)}
+	if _, err := conf.Check(`main`, fset, []*ast.File{router, routes}, nil); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+}
+
+func TestBench(t *testing.T) {
+	r, err := analyze.Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	src, err := Bench(r)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	exp, err := ioutil.ReadFile(filepath.Join(testDir, `bench.golden`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if string(exp) != string(src) {
+		t.Fatalf("exp source:\n%s\ngot:\n%s", exp, src)
+	}
+
+	// the benchmark must type check alongside the router and its routes
+	fset := token.NewFileSet()
+	router, err := parser.ParseFile(fset, filepath.Join(testDir, `router.go`), nil, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	routes, err := parser.ParseFile(fset, `routes.go`, testSource(t), 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	bench, err := parser.ParseFile(fset, `routes_test.go`, src, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, `source`, nil)}
+	if _, err := conf.Check(`main`, fset, []*ast.File{router, routes, bench}, nil); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+}
+
+func testProgram(t *testing.T) *backendtest.Program {
+	router, err := ioutil.ReadFile(filepath.Join(testDir, `router.go`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	return backendtest.Build(t, map[string][]byte{
+		`router.go`: router,
+		`routes.go`: testSource(t),
+	})
+}
+
+func TestServe(t *testing.T) {
+	prog := testProgram(t)
+
+	tests := []struct {
+		meth, target string
+		code         int
+		body         string
+	}{
+		{`GET`, `/`, 200, `root`},
+		{`GET`, `/health`, 200, `ok`},
+		{`POST`, `/health`, 404, "404 page not found\n"},
+		{`GET`, `/time`, 200, `time`},
+		{`DELETE`, `/time`, 200, `time`},
+		{`GET`, `/missing`, 404, "404 page not found\n"},
+		{`GET`, `/echo`, 501, "Not Implemented\n"},
+		{`PUT`, `/legacy`, 501, "Not Implemented\n"},
+		{`GET`, `/check`, 200, `Router has unset handlers: Echo for GET /echo, ` +
+			`Legacy for /legacy (var handleLegacy)`},
+
+		// methods of the handler struct
+		{`GET`, `/orgs`, 200, `orgs`},
+		{`POST`, `/orgs`, 200, `new org`},
+		{`PUT`, `/orgs`, 404, "404 page not found\n"},
+		{`CONNECT`, `/tunnel`, 200, `tunnel`},
+
+		// path params
+		{`GET`, `/orgs/acme`, 200, `org acme`},
+		{`GET`, `/orgs/ac`, 404, "404 page not found\n"},
+		{`GET`, `/orgs/ACME`, 404, "404 page not found\n"},
+		{`GET`, `/v1.2`, 200, `version 1.2`},
+		{`GET`, `/archive/2020/3/4`, 200, `archive 2020-3-4`},
+		{`GET`, `/archive/2020/3`, 200, `archive 2020-3-0`},
+		{`GET`, `/archive/2020`, 200, `archive 2020-1-0`},
+		{`GET`, `/archive`, 404, "404 page not found\n"},
+		{`GET`, `/v1.2.3`, 400,
+			"invalid value \"1.2\" for param \"major\": strconv.ParseUint: parsing \"1.2\": invalid syntax\n"},
+		{`GET`, `/v1.256`, 400,
+			"invalid value \"256\" for param \"minor\": strconv.ParseUint: parsing \"256\": value out of range\n"},
+		{`GET`, `/dl/a%2Fb`, 200, `dl a/b`},
+		{`GET`, `/dl/a%20b`, 200, `dl a b`},
+		{`GET`, `/dl/a/b`, 404, "404 page not found\n"},
+		{`GET`, `/dl/`, 404, "404 page not found\n"},
+
+		{`GET`, `/orgs/acme/teams/core/7`, 200, `team acme/core 7`},
+
+		// wildcards
+		{`GET`, `/files/a/raw`, 200, `get a`},
+		{`GET`, `/files/a/b/c/raw`, 200, `get a/b/c`},
+		{`PUT`, `/files/a%2Fb/raw`, 200, `PUT a/b`},
+		{`GET`, `/files/raw`, 404, "404 page not found\n"},
+
+		// query params
+		{`GET`, `/orgs/acme/users/bob?limit=5`, 200, `user acme/bob page 1 limit 5`},
+		{`GET`, `/orgs/acme/users/bob?page=3&limit=5&page=4`, 200,
+			`user acme/bob page 3 limit 5`},
+		{`GET`, `/orgs/acme/users/bob?since=2020-01-02T03:04:05Z&limit=5`, 200,
+			`user acme/bob page 1 limit 5 since 2020-01-02T03:04:05Z`},
+		{`GET`, `/orgs/acme/users/bob?limit=5&since=yesterday`, 400,
+			"invalid value \"yesterday\" for param \"since\": parsing time \"yesterday\" as " +
+				"\"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\"\n"},
+		{`GET`, `/orgs/acme/users/bob?page=0&limit=5`, 400,
+			"invalid value \"0\" for param \"page\": value is less than the min of 1\n"},
+		{`GET`, `/orgs/acme/users/bob?page=101&limit=5`, 400,
+			"invalid value \"101\" for param \"page\": value is greater than the max of 100\n"},
+		{`GET`, `/orgs/acme/users/bob`, 404, "404 page not found\n"},
+		{`GET`, `/orgs/acme/users/bob?limit=5;x`, 404, "404 page not found\n"},
+		{`GET`, `/orgs/acme/users/toolongname?limit=5`, 400,
+			"invalid value \"toolongname\" for param \"user\": value is greater than the max of 8\n"},
+		{`GET`, `/orgs/acme/users/error?limit=5`, 500, "user error failed\n"},
+		{`GET`, `/orgs/acme/users/gone?limit=5`, 410, "user gone was removed\n"},
+		{`GET`, `/reports/7`, 200, `report 7 from 0 every 1h0m0s as `},
+		{`GET`, `/reports/7?from=1.5&every=30m&fmt=CSV`, 200,
+			`report 7 from 1.5 every 30m0s as csv`},
+		{`GET`, `/reports/7?from=-1`, 400,
+			"invalid value \"-1\" for param \"from\": value is less than the min of 0\n"},
+		{`GET`, `/reports/7?every=25h`, 400,
+			"invalid value \"25h\" for param \"every\": value is greater than the max of 24h0m0s\n"},
+		{`GET`, `/reports/7?fmt=xml`, 400,
+			"invalid value \"xml\" for param \"fmt\": unknown format \"xml\"\n"},
+		{`GET`, `/reports/x`, 400,
+			"invalid value \"x\" for param \"id\": strconv.ParseInt: parsing \"x\": invalid syntax\n"},
+	}
+
+	var reqs []backendtest.Request
+	for _, test := range tests {
+		reqs = append(reqs, backendtest.Request{Method: test.meth, Target: test.target})
+	}
+	res := prog.Serve(t, reqs)
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - %v %v exp %v %q`, idx, test.meth, test.target, test.code, test.body)
+		if exp, got := test.code, res[idx].Code; exp != got {
+			t.Fatalf(`exp code %v; got %v (%q)`, exp, got, res[idx].Body)
+		}
+		if exp, got := test.body, res[idx].Body; exp != got {
+			t.Fatalf(`exp body %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeWild(t *testing.T) {
+	const router = `package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Router struct {
+	Tree Files ` + "`get:\"/files/:path*\" func:\"Tree\"`" + `
+	Raw  Files ` + "`get:\"/files/:path*/raw\"`" + `
+}
+
+type Files struct {
+	Path string
+}
+
+func (h *Files) Get(w http.ResponseWriter, r *http.Request)  { fmt.Fprintf(w, "raw %v", h.Path) }
+func (h *Files) Tree(w http.ResponseWriter, r *http.Request) { fmt.Fprintf(w, "tree %v", h.Path) }
+
+func newHandler() http.Handler { return &Router{} }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, router, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	r, err := analyze.Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	tests := []struct {
+		target string
+		body   string
+	}{
+		{`/files/a/raw`, `raw a`},
+		{`/files/a/b/raw`, `raw a/b`},
+		{`/files/a`, `tree a`},
+		{`/files/a/raw/b`, `tree a/raw/b`},
+		{`/files/raw`, `tree raw`},
+	}
+	var reqs []backendtest.Request
+	for _, test := range tests {
+		reqs = append(reqs, backendtest.Request{Method: `GET`, Target: test.target})
+	}
+	for _, strategy := range []backend.Strategy{backend.Linear, backend.Radix} {
+		r.Strategy = strategy
+		src, err := Source(r)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		prog := backendtest.Build(t, map[string][]byte{
+			`router.go`: []byte(router),
+			`routes.go`: src,
+		})
+		res := prog.Serve(t, reqs)
+		for idx, test := range tests {
+			t.Logf(`test #%.2d - exp GET %v to respond %q`, idx, test.target, test.body)
+			if exp, got := 200, res[idx].Code; exp != got {
+				t.Fatalf(`exp code %v; got %v (%q)`, exp, got, res[idx].Body)
+			}
+			if exp, got := test.body, res[idx].Body; exp != got {
+				t.Fatalf(`exp body %q; got %q`, exp, got)
+			}
+		}
+	}
+}
+
+func TestServePredicates(t *testing.T) {
+	prog := testProgram(t)
+
+	tests := []struct {
+		req  backendtest.Request
+		code int
+		body string
+	}{
+		{backendtest.Request{Method: `GET`, Target: `/items`}, 200, `items`},
+		{backendtest.Request{Method: `GET`, Target: `/items`,
+			Header: map[string]string{`Accept`: `text/csv`}}, 200, `items`},
+		{backendtest.Request{Method: `GET`, Target: `/items`,
+			Header: map[string]string{`Accept`: `text/html, text/*;q=0.5`}}, 200, `items`},
+		{backendtest.Request{Method: `GET`, Target: `/items`,
+			Header: map[string]string{`Accept`: `text/html`}}, 406, "Not Acceptable\n"},
+		{backendtest.Request{Method: `GET`, Target: `/items`,
+			Header: map[string]string{`Accept`: `application/json;q=0`}}, 406, "Not Acceptable\n"},
+		{backendtest.Request{Method: `GET`, Target: `/items`,
+			Header: map[string]string{`Accept`: `text/html`, `X-Api-Version`: `2`}}, 200, `items v2`},
+		{backendtest.Request{Method: `GET`, Target: `/items`,
+			Header: map[string]string{`X-Api-Version`: `3`}}, 200, `items`},
+		{backendtest.Request{Method: `POST`, Target: `/items`, Body: `{}`,
+			Header: map[string]string{`Content-Type`: `Application/JSON; charset=utf-8`,
+				`Authorization`: `token`}}, 200, `new item`},
+		{backendtest.Request{Method: `POST`, Target: `/items`, Body: `{}`,
+			Header: map[string]string{`Content-Type`: `application/json`}}, 401, "unauthorized\n"},
+		{backendtest.Request{Method: `POST`, Target: `/items`, Body: `a`,
+			Header: map[string]string{`Content-Type`: `text/plain`}}, 415, "Unsupported Media Type\n"},
+		{backendtest.Request{Method: `POST`, Target: `/items`}, 415, "Unsupported Media Type\n"},
+		{backendtest.Request{Method: `PUT`, Target: `/items`}, 404, "404 page not found\n"},
+	}
+
+	var reqs []backendtest.Request
+	for _, test := range tests {
+		reqs = append(reqs, test.req)
+	}
+	res := prog.Serve(t, reqs)
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - %v %v %v exp %v %q`,
+			idx, test.req.Method, test.req.Target, test.req.Header, test.code, test.body)
+		if exp, got := test.code, res[idx].Code; exp != got {
+			t.Fatalf(`exp code %v; got %v (%q)`, exp, got, res[idx].Body)
+		}
+		if exp, got := test.body, res[idx].Body; exp != got {
+			t.Fatalf(`exp body %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeMiddleware(t *testing.T) {
+	prog := testProgram(t)
+	res := prog.Serve(t, []backendtest.Request{
+		{Method: `GET`, Target: `/items`},
+		{Method: `POST`, Target: `/items`, Header: map[string]string{
+			`Content-Type`: `application/json`, `Authorization`: `token`}},
+		{Method: `POST`, Target: `/items`, Header: map[string]string{
+			`Content-Type`: `application/json`}},
+		{Method: `GET`, Target: `/orgs`},
+	})
+	for idx, exp := range []string{`GET /items`, `POST /items`, `POST /items`, ``} {
+		t.Logf(`test #%.2d - exp X-Audit header %q`, idx, exp)
+		if got := res[idx].Header[`X-Audit`]; exp != got {
+			t.Fatalf(`exp X-Audit header %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeMiddlewareOnce(t *testing.T) {
+	const router = `package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Router struct {
+	Item  Item             ` + "`get:\"/items/:id?v\" use:\"count\"`" + `
+	Wraps http.HandlerFunc ` + "`get:\"/wraps\"`" + `
+
+	wraps int
+}
+
+func (rt *Router) Count(next http.Handler) http.Handler {
+	rt.wraps++
+	return next
+}
+
+type Item struct {
+	ID int
+	V  string
+}
+
+func (h *Item) Get(w http.ResponseWriter, r *http.Request) { fmt.Fprintf(w, "item %d %v", h.ID, h.V) }
+
+func newHandler() http.Handler {
+	rt := &Router{}
+	rt.Wraps = func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, rt.wraps) }
+	return rt
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, router, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	r, err := analyze.Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	src, err := Source(r)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	prog := backendtest.Build(t, map[string][]byte{
+		`router.go`: []byte(router),
+		`routes.go`: src,
+	})
+
+	tests := []struct {
+		target string
+		body   string
+	}{
+		{`/wraps`, `0`},
+		{`/items/1?v=a`, `item 1 a`},
+		{`/items/2`, `item 2 `},
+		{`/wraps`, `1`},
+	}
+	var reqs []backendtest.Request
+	for _, test := range tests {
+		reqs = append(reqs, backendtest.Request{Method: `GET`, Target: test.target})
+	}
+	res := prog.Serve(t, reqs)
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp GET %v to respond %q`, idx, test.target, test.body)
+		if exp, got := test.body, res[idx].Body; exp != got {
+			t.Fatalf(`exp body %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeInject(t *testing.T) {
+	prog := testProgram(t)
+	res := prog.Serve(t, []backendtest.Request{
+		{Method: `GET`, Target: `/stats/hits`},
+		{Method: `GET`, Target: `/stats/hits`, Header: map[string]string{`X-Fail`: `1`}},
+	})
+	tests := []struct {
+		code int
+		body string
+	}{
+		{200, `app log: hits tx /stats/hits ctx true router true`},
+		{500, "tx failed\n"},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp injected params to respond %v %q`, idx, test.code, test.body)
+		if exp, got := test.code, res[idx].Code; exp != got {
+			t.Fatalf(`exp code %v; got %v`, exp, got)
+		}
+		if exp, got := test.body, res[idx].Body; exp != got {
+			t.Fatalf(`exp body %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeErrorHandler(t *testing.T) {
+	const router = `package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type Router struct {
+	User Users ` + "`get:\"/users/:id\"`" + `
+}
+
+type Users struct {
+	ID int ` + "`max:\"9\"`" + `
+}
+
+func (h *Users) Get(w http.ResponseWriter, r *http.Request) error {
+	if h.ID == 0 {
+		return errors.New("no user")
+	}
+	fmt.Fprintf(w, "user %d", h.ID)
+	return nil
+}
+
+func (rt *Router) ErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var pe *RouterParamError
+	if errors.As(err, &pe) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "param %v: %v", pe.Param, pe.Err)
+		return
+	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprint(w, err)
+}
+
+func newHandler() http.Handler { return &Router{} }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, router, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	r, err := analyze.Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	src, err := Source(r)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	prog := backendtest.Build(t, map[string][]byte{
+		`router.go`: []byte(router),
+		`routes.go`: src,
+	})
+
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{`/users/3`, 200, `user 3`},
+		{`/users/0`, 503, `no user`},
+		{`/users/x`, 422, `param id: strconv.ParseInt: parsing "x": invalid syntax`},
+		{`/users/12`, 422, `param id: value is greater than the max of 9`},
+	}
+	var reqs []backendtest.Request
+	for _, test := range tests {
+		reqs = append(reqs, backendtest.Request{Method: `GET`, Target: test.target})
+	}
+	res := prog.Serve(t, reqs)
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp GET %v to respond %v %q`, idx, test.target, test.code, test.body)
+		if exp, got := test.code, res[idx].Code; exp != got {
+			t.Fatalf(`exp code %v; got %v`, exp, got)
+		}
+		if exp, got := test.body, res[idx].Body; exp != got {
+			t.Fatalf(`exp body %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeMatch(t *testing.T) {
+	const router = `package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Router struct {
+	_    struct{} ` + "`match:\"runes\" case:\"fold\"`" + `
+	Cafe Names    ` + "`get:\"/Café/:name([a-zé]+){2-3}\"`" + `
+	Word Names    ` + "`get:\"/words/:name{1-2}\"`" + `
+	Long Names    ` + "`get:\"/long/:name\"`" + `
+}
+
+type Names struct {
+	Name string ` + "`max:\"3\"`" + `
+}
+
+func (h *Names) Get(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, h.Name) }
+
+func newHandler() http.Handler { return &Router{} }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, router, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	r, err := analyze.Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{`/Caf%C3%A9/%C3%A9t%C3%A9`, 200, `été`},
+		{`/CAF%C3%89/ab`, 200, `ab`},
+		{`/caf%c3%a9/ab`, 200, `ab`},
+		{`/cafe/ab`, 404, "404 page not found\n"},
+		{`/Caf%C3%A9/abcd`, 404, "404 page not found\n"},
+		{`/WORDS/%C3%A9%C3%A9`, 200, `éé`},
+		{`/words/%FF`, 404, "404 page not found\n"},
+		{`/long/%C3%A9%C3%A9%C3%A9`, 200, `ééé`},
+		{`/long/abcd`, 400, "invalid value \"abcd\" for param \"name\": " +
+			"value is greater than the max of 3\n"},
+	}
+	var reqs []backendtest.Request
+	for _, test := range tests {
+		reqs = append(reqs, backendtest.Request{Method: `GET`, Target: test.target})
+	}
+	for _, strategy := range []backend.Strategy{backend.Linear, backend.Radix} {
+		r.Strategy = strategy
+		src, err := Source(r)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		prog := backendtest.Build(t, map[string][]byte{
+			`router.go`: []byte(router),
+			`routes.go`: src,
+		})
+		res := prog.Serve(t, reqs)
+		for idx, test := range tests {
+			t.Logf(`test #%.2d - exp GET %v to respond %v %q`, idx, test.target, test.code, test.body)
+			if exp, got := test.code, res[idx].Code; exp != got {
+				t.Fatalf(`exp code %v; got %v`, exp, got)
+			}
+			if exp, got := test.body, res[idx].Body; exp != got {
+				t.Fatalf(`exp body %q; got %q`, exp, got)
+			}
+		}
+	}
+
+	// a decomposed path matches once normalized, which requires the module of
+	// the router to require golang.org/x/text
+	r.Match.NFC = true
+	src, err := Source(r)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	prog := backendtest.Build(t, map[string][]byte{
+		`go.mod`:    []byte("module backendtest\n\ngo 1.18\n\nrequire golang.org/x/text v0.14.0\n"),
+		`router.go`: []byte(router),
+		`routes.go`: src,
+	})
+	nfc := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{`/Cafe%CC%81/e%CC%81te%CC%81`, 200, `été`},
+		{`/Caf%C3%A9/%C3%A9t%C3%A9`, 200, `été`},
+		{`/CAFE%CC%81/ab`, 200, `ab`},
+		{`/long/e%CC%81e%CC%81e%CC%81`, 200, `ééé`},
+		{`/cafe/ab`, 404, "404 page not found\n"},
+	}
+	reqs = reqs[:0]
+	for _, test := range nfc {
+		reqs = append(reqs, backendtest.Request{Method: `GET`, Target: test.target})
+	}
+	res := prog.Serve(t, reqs)
+	for idx, test := range nfc {
+		t.Logf(`test #%.2d - exp NFC GET %v to respond %v %q`, idx, test.target, test.code, test.body)
+		if exp, got := test.code, res[idx].Code; exp != got {
+			t.Fatalf(`exp code %v; got %v (%q)`, exp, got, res[idx].Body)
+		}
+		if exp, got := test.body, res[idx].Body; exp != got {
+			t.Fatalf(`exp body %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeClean(t *testing.T) {
+	const router = `package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Router struct {
+	File Files ` + "`get:\"/files/:name\"`" + `
+	Save Files ` + "`post:\"/files/:name\"`" + `
+	Cafe Files ` + "`get:\"/café/:name\"`" + `
+}
+
+type Files struct {
+	Name string
+}
+
+func (h *Files) Get(w http.ResponseWriter, r *http.Request)  { fmt.Fprint(w, h.Name) }
+func (h *Files) Post(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, h.Name) }
+
+func newHandler() http.Handler { return &Router{} }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, router, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	r, err := analyze.Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	const notFound = "404 page not found\n"
+	var (
+		strict   = backend.Match{}
+		reject   = backend.Match{Slash: backend.Reject}
+		decode   = backend.Match{Slash: backend.Decode}
+		redirect = backend.Match{Clean: backend.Redirect, Slash: backend.Decode}
+		lenient  = backend.Match{Clean: backend.Lenient}
+	)
+	tests := []struct {
+		match  backend.Match
+		method string
+		target string
+		code   int
+		exp    string // body, or the Location header of a redirect
+	}{
+		{strict, `GET`, `/files/a%2Fb`, 200, `a/b`},
+		{strict, `GET`, `/caf%c3%a9/x`, 200, `x`},
+		{strict, `GET`, `/%66iles/x`, 200, `x`},
+		{strict, `GET`, `//files/x`, 404, notFound},
+		{strict, `GET`, `/files/x/`, 404, notFound},
+		{strict, `GET`, `/files/./x`, 404, notFound},
+		{reject, `GET`, `/files/a%2fb`, 404, notFound},
+		{reject, `GET`, `/files/ab`, 200, `ab`},
+		{decode, `GET`, `/files/a%2Fb`, 404, notFound},
+		{decode, `GET`, `/files%2Fx`, 200, `x`},
+		{redirect, `GET`, `//files/./x?q=1`, 301, `/files/x?q=1`},
+		{redirect, `POST`, `/files/x/`, 308, `/files/x`},
+		{redirect, `GET`, `/files/a%2F..%2Fb`, 301, `/files/b`},
+		{redirect, `GET`, `/caf%C3%A9/x`, 200, `x`},
+		{lenient, `GET`, `/files/../files/x/`, 200, `x`},
+		{lenient, `POST`, `/a/%2E%2E//files/x`, 200, `x`},
+		{lenient, `GET`, `/files/a%2Fb`, 200, `a/b`},
+	}
+	progs := make(map[backend.Match]*backendtest.Program)
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp %v %v with %+v to respond %v %q`,
+			idx, test.method, test.target, test.match, test.code, test.exp)
+		prog := progs[test.match]
+		if prog == nil {
+			r.Match = test.match
+			src, err := Source(r)
+			if err != nil {
+				t.Fatalf(`exp nil err; got %v`, err)
+			}
+			prog = backendtest.Build(t, map[string][]byte{
+				`router.go`: []byte(router),
+				`routes.go`: src,
+			})
+			progs[test.match] = prog
+		}
+		res := prog.Serve(t, []backendtest.Request{{Method: test.method, Target: test.target}})
+		if exp, got := test.code, res[0].Code; exp != got {
+			t.Fatalf(`exp code %v; got %v`, exp, got)
+		}
+		got := res[0].Body
+		if test.code/100 == 3 {
+			got = res[0].Header[`Location`]
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestServeCase(t *testing.T) {
+	const router = `package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Router struct {
+	Users Names ` + "`get:\"/Users/:name/Profile\" case:\"redirect\"`" + `
+	Files Names ` + "`get:\"/Files/:name*/Raw\" case:\"redirect\"`" + `
+	Cafe  Names ` + "`get:\"/Café/:name\" case:\"redirect\"`" + `
+	Orgs  Names ` + "`get:\"/orgs/:name\" case:\"fold\"`" + `
+	Keys  Names ` + "`get:\"/keys/:name\" case:\"fold\"`" + `
+	Team  Names ` + "`get:\"/Team/:name\"`" + `
+}
+
+type Names struct {
+	Name string
+}
+
+func (h *Names) Get(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, h.Name) }
+
+func newHandler() http.Handler { return &Router{} }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, router, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	r, err := analyze.Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	const notFound = "404 page not found\n"
+	tests := []struct {
+		target string
+		code   int
+		exp    string // body, or the Location header of a redirect
+	}{
+		{`/Users/Bob/Profile`, 200, `Bob`},
+		{`/users/Bob/profile`, 301, `/Users/Bob/Profile`},
+		{`/USERS/bOB/PROFILE?tab=1`, 301, `/Users/bOB/Profile?tab=1`},
+		{`/files/a/B/raw`, 301, `/Files/a/B/Raw`},
+		{`/CAF%C3%89/X`, 301, `/Caf%C3%A9/X`},
+		{`/ORGS/Acme`, 200, `Acme`},
+		{`/Orgs/acme`, 200, `acme`},
+		{`/%E2%84%AAeys/a`, 404, notFound},
+		{`/KEYS/a`, 200, `a`},
+		{`/Team/a`, 200, `a`},
+		{`/team/a`, 404, notFound},
+	}
+	var reqs []backendtest.Request
+	for _, test := range tests {
+		reqs = append(reqs, backendtest.Request{Method: `GET`, Target: test.target})
+	}
+	for _, strategy := range []backend.Strategy{backend.Linear, backend.Radix} {
+		r.Strategy = strategy
+		src, err := Source(r)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		prog := backendtest.Build(t, map[string][]byte{
+			`router.go`: []byte(router),
+			`routes.go`: src,
+		})
+		res := prog.Serve(t, reqs)
+		for idx, test := range tests {
+			t.Logf(`test #%.2d - exp GET %v to respond %v %q`, idx, test.target, test.code, test.exp)
+			if exp, got := test.code, res[idx].Code; exp != got {
+				t.Fatalf(`exp code %v; got %v`, exp, got)
+			}
+			got := res[idx].Body
+			if test.code/100 == 3 {
+				got = res[idx].Header[`Location`]
+			}
+			if exp := test.exp; exp != got {
+				t.Fatalf(`exp %q; got %q`, exp, got)
+			}
+		}
+	}
+}
+
+func TestServePool(t *testing.T) {
+	prog := testProgram(t)
+	var reqs []backendtest.Request
+	for i := 0; i < 4; i++ {
+		reqs = append(reqs,
+			backendtest.Request{Method: `GET`, Target: `/orgs/acme/users/bob?limit=5&since=2020-01-02T03:04:05Z`},
+			backendtest.Request{Method: `GET`, Target: `/orgs/acme/users/bob?limit=5`},
+			backendtest.Request{Method: `GET`, Target: `/reports/7?fmt=csv`},
+			backendtest.Request{Method: `GET`, Target: `/reports/8`})
+	}
+	res := prog.Serve(t, reqs)
+	for idx, exp := range []string{
+		`user acme/bob page 1 limit 5 since 2020-01-02T03:04:05Z`,
+		`user acme/bob page 1 limit 5`,
+		`report 7 from 0 every 1h0m0s as csv`,
+		`report 8 from 0 every 1h0m0s as `,
+	} {
+		for i := idx; i < len(res); i += 4 {
+			t.Logf(`test #%.2d - exp pooled value to be reset before %v`, i, reqs[i].Target)
+			if got := res[i].Body; exp != got {
+				t.Fatalf(`exp body %q; got %q`, exp, got)
+			}
+		}
+	}
+}
+
+func TestRadix(t *testing.T) {
+	for _, dir := range []string{testDir, `testdata/github`} {
+		t.Logf(`exp the strategies of the router of %v to serve the same responses`, dir)
+		r, err := analyze.Load(dir, `Router`)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		router, err := ioutil.ReadFile(filepath.Join(dir, `router.go`))
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		// each route is requested along with requests which almost match it
+		var reqs []backendtest.Request
+		for _, rt := range r.Routes {
+			target, ok := benchTarget(rt)
+			if !ok {
+				continue
+			}
+			header := make(map[string]string)
+			for _, pred := range rt.Predicates {
+				header[pred.Header] = pred.Values[0]
+			}
+			path, query := target, ``
+			if i := strings.IndexByte(target, '?'); i >= 0 {
+				path, query = target[:i], target[i:]
+			}
+			for _, target := range []string{
+				target, path + `/` + query, path + `/x` + query, strings.ToUpper(path) + query,
+				path[:strings.LastIndexByte(path, '/')+1] + query,
+			} {
+				for _, method := range []string{rt.Method, `GET`, `PATCH`} {
+					reqs = append(reqs, backendtest.Request{
+						Method: method, Target: target, Header: header})
+				}
+			}
+		}
+
+		// static segments compared under case folding walk the tree differently
+		for _, fold := range []bool{false, true} {
+			for _, rt := range r.Routes {
+				rt.Case = backend.Exact
+				if fold {
+					rt.Case = backend.Fold
+				}
+			}
+			var res [][]backendtest.Response
+			for _, strategy := range []backend.Strategy{backend.Linear, backend.Radix} {
+				r.Strategy = strategy
+				src, err := Source(r)
+				if err != nil {
+					t.Fatalf(`exp nil err; got %v`, err)
+				}
+				prog := backendtest.Build(t, map[string][]byte{
+					`router.go`: router,
+					`routes.go`: src,
+				})
+				res = append(res, prog.Serve(t, reqs))
+			}
+			var served int
+			for idx, req := range reqs {
+				if exp, got := res[0][idx], res[1][idx]; !reflect.DeepEqual(exp, got) {
+					t.Fatalf("exp radix response to %v %v with fold %v to be %v; got %v",
+						req.Method, req.Target, fold, exp, got)
+				}
+				if res[0][idx].Code != http.StatusNotFound {
+					served++
+				}
+			}
+			if served == 0 || served == len(reqs) {
+				t.Fatalf(`exp some of %v requests to be served; got %v`, len(reqs), served)
+			}
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package gosrc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// helpersFile writes each helper func used by the generated routes.
+func (g *gen) helpersFile() {
+	var names []string
+	for name := range g.helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h := helpers[name]
+		g.use(h.imports...)
+		g.p(``)
+		g.p(`%v`, fmt.Sprintf(h.src, g.prefix))
+	}
+}
+
+// helpers are the source of each helper func, with the prefix of the generated
+// declarations as the first operand.
+var helpers = map[string]struct {
+	imports []string
+	src     string
+}{
+	`Next`: {nil, `// %[1]vNext returns the first segment of a path beginning with a slash
+// and the remainder of the path following it.
+func %[1]vNext(path string) (string, string) {
+	if i := strings.IndexByte(path[1:], '/'); i >= 0 {
+		return path[1 : i+1], path[i+1:]
+	}
+	return path[1:], ""
+}`},
+
+	`Wild`: {nil, `// %[1]vWild returns all but the last n segments of a path beginning with a
+// slash and the remainder of the path following them.
+func %[1]vWild(path string, n int) (string, string) {
+	end := len(path)
+	for ; n > 0; n-- {
+		end = strings.LastIndexByte(path[:end], '/')
+	}
+	return path[1:end], path[end:]
+}`},
+
+	`Unescape`: {[]string{`net/url`}, `// %[1]vUnescape returns the unescaped form of an escaped path segment.
+func %[1]vUnescape(s string) string {
+	if strings.IndexByte(s, '%%') < 0 {
+		return s
+	}
+	if v, err := url.PathUnescape(s); err == nil {
+		return v
+	}
+	return s
+}`},
+
+	`Fold`: {nil, `// %[1]vFold returns true if the escaped path segment s equals the unescaped
+// literal lit under Unicode case folding.
+func %[1]vFold(s, lit string) bool {
+	if strings.IndexByte(s, '%%') >= 0 {
+		s = %[1]vUnescape(s)
+	}
+	return strings.EqualFold(s, lit)
+}`},
+
+	`FoldASCII`: {nil, `// %[1]vFoldASCII returns true if the escaped path segment s equals the escaped
+// ASCII literal lit regardless of the case of ASCII letters.
+func %[1]vFoldASCII(s, lit string) bool {
+	if len(s) != len(lit) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c, l := s[i], lit[i]; c != l {
+			if c |= 0x20; c != l|0x20 || c < 'a' || c > 'z' {
+				return false
+			}
+		}
+	}
+	return true
+}`},
+
+	`NFC`: {[]string{`net/url`, `golang.org/x/text/unicode/norm`}, `// %[1]vNFC returns a canonical escaped path with each segment normalized to
+// Unicode NFC. A canonical escaped path holds only ASCII, so a path without a
+// percent-encoded byte is returned as is.
+func %[1]vNFC(path string) string {
+	if strings.IndexByte(path, '%%') < 0 {
+		return path
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		v, err := url.PathUnescape(seg)
+		if err == nil && !norm.NFC.IsNormalString(v) {
+			segs[i] = %[1]vEscape(norm.NFC.String(v))
+		}
+	}
+	return strings.Join(segs, "/")
+}`},
+
+	`Escape`: {nil, `// %[1]vEscape returns the canonical escaped form of an unescaped path segment,
+// where each byte which may not appear unescaped within a path segment is
+// percent-encoded with upper case hex digits.
+func %[1]vEscape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			strings.IndexByte("-._~!$&'()*+,;=:@[]", c) >= 0:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}`},
+
+	`Canonical`: {[]string{`net/url`}, `// %[1]vCanonical returns an escaped path with each percent-encoding made
+// canonical, so a path may be compared to the literals of routes however the
+// client escaped it. Percent-encoded slashes remain within their segment.
+func %[1]vCanonical(path string) string {
+	if strings.IndexByte(path, '%%') < 0 {
+		return path
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.IndexByte(seg, '%%') < 0 {
+			continue
+		}
+		if v, err := url.PathUnescape(seg); err == nil {
+			segs[i] = %[1]vEscape(v)
+		}
+	}
+	return strings.Join(segs, "/")
+}`},
+
+	`Clean`: {[]string{`path`}, `// %[1]vClean returns the clean form of an escaped path, without repeated
+// slashes, dot segments or a trailing slash, along with true if it differs.
+func %[1]vClean(p string) (string, bool) {
+	if p == "" || p[0] != '/' {
+		return p, false
+	}
+	clean := path.Clean(p)
+	return clean, clean != p
+}`},
+
+	`Redirect`: {nil, `// %[1]vRedirect redirects a request to the escaped path p along with its query,
+// with 301 Moved Permanently for a GET or HEAD request and otherwise 308
+// Permanent Redirect, so the method and body are kept.
+func %[1]vRedirect(w http.ResponseWriter, r *http.Request, p string) {
+	if r.URL.RawQuery != "" {
+		p += "?" + r.URL.RawQuery
+	}
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, p, code)
+}`},
+
+	`Part`: {nil, `// %[1]vPart is a literal or a param within a path segment.
+type %[1]vPart struct {
+	lit   string            // literal, empty for a param
+	param int               // index of the param value
+	depth int               // max segments of a wildcard param, zero if unbound
+	check func(string) bool // reports if an unescaped param value is in bounds
+}`},
+
+	`Parts`: {nil, `// %[1]vParts returns true if the parts of a path segment match all of s,
+// storing the unescaped value of each param in vs. Each param is followed by a
+// literal, or ends the segment, and matches as much of s as possible. States of
+// the search which failed are recorded, so a segment is matched in polynomial
+// time regardless of how many params it holds.
+func %[1]vParts(s string, parts []%[1]vPart, vs []string) bool {
+	var buf [128]bool
+	failed := buf[:0]
+	if n := len(parts) * (len(s) + 1); n <= len(buf) {
+		failed = buf[:n]
+	} else {
+		failed = make([]bool, n)
+	}
+	return %[1]vPartsAt(s, 0, parts, 0, vs, failed)
+}
+
+func %[1]vPartsAt(s string, off int, parts []%[1]vPart, pi int, vs []string, failed []bool) bool {
+	if pi == len(parts) {
+		return off == len(s)
+	}
+	state := pi*(len(s)+1) + off
+	if failed[state] {
+		return false
+	}
+	switch p := parts[pi]; {
+	case p.lit != "" && pi == len(parts)-1:
+		if s[off:] == p.lit {
+			return true
+		}
+	case p.lit != "":
+		if strings.HasPrefix(s[off:], p.lit) &&
+			%[1]vPartsAt(s, off+len(p.lit), parts, pi+1, vs, failed) {
+			return true
+		}
+	case pi == len(parts)-1:
+		if off < len(s) && p.match(s[off:], vs) {
+			return true
+		}
+	default:
+		lit := parts[pi+1].lit
+		for end := len(s) - 1; end > off; end-- {
+			if strings.HasPrefix(s[end:], lit) && p.match(s[off:end], vs) &&
+				%[1]vPartsAt(s, end, parts, pi+1, vs, failed) {
+				return true
+			}
+		}
+	}
+	failed[state] = true
+	return false
+}
+
+// match returns true if the escaped value s of a param is within its bounds,
+// storing its unescaped value in vs.
+func (p *%[1]vPart) match(s string, vs []string) bool {
+	if p.depth > 0 && strings.Count(s, "/") >= p.depth {
+		return false
+	}
+	v := %[1]vUnescape(s)
+	if p.check != nil && !p.check(v) {
+		return false
+	}
+	vs[p.param] = v
+	return true
+}`},
+
+	`Query`: {[]string{`net/url`}, `// %[1]vQuery stores the unescaped value of the first occurrence of each
+// named param of the raw query q in vs, returning a bitmask of the params which
+// are present. Pairs which fail to unescape are skipped as url.ParseQuery does.
+func %[1]vQuery(q string, names []string, vs []string) (set uint64) {
+	for q != "" {
+		pair := q
+		if i := strings.IndexByte(q, '&'); i >= 0 {
+			pair, q = q[:i], q[i+1:]
+		} else {
+			q = ""
+		}
+		if pair == "" || strings.IndexByte(pair, ';') >= 0 {
+			continue
+		}
+		key, val := pair, ""
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			key, val = pair[:i], pair[i+1:]
+		}
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			continue
+		}
+		for i, name := range names {
+			if key != name || set&(1<<uint(i)) != 0 {
+				continue
+			}
+			if v, err := url.QueryUnescape(val); err == nil {
+				vs[i], set = v, set|1<<uint(i)
+			}
+			break
+		}
+	}
+	return set
+}`},
+
+	`Consumes`: {[]string{`mime`}, `// %[1]vConsumes returns true if the media type of the Content-Type header of
+// r is one of types, where a request without the header is treated as
+// application/octet-stream.
+func %[1]vConsumes(r *http.Request, types ...string) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, typ := range types {
+		if mt == typ {
+			return true
+		}
+	}
+	return false
+}`},
+
+	`Accepts`: {[]string{`mime`, `strconv`}, `// %[1]vAccepts returns true if a media range of the Accept header of r with a
+// non-zero quality includes one of types, where a request without the header
+// accepts any type.
+func %[1]vAccepts(r *http.Request, types ...string) bool {
+	vs := r.Header.Values("Accept")
+	if len(vs) == 0 {
+		return true
+	}
+	for _, v := range vs {
+		for _, rng := range strings.Split(v, ",") {
+			mt, params, err := mime.ParseMediaType(rng)
+			if err != nil {
+				continue
+			}
+			if q, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(q, 64); err != nil || f <= 0 {
+					continue
+				}
+			}
+			for _, typ := range types {
+				if mt == "*/*" || mt == typ ||
+					strings.HasSuffix(mt, "/*") && strings.HasPrefix(typ, mt[:len(mt)-1]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}`},
+}
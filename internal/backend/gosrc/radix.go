@@ -0,0 +1,211 @@
+package gosrc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/backend"
+	"github.com/cstockton/routepiler/internal/parser"
+)
+
+// node is a node of the compressed prefix tree of the path segments of routes,
+// where each edge is a run of static segments or a single lone param. A request
+// walks the static child matching its next segment, then the routes whose next
+// segment holds a literal or regexp, then each param child in order of shape,
+// then the routes whose next segment is a wildcard. This is the order of
+// precedence, so routes are tried in the same order a linear walk tries them.
+type node struct {
+	label    []string // escaped static segments, or the shape of a lone param
+	children []*node  // static children ordered by the first segment of their label
+	params   []*node  // lone param children ordered by shape
+	end      []int    // routes with no segment following the node
+	mixed    []int    // routes with a literal or regexp segment following the node
+	wild     []int    // routes with a wildcard segment following the node
+	fold     bool     // a route below the static label compares it regardless of case
+}
+
+// tree returns the root of the prefix tree of the routes of the router, which
+// are given in order of precedence.
+func tree(routes []*backend.Route) *node {
+	root := &node{}
+	for i, rt := range routes {
+		root.insert(i, rt.Path, rt.Case != backend.Exact)
+	}
+	root.compress()
+	return root
+}
+
+// insert adds route i below n, where path holds the segments of the route
+// following the label of n and fold is true if they compare regardless of case.
+func (n *node) insert(i int, path []parser.Segment, fold bool) {
+	if len(path) == 0 {
+		n.end = append(n.end, i)
+		return
+	}
+	switch seg := path[0]; analyze.Rank(seg) {
+	case 3:
+		c := child(&n.children, seg.String())
+		c.fold = c.fold || fold
+		c.insert(i, path[1:], fold)
+	case 1:
+		child(&n.params, analyze.Shape(seg)).insert(i, path[1:], fold)
+	case 2:
+		n.mixed = append(n.mixed, i)
+	default:
+		n.wild = append(n.wild, i)
+	}
+}
+
+// child returns the node within children with the given first label, adding
+// it if it does not exist.
+func child(children *[]*node, label string) *node {
+	for _, c := range *children {
+		if c.label[0] == label {
+			return c
+		}
+	}
+	c := &node{label: []string{label}}
+	*children = append(*children, c)
+	return c
+}
+
+// compress merges each static child which has a single static child and no
+// other children or routes into that child, unless either compares its label
+// regardless of case.
+func (n *node) compress() {
+	for _, c := range n.children {
+		for len(c.children) == 1 && len(c.params) == 0 && !c.routes() &&
+			!c.fold && !c.children[0].fold {
+			gc := c.children[0]
+			c.label = append(c.label, gc.label...)
+			c.children, c.params = gc.children, gc.params
+			c.end, c.mixed, c.wild = gc.end, gc.mixed, gc.wild
+		}
+		c.compress()
+	}
+	for _, c := range n.params {
+		c.compress()
+	}
+	sort.Slice(n.children, func(i, j int) bool {
+		return n.children[i].label[0] < n.children[j].label[0]
+	})
+	sort.Slice(n.params, func(i, j int) bool {
+		return n.params[i].label[0] < n.params[j].label[0]
+	})
+}
+
+// routes returns true if any route ends at n or continues from it with a
+// segment which is not static or a lone param.
+func (n *node) routes() bool {
+	return len(n.end) > 0 || len(n.mixed) > 0 || len(n.wild) > 0
+}
+
+// uses returns true if the statements written for n refer to the remainder of
+// the path following its label.
+func (n *node) uses() bool {
+	return len(n.end) > 0 || len(n.children) > 0 || len(n.params) > 0
+}
+
+// radix writes the statements of ServeHTTP which walk the prefix tree of the
+// routes, switching on each static segment of the escaped path, or comparing
+// it to each static child in turn when any compares regardless of case.
+func (g *gen) radix() {
+	root := tree(g.router.Routes)
+	if root.uses() {
+		g.p(`p0 := path`)
+	}
+	g.node(root, 0)
+}
+
+// node writes the statements trying the routes below n, where the variable
+// p<depth> holds the remainder of the path following the label of n.
+func (g *gen) node(n *node, depth int) {
+	rest := fmt.Sprintf(`p%d`, depth)
+	if len(n.end) > 0 {
+		g.p(`if %v == "" {`, rest)
+		g.tries(n.end)
+		g.p(`}`)
+	}
+	if len(n.children) == 0 && len(n.params) == 0 {
+		g.tries(n.mixed)
+		g.tries(n.wild)
+		return
+	}
+
+	seg, next := fmt.Sprintf(`s%d`, depth+1), `_`
+	for _, c := range n.children {
+		if len(c.label) > 1 || c.uses() {
+			next = fmt.Sprintf(`p%d`, depth+1)
+		}
+	}
+	for _, c := range n.params {
+		if c.uses() {
+			next = fmt.Sprintf(`p%d`, depth+1)
+		}
+	}
+	g.p(`if %v != "" {`, rest)
+	g.p(`%v, %v := %v(%v)`, seg, next, g.helper(`Next`), rest)
+	var fold bool
+	for _, c := range n.children {
+		fold = fold || c.fold
+	}
+	if fold {
+		// the routes below a child verify the case of the segment themselves
+		for _, c := range n.children {
+			if c.fold {
+				g.p(`if %v {`, g.fold(seg, c.label[0]))
+			} else {
+				g.p(`if %v == %q {`, seg, c.label[0])
+			}
+			g.edge(c, depth, next)
+			g.p(`}`)
+		}
+	} else if len(n.children) > 0 {
+		g.p(`switch %v {`, seg)
+		for _, c := range n.children {
+			g.p(`case %q:`, c.label[0])
+			g.edge(c, depth, next)
+		}
+		g.p(`}`)
+	}
+	g.tries(n.mixed)
+	if len(n.params) > 0 {
+		g.p(`if %v != "" {`, seg)
+		for _, c := range n.params {
+			g.node(c, depth+1)
+		}
+		g.p(`}`)
+	}
+	g.p(`}`)
+	g.tries(n.wild)
+}
+
+// edge writes the statements trying the routes below the static child c of a
+// node at depth once the first segment of its label matches, where the
+// variable next holds the remainder of the path following that segment.
+func (g *gen) edge(c *node, depth int, next string) {
+	if len(c.label) == 1 {
+		g.node(c, depth+1)
+		return
+	}
+
+	// the remaining segments of a compressed edge are compared at once
+	g.use(`strings`)
+	lit := `/` + strings.Join(c.label[1:], `/`)
+	g.p(`if strings.HasPrefix(%v, %q) && (len(%v) == %d || %v[%d] == '/') {`,
+		next, lit, next, len(lit), next, len(lit))
+	if c.uses() {
+		g.p(`p%d := %v[%d:]`, depth+2, next, len(lit))
+	}
+	g.node(c, depth+2)
+	g.p(`}`)
+}
+
+// tries writes the statements trying each of the given routes in order.
+func (g *gen) tries(routes []int) {
+	for _, i := range routes {
+		g.try(i, g.router.Routes[i])
+	}
+}
@@ -0,0 +1,232 @@
+// Package main declares the routes of the GitHub API, the route set commonly
+// used to compare the performance of routers, for testing and benchmarking the
+// strategies of generated routers. Generate the router and its benchmarks with:
+//
+//	routepiler gen -o routes.go -bench routes_test.go
+//	go test -bench .
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Router struct {
+	GetAuthorizations                                API `get:"/authorizations"`
+	GetAuthorizationsId                              API `get:"/authorizations/:id"`
+	PostAuthorizations                               API `post:"/authorizations"`
+	DeleteAuthorizationsId                           API `delete:"/authorizations/:id"`
+	GetApplicationsClientIdTokensAccessToken         API `get:"/applications/:clientId/tokens/:accessToken"`
+	DeleteApplicationsClientIdTokens                 API `delete:"/applications/:clientId/tokens"`
+	DeleteApplicationsClientIdTokensAccessToken      API `delete:"/applications/:clientId/tokens/:accessToken"`
+	GetEvents                                        API `get:"/events"`
+	GetReposOwnerRepoEvents                          API `get:"/repos/:owner/:repo/events"`
+	GetNetworksOwnerRepoEvents                       API `get:"/networks/:owner/:repo/events"`
+	GetOrgsOrgEvents                                 API `get:"/orgs/:org/events"`
+	GetUsersUserReceivedEvents                       API `get:"/users/:user/received_events"`
+	GetUsersUserReceivedEventsPublic                 API `get:"/users/:user/received_events/public"`
+	GetUsersUserEvents                               API `get:"/users/:user/events"`
+	GetUsersUserEventsPublic                         API `get:"/users/:user/events/public"`
+	GetUsersUserEventsOrgsOrg                        API `get:"/users/:user/events/orgs/:org"`
+	GetFeeds                                         API `get:"/feeds"`
+	GetNotifications                                 API `get:"/notifications"`
+	GetReposOwnerRepoNotifications                   API `get:"/repos/:owner/:repo/notifications"`
+	PutNotifications                                 API `put:"/notifications"`
+	PutReposOwnerRepoNotifications                   API `put:"/repos/:owner/:repo/notifications"`
+	GetNotificationsThreadsId                        API `get:"/notifications/threads/:id"`
+	GetNotificationsThreadsIdSubscription            API `get:"/notifications/threads/:id/subscription"`
+	PutNotificationsThreadsIdSubscription            API `put:"/notifications/threads/:id/subscription"`
+	DeleteNotificationsThreadsIdSubscription         API `delete:"/notifications/threads/:id/subscription"`
+	GetReposOwnerRepoStargazers                      API `get:"/repos/:owner/:repo/stargazers"`
+	GetUsersUserStarred                              API `get:"/users/:user/starred"`
+	GetUserStarred                                   API `get:"/user/starred"`
+	GetUserStarredOwnerRepo                          API `get:"/user/starred/:owner/:repo"`
+	PutUserStarredOwnerRepo                          API `put:"/user/starred/:owner/:repo"`
+	DeleteUserStarredOwnerRepo                       API `delete:"/user/starred/:owner/:repo"`
+	GetReposOwnerRepoSubscribers                     API `get:"/repos/:owner/:repo/subscribers"`
+	GetUsersUserSubscriptions                        API `get:"/users/:user/subscriptions"`
+	GetUserSubscriptions                             API `get:"/user/subscriptions"`
+	GetReposOwnerRepoSubscription                    API `get:"/repos/:owner/:repo/subscription"`
+	PutReposOwnerRepoSubscription                    API `put:"/repos/:owner/:repo/subscription"`
+	DeleteReposOwnerRepoSubscription                 API `delete:"/repos/:owner/:repo/subscription"`
+	GetUserSubscriptionsOwnerRepo                    API `get:"/user/subscriptions/:owner/:repo"`
+	PutUserSubscriptionsOwnerRepo                    API `put:"/user/subscriptions/:owner/:repo"`
+	DeleteUserSubscriptionsOwnerRepo                 API `delete:"/user/subscriptions/:owner/:repo"`
+	GetUsersUserGists                                API `get:"/users/:user/gists"`
+	GetGists                                         API `get:"/gists"`
+	GetGistsId                                       API `get:"/gists/:id"`
+	PostGists                                        API `post:"/gists"`
+	PutGistsIdStar                                   API `put:"/gists/:id/star"`
+	DeleteGistsIdStar                                API `delete:"/gists/:id/star"`
+	GetGistsIdStar                                   API `get:"/gists/:id/star"`
+	PostGistsIdForks                                 API `post:"/gists/:id/forks"`
+	DeleteGistsId                                    API `delete:"/gists/:id"`
+	GetReposOwnerRepoGitBlobsSha                     API `get:"/repos/:owner/:repo/git/blobs/:sha"`
+	PostReposOwnerRepoGitBlobs                       API `post:"/repos/:owner/:repo/git/blobs"`
+	GetReposOwnerRepoGitCommitsSha                   API `get:"/repos/:owner/:repo/git/commits/:sha"`
+	PostReposOwnerRepoGitCommits                     API `post:"/repos/:owner/:repo/git/commits"`
+	GetReposOwnerRepoGitRefs                         API `get:"/repos/:owner/:repo/git/refs"`
+	PostReposOwnerRepoGitRefs                        API `post:"/repos/:owner/:repo/git/refs"`
+	GetReposOwnerRepoGitTagsSha                      API `get:"/repos/:owner/:repo/git/tags/:sha"`
+	PostReposOwnerRepoGitTags                        API `post:"/repos/:owner/:repo/git/tags"`
+	GetReposOwnerRepoGitTreesSha                     API `get:"/repos/:owner/:repo/git/trees/:sha"`
+	PostReposOwnerRepoGitTrees                       API `post:"/repos/:owner/:repo/git/trees"`
+	GetIssues                                        API `get:"/issues"`
+	GetUserIssues                                    API `get:"/user/issues"`
+	GetOrgsOrgIssues                                 API `get:"/orgs/:org/issues"`
+	GetReposOwnerRepoIssues                          API `get:"/repos/:owner/:repo/issues"`
+	GetReposOwnerRepoIssuesNumber                    API `get:"/repos/:owner/:repo/issues/:number"`
+	PostReposOwnerRepoIssues                         API `post:"/repos/:owner/:repo/issues"`
+	GetReposOwnerRepoAssignees                       API `get:"/repos/:owner/:repo/assignees"`
+	GetReposOwnerRepoAssigneesAssignee               API `get:"/repos/:owner/:repo/assignees/:assignee"`
+	GetReposOwnerRepoIssuesNumberComments            API `get:"/repos/:owner/:repo/issues/:number/comments"`
+	PostReposOwnerRepoIssuesNumberComments           API `post:"/repos/:owner/:repo/issues/:number/comments"`
+	GetReposOwnerRepoIssuesNumberEvents              API `get:"/repos/:owner/:repo/issues/:number/events"`
+	GetReposOwnerRepoLabels                          API `get:"/repos/:owner/:repo/labels"`
+	GetReposOwnerRepoLabelsName                      API `get:"/repos/:owner/:repo/labels/:name"`
+	PostReposOwnerRepoLabels                         API `post:"/repos/:owner/:repo/labels"`
+	DeleteReposOwnerRepoLabelsName                   API `delete:"/repos/:owner/:repo/labels/:name"`
+	GetReposOwnerRepoIssuesNumberLabels              API `get:"/repos/:owner/:repo/issues/:number/labels"`
+	PostReposOwnerRepoIssuesNumberLabels             API `post:"/repos/:owner/:repo/issues/:number/labels"`
+	DeleteReposOwnerRepoIssuesNumberLabelsName       API `delete:"/repos/:owner/:repo/issues/:number/labels/:name"`
+	PutReposOwnerRepoIssuesNumberLabels              API `put:"/repos/:owner/:repo/issues/:number/labels"`
+	DeleteReposOwnerRepoIssuesNumberLabels           API `delete:"/repos/:owner/:repo/issues/:number/labels"`
+	GetReposOwnerRepoMilestonesNumberLabels          API `get:"/repos/:owner/:repo/milestones/:number/labels"`
+	GetReposOwnerRepoMilestones                      API `get:"/repos/:owner/:repo/milestones"`
+	GetReposOwnerRepoMilestonesNumber                API `get:"/repos/:owner/:repo/milestones/:number"`
+	PostReposOwnerRepoMilestones                     API `post:"/repos/:owner/:repo/milestones"`
+	DeleteReposOwnerRepoMilestonesNumber             API `delete:"/repos/:owner/:repo/milestones/:number"`
+	GetEmojis                                        API `get:"/emojis"`
+	GetGitignoreTemplates                            API `get:"/gitignore/templates"`
+	GetGitignoreTemplatesName                        API `get:"/gitignore/templates/:name"`
+	PostMarkdown                                     API `post:"/markdown"`
+	PostMarkdownRaw                                  API `post:"/markdown/raw"`
+	GetMeta                                          API `get:"/meta"`
+	GetRateLimit                                     API `get:"/rate_limit"`
+	GetUsersUserOrgs                                 API `get:"/users/:user/orgs"`
+	GetUserOrgs                                      API `get:"/user/orgs"`
+	GetOrgsOrg                                       API `get:"/orgs/:org"`
+	GetOrgsOrgMembers                                API `get:"/orgs/:org/members"`
+	GetOrgsOrgMembersUser                            API `get:"/orgs/:org/members/:user"`
+	DeleteOrgsOrgMembersUser                         API `delete:"/orgs/:org/members/:user"`
+	GetOrgsOrgPublicMembers                          API `get:"/orgs/:org/public_members"`
+	GetOrgsOrgPublicMembersUser                      API `get:"/orgs/:org/public_members/:user"`
+	PutOrgsOrgPublicMembersUser                      API `put:"/orgs/:org/public_members/:user"`
+	DeleteOrgsOrgPublicMembersUser                   API `delete:"/orgs/:org/public_members/:user"`
+	GetOrgsOrgTeams                                  API `get:"/orgs/:org/teams"`
+	GetTeamsId                                       API `get:"/teams/:id"`
+	PostOrgsOrgTeams                                 API `post:"/orgs/:org/teams"`
+	DeleteTeamsId                                    API `delete:"/teams/:id"`
+	GetTeamsIdMembers                                API `get:"/teams/:id/members"`
+	GetTeamsIdMembersUser                            API `get:"/teams/:id/members/:user"`
+	PutTeamsIdMembersUser                            API `put:"/teams/:id/members/:user"`
+	DeleteTeamsIdMembersUser                         API `delete:"/teams/:id/members/:user"`
+	GetTeamsIdRepos                                  API `get:"/teams/:id/repos"`
+	GetTeamsIdReposOwnerRepo                         API `get:"/teams/:id/repos/:owner/:repo"`
+	PutTeamsIdReposOwnerRepo                         API `put:"/teams/:id/repos/:owner/:repo"`
+	DeleteTeamsIdReposOwnerRepo                      API `delete:"/teams/:id/repos/:owner/:repo"`
+	GetUserTeams                                     API `get:"/user/teams"`
+	GetReposOwnerRepoPulls                           API `get:"/repos/:owner/:repo/pulls"`
+	GetReposOwnerRepoPullsNumber                     API `get:"/repos/:owner/:repo/pulls/:number"`
+	PostReposOwnerRepoPulls                          API `post:"/repos/:owner/:repo/pulls"`
+	GetReposOwnerRepoPullsNumberCommits              API `get:"/repos/:owner/:repo/pulls/:number/commits"`
+	GetReposOwnerRepoPullsNumberFiles                API `get:"/repos/:owner/:repo/pulls/:number/files"`
+	GetReposOwnerRepoPullsNumberMerge                API `get:"/repos/:owner/:repo/pulls/:number/merge"`
+	PutReposOwnerRepoPullsNumberMerge                API `put:"/repos/:owner/:repo/pulls/:number/merge"`
+	GetReposOwnerRepoPullsNumberComments             API `get:"/repos/:owner/:repo/pulls/:number/comments"`
+	PutReposOwnerRepoPullsNumberComments             API `put:"/repos/:owner/:repo/pulls/:number/comments"`
+	GetUserRepos                                     API `get:"/user/repos"`
+	GetUsersUserRepos                                API `get:"/users/:user/repos"`
+	GetOrgsOrgRepos                                  API `get:"/orgs/:org/repos"`
+	GetRepositories                                  API `get:"/repositories"`
+	PostUserRepos                                    API `post:"/user/repos"`
+	PostOrgsOrgRepos                                 API `post:"/orgs/:org/repos"`
+	GetReposOwnerRepo                                API `get:"/repos/:owner/:repo"`
+	GetReposOwnerRepoContributors                    API `get:"/repos/:owner/:repo/contributors"`
+	GetReposOwnerRepoLanguages                       API `get:"/repos/:owner/:repo/languages"`
+	GetReposOwnerRepoTeams                           API `get:"/repos/:owner/:repo/teams"`
+	GetReposOwnerRepoTags                            API `get:"/repos/:owner/:repo/tags"`
+	GetReposOwnerRepoBranches                        API `get:"/repos/:owner/:repo/branches"`
+	GetReposOwnerRepoBranchesBranch                  API `get:"/repos/:owner/:repo/branches/:branch"`
+	DeleteReposOwnerRepo                             API `delete:"/repos/:owner/:repo"`
+	GetReposOwnerRepoCollaborators                   API `get:"/repos/:owner/:repo/collaborators"`
+	GetReposOwnerRepoCollaboratorsUser               API `get:"/repos/:owner/:repo/collaborators/:user"`
+	PutReposOwnerRepoCollaboratorsUser               API `put:"/repos/:owner/:repo/collaborators/:user"`
+	DeleteReposOwnerRepoCollaboratorsUser            API `delete:"/repos/:owner/:repo/collaborators/:user"`
+	GetReposOwnerRepoComments                        API `get:"/repos/:owner/:repo/comments"`
+	GetReposOwnerRepoCommitsShaComments              API `get:"/repos/:owner/:repo/commits/:sha/comments"`
+	PostReposOwnerRepoCommitsShaComments             API `post:"/repos/:owner/:repo/commits/:sha/comments"`
+	GetReposOwnerRepoCommentsId                      API `get:"/repos/:owner/:repo/comments/:id"`
+	DeleteReposOwnerRepoCommentsId                   API `delete:"/repos/:owner/:repo/comments/:id"`
+	GetReposOwnerRepoCommits                         API `get:"/repos/:owner/:repo/commits"`
+	GetReposOwnerRepoCommitsSha                      API `get:"/repos/:owner/:repo/commits/:sha"`
+	GetReposOwnerRepoReadme                          API `get:"/repos/:owner/:repo/readme"`
+	GetReposOwnerRepoKeys                            API `get:"/repos/:owner/:repo/keys"`
+	GetReposOwnerRepoKeysId                          API `get:"/repos/:owner/:repo/keys/:id"`
+	PostReposOwnerRepoKeys                           API `post:"/repos/:owner/:repo/keys"`
+	DeleteReposOwnerRepoKeysId                       API `delete:"/repos/:owner/:repo/keys/:id"`
+	GetReposOwnerRepoDownloads                       API `get:"/repos/:owner/:repo/downloads"`
+	GetReposOwnerRepoDownloadsId                     API `get:"/repos/:owner/:repo/downloads/:id"`
+	DeleteReposOwnerRepoDownloadsId                  API `delete:"/repos/:owner/:repo/downloads/:id"`
+	GetReposOwnerRepoForks                           API `get:"/repos/:owner/:repo/forks"`
+	PostReposOwnerRepoForks                          API `post:"/repos/:owner/:repo/forks"`
+	GetReposOwnerRepoHooks                           API `get:"/repos/:owner/:repo/hooks"`
+	GetReposOwnerRepoHooksId                         API `get:"/repos/:owner/:repo/hooks/:id"`
+	PostReposOwnerRepoHooks                          API `post:"/repos/:owner/:repo/hooks"`
+	PostReposOwnerRepoHooksIdTests                   API `post:"/repos/:owner/:repo/hooks/:id/tests"`
+	DeleteReposOwnerRepoHooksId                      API `delete:"/repos/:owner/:repo/hooks/:id"`
+	PostReposOwnerRepoMerges                         API `post:"/repos/:owner/:repo/merges"`
+	GetReposOwnerRepoReleases                        API `get:"/repos/:owner/:repo/releases"`
+	GetReposOwnerRepoReleasesId                      API `get:"/repos/:owner/:repo/releases/:id"`
+	PostReposOwnerRepoReleases                       API `post:"/repos/:owner/:repo/releases"`
+	DeleteReposOwnerRepoReleasesId                   API `delete:"/repos/:owner/:repo/releases/:id"`
+	GetReposOwnerRepoReleasesIdAssets                API `get:"/repos/:owner/:repo/releases/:id/assets"`
+	GetReposOwnerRepoStatsContributors               API `get:"/repos/:owner/:repo/stats/contributors"`
+	GetReposOwnerRepoStatsCommitActivity             API `get:"/repos/:owner/:repo/stats/commit_activity"`
+	GetReposOwnerRepoStatsCodeFrequency              API `get:"/repos/:owner/:repo/stats/code_frequency"`
+	GetReposOwnerRepoStatsParticipation              API `get:"/repos/:owner/:repo/stats/participation"`
+	GetReposOwnerRepoStatsPunchCard                  API `get:"/repos/:owner/:repo/stats/punch_card"`
+	GetReposOwnerRepoStatusesRef                     API `get:"/repos/:owner/:repo/statuses/:ref"`
+	PostReposOwnerRepoStatusesRef                    API `post:"/repos/:owner/:repo/statuses/:ref"`
+	GetSearchRepositories                            API `get:"/search/repositories"`
+	GetSearchCode                                    API `get:"/search/code"`
+	GetSearchIssues                                  API `get:"/search/issues"`
+	GetSearchUsers                                   API `get:"/search/users"`
+	GetLegacyIssuesSearchOwnerRepositoryStateKeyword API `get:"/legacy/issues/search/:owner/:repository/:state/:keyword"`
+	GetLegacyReposSearchKeyword                      API `get:"/legacy/repos/search/:keyword"`
+	GetLegacyUserSearchKeyword                       API `get:"/legacy/user/search/:keyword"`
+	GetLegacyUserEmailEmail                          API `get:"/legacy/user/email/:email"`
+	GetUsersUser                                     API `get:"/users/:user"`
+	GetUser                                          API `get:"/user"`
+	GetUsers                                         API `get:"/users"`
+	GetUserEmails                                    API `get:"/user/emails"`
+	PostUserEmails                                   API `post:"/user/emails"`
+	DeleteUserEmails                                 API `delete:"/user/emails"`
+	GetUsersUserFollowers                            API `get:"/users/:user/followers"`
+	GetUserFollowers                                 API `get:"/user/followers"`
+	GetUsersUserFollowing                            API `get:"/users/:user/following"`
+	GetUserFollowing                                 API `get:"/user/following"`
+	GetUserFollowingUser                             API `get:"/user/following/:user"`
+	GetUsersUserFollowingTargetUser                  API `get:"/users/:user/following/:targetUser"`
+	PutUserFollowingUser                             API `put:"/user/following/:user"`
+	DeleteUserFollowingUser                          API `delete:"/user/following/:user"`
+	GetUsersUserKeys                                 API `get:"/users/:user/keys"`
+	GetUserKeys                                      API `get:"/user/keys"`
+	GetUserKeysId                                    API `get:"/user/keys/:id"`
+	PostUserKeys                                     API `post:"/user/keys"`
+	DeleteUserKeysId                                 API `delete:"/user/keys/:id"`
+}
+
+func newHandler() http.Handler { return new(Router) }
+
+// API holds the value of each param of the GitHub API.
+type API struct {
+	AccessToken, Assignee, Branch, ClientID, Email, ID, Keyword, Name       string
+	Number, Org, Owner, Ref, Repo, Repository, Sha, State, TargetUser, User string
+}
+
+func (h *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, r.Method, h.AccessToken, h.Assignee, h.Branch, h.ClientID, h.Email,
+		h.ID, h.Keyword, h.Name, h.Number, h.Org, h.Owner, h.Ref, h.Repo, h.Repository,
+		h.Sha, h.State, h.TargetUser, h.User)
+}
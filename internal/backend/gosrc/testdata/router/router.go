@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Router struct {
+	Root    http.Handler                                   `get:"/"`
+	Health  http.HandlerFunc                               `get:"/health"`
+	Version Version                                        `get:"/v{major}.{minor}"`
+	Orgs    Orgs                                           `path:"/orgs"`
+	Org     Orgs                                           `get:"/orgs/:org([a-z]+){3-20}" func:"GetOrg"`
+	User    Users                                          `get:"/orgs/:org/users/:user?since&page{default: 1}&limit{required: true}" func:"GetUser"`
+	Report  Report                                         `get:"/reports/:id?from&every{default: 1h}&fmt"`
+	Tunnel  Users                                          `connect:"/tunnel"`
+	Files   Files                                          "path:\"/files/:path*/raw\""
+	Dl      Download                                       `get:"/dl/:id"`
+	Team    Team                                           `get:"/orgs/:org/teams/:team/:id"`
+	Time    http.Handler                                   `path:"/time" func:"handleTime"`
+	Echo    func(http.ResponseWriter, *http.Request) error `get:"/echo"`
+	Check   func(http.ResponseWriter, *http.Request)       `get:"/check"`
+	Legacy  http.Handler                                   `path:"/legacy" func:"handleLegacy"`
+	ItemsV2 Items                                          `get:"/items" header:"X-API-Version: 2" func:"GetV2"`
+	Items   Items                                          `get:"/items" accept:"application/json, text/csv"`
+	NewItem Items                                          `post:"/items" consumes:"application/json" use:"auth"`
+	Stats   Stats                                          `get:"/stats/:name"`
+	Archive Archive                                        `get:"/archive/:year/:month?{default: 1}/:day?"`
+
+	ErrorHandler func(http.ResponseWriter, *http.Request, error)
+
+	app *App
+	log *prefixLog
+}
+
+var handleLegacy http.Handler
+
+var handleTime = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, "time")
+})
+
+func (rt *Router) Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rt *Router) Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Audit", r.Method+" "+r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newHandler() http.Handler {
+	rt := &Router{
+		app: &App{Name: "app"},
+		log: &prefixLog{prefix: "log"},
+		Root: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "root")
+		}),
+		Health: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ok")
+		},
+	}
+	rt.Check = func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, rt.Validate())
+	}
+	return rt
+}
+
+type Version struct {
+	Major uint8
+	Minor uint8
+}
+
+func (h *Version) Get(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "version %d.%d", h.Major, h.Minor)
+}
+
+type Orgs struct {
+	Org string
+}
+
+func (h *Orgs) Get(w http.ResponseWriter, r *http.Request)    { fmt.Fprint(w, "orgs") }
+func (h *Orgs) Post(w http.ResponseWriter, r *http.Request)   { fmt.Fprint(w, "new org") }
+func (h *Orgs) GetOrg(w http.ResponseWriter, r *http.Request) { fmt.Fprintf(w, "org %v", h.Org) }
+
+type Users struct {
+	_     struct{} `pool:"true"`
+	Org   string
+	User  string `max:"8"`
+	Since *time.Time
+	Page  int `min:"1" max:"100"`
+	Limit uint16
+}
+
+func (h *Users) Connect(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "tunnel") }
+
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request) error {
+	switch h.User {
+	case "error":
+		return fmt.Errorf("user %v failed", h.User)
+	case "gone":
+		return goneError(h.User)
+	}
+	fmt.Fprintf(w, "user %v/%v page %d limit %d", h.Org, h.User, h.Page, h.Limit)
+	if h.Since != nil {
+		fmt.Fprintf(w, " since %v", h.Since.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+type goneError string
+
+func (e goneError) Error() string   { return "user " + string(e) + " was removed" }
+func (e goneError) StatusCode() int { return http.StatusGone }
+
+type Report struct {
+	_     struct{} `pool:"true"`
+	ID    int64
+	From  float64       `min:"0"`
+	Every time.Duration `max:"24h"`
+	Fmt   Format
+}
+
+// Reset is called before a Report is returned to its pool.
+func (h *Report) Reset() {
+	*h = Report{}
+}
+
+func (h *Report) Get(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "report %d from %v every %v as %v", h.ID, h.From, h.Every, h.Fmt)
+}
+
+type Format string
+
+func (f *Format) UnmarshalText(text []byte) error {
+	switch s := strings.ToLower(string(text)); s {
+	case "json", "csv":
+		*f = Format(s)
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", text)
+}
+
+type Files struct {
+	Path string
+}
+
+func (h *Files) Get(w http.ResponseWriter, r *http.Request) { fmt.Fprintf(w, "get %v", h.Path) }
+
+func (h *Files) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "%v %v", r.Method, h.Path)
+}
+
+type Download struct {
+	ID []byte
+}
+
+func (h *Download) Get(w http.ResponseWriter, r *http.Request) { fmt.Fprintf(w, "dl %s", h.ID) }
+
+type Items struct {
+	_ struct{} `use:"audit"`
+}
+
+func (h *Items) Get(w http.ResponseWriter, r *http.Request)   { fmt.Fprint(w, "items") }
+func (h *Items) GetV2(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "items v2") }
+func (h *Items) Post(w http.ResponseWriter, r *http.Request)  { fmt.Fprint(w, "new item") }
+
+type Team struct {
+	*Orgs
+	Base
+	Team string
+}
+
+type Base struct {
+	ID int
+}
+
+func (h *Team) Get(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "team %v/%v %d", h.Org, h.Team, h.ID)
+}
+
+type App struct {
+	Name string
+}
+
+type Logger interface {
+	Log(msg string) string
+}
+
+type prefixLog struct {
+	prefix string
+}
+
+func (l *prefixLog) Log(msg string) string { return l.prefix + ": " + msg }
+
+type Tx struct {
+	ID string
+}
+
+func (rt *Router) NewTx(r *http.Request) (*Tx, error) {
+	if r.Header.Get("X-Fail") != "" {
+		return nil, fmt.Errorf("tx failed")
+	}
+	return &Tx{ID: r.URL.Path}, nil
+}
+
+type Stats struct {
+	Name string
+}
+
+func (h *Stats) Get(w http.ResponseWriter, r *http.Request, app *App, log Logger, tx *Tx,
+	ctx context.Context, rt *Router) {
+	fmt.Fprintf(w, "%v %v tx %v ctx %v router %v",
+		app.Name, log.Log(h.Name), tx.ID, ctx != nil, rt.app == app)
+}
+
+type Archive struct {
+	Year, Month, Day int
+}
+
+func (h *Archive) Get(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "archive %d-%d-%d", h.Year, h.Month, h.Day)
+}
@@ -0,0 +1,33 @@
+// Package compile generates code from analyzed routes using the currently
+// configured backend.
+package compile
+
+import (
+	"bytes"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/backend"
+	"github.com/cstockton/routepiler/internal/backend/gosrc"
+)
+
+// Backend is the backend used when none is given.
+var Backend backend.Backend = gosrc.New()
+
+// Load analyzes the named router struct declared within the non-test Go files
+// of dir and returns the source generated for it by the backend b, or Backend
+// when b is nil.
+func Load(dir, router string, b backend.Backend) ([]byte, error) {
+	r, err := analyze.Load(dir, router)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		b = Backend
+	}
+
+	var buf bytes.Buffer
+	if err := b.Generate(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+// Package format implements canonical formatting of route patterns and of the
+// route struct tags within Go source files.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/scanner"
+	"github.com/cstockton/routepiler/internal/tag"
+	rtoken "github.com/cstockton/routepiler/internal/token"
+)
+
+// Pattern returns the canonical form of the given route pattern. Insignificant
+// whitespace is removed, template pairs are separated by ", " with a single
+// space following each key and values copied verbatim, strings are single
+// quoted and regexps are wrapped in bare parens when balanced. The result always
+// scans to the same tokens as the given pattern, ignoring whitespace and
+// positions.
+func Pattern(pat string) (string, error) {
+	toks, err := scanner.Scan(pat)
+	if err != nil {
+		return ``, err
+	}
+
+	var (
+		buf bytes.Buffer
+		tpl int
+		key bool // within the key of a template pair
+	)
+	for i, tok := range toks {
+		switch tok.Lex {
+		case rtoken.EOF:
+		case rtoken.WHITESPACE:
+			// whitespace is only kept within templates where it separates two
+			// tokens that would otherwise scan as one.
+			if tpl > 0 && separates(toks, i) {
+				buf.WriteByte(' ')
+			}
+		case rtoken.METHOD:
+			buf.WriteString(tok.Lit + ` `)
+		case rtoken.STRING:
+			buf.WriteString(Quote(tok.Lit))
+		case rtoken.REGEXP:
+			buf.WriteString(`(` + regexp(tok.Lit) + `)`)
+		case rtoken.LBRACE:
+			if tpl++; tpl == 1 {
+				key = true
+			}
+			buf.WriteString(tok.Lit)
+		case rtoken.RBRACE:
+			tpl--
+			buf.WriteString(tok.Lit)
+		case rtoken.COLON, rtoken.COMMA:
+			// only the separators of top level pairs are respaced, any within a
+			// value such as `{default: a:b}` are copied verbatim.
+			buf.WriteString(tok.Lit)
+			if tpl == 1 && (key || tok.Lex == rtoken.COMMA) {
+				key = tok.Lex == rtoken.COMMA
+				buf.WriteByte(' ')
+			}
+		default:
+			buf.WriteString(tok.Lit)
+		}
+	}
+
+	out := buf.String()
+	if err = equivalent(toks, out); err != nil {
+		return ``, fmt.Errorf(`unable to format pattern %q: %v`, pat, err)
+	}
+	return out, nil
+}
+
+// Source formats the route patterns within the struct tags of the given Go
+// source file. Only the tags which contain route patterns are rewritten, the
+// remaining source including comments is returned unchanged.
+func Source(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, ``, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		beg, end int
+		lit      string
+	}
+	var edits []edit
+	ast.Inspect(f, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		fd, ok := n.(*ast.Field)
+		if !ok || fd.Tag == nil {
+			return true
+		}
+
+		var lit string
+		if lit, err = Tag(fd.Tag.Value); err != nil {
+			err = fmt.Errorf(`%v: %v`, fset.Position(fd.Tag.Pos()), err)
+			return false
+		}
+		if lit != fd.Tag.Value {
+			beg := fset.Position(fd.Tag.Pos()).Offset
+			edits = append(edits, edit{beg, beg + len(fd.Tag.Value), lit})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].beg < edits[j].beg })
+
+	var buf bytes.Buffer
+	var off int
+	for _, e := range edits {
+		buf.Write(src[off:e.beg])
+		buf.WriteString(e.lit)
+		off = e.end
+	}
+	buf.Write(src[off:])
+	return buf.Bytes(), nil
+}
+
+// Tag formats the route patterns within the given Go string literal of a
+// struct tag, returning lit unchanged when it declares no routes.
+func Tag(lit string) (string, error) {
+	str, err := tag.Unquote(lit)
+	if err != nil {
+		return ``, err
+	}
+	ps, err := tag.Parse(str)
+	if err != nil || len(ps.Routes()) == 0 {
+		// tags which are not for routes are not our concern, go vet reports
+		// malformed struct tags.
+		return lit, nil
+	}
+
+	for i, p := range ps {
+		if !p.Route() {
+			continue
+		}
+		if ps[i].Value, err = Pattern(p.Value); err != nil {
+			return ``, fmt.Errorf(`%v tag: %v`, p.Key, err)
+		}
+	}
+	if out := tag.Quote(ps.String()); out != lit {
+		return out, nil
+	}
+	return lit, nil
+}
+
+// separates returns true if the whitespace token at idx is the first of a run
+// between two tokens which must remain apart.
+func separates(toks rtoken.Tokens, idx int) bool {
+	if idx == 0 {
+		return false
+	}
+	switch toks[idx-1].Lex {
+	case rtoken.WHITESPACE, rtoken.LBRACE, rtoken.COLON, rtoken.COMMA:
+		return false
+	}
+	for idx < len(toks) && toks[idx].Lex == rtoken.WHITESPACE {
+		idx++
+	}
+	if idx >= len(toks) {
+		return false
+	}
+	switch toks[idx].Lex {
+	case rtoken.RBRACE, rtoken.COLON, rtoken.COMMA, rtoken.EOF:
+		return false
+	}
+	return true
+}
+
+// Quote returns s as a string literal within a route pattern, preferring single
+// quotes, then back quotes and finally double quotes.
+func Quote(s string) string {
+	switch {
+	case !strings.Contains(s, `'`) && !strings.HasSuffix(s, `\`):
+		return `'` + s + `'`
+	case !strings.Contains(s, "`"):
+		return "`" + s + "`"
+	default:
+		return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+	}
+}
+
+func regexp(s string) string {
+	if s == `` || !strings.ContainsAny(s[:1], "`'\"\n") && balanced(s) {
+		return s
+	}
+	return Quote(s)
+}
+
+func balanced(s string) bool {
+	var depth int
+	for _, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// equivalent returns a non-nil error if the formatted pattern does not scan to
+// the same lexemes and literals as the original tokens.
+func equivalent(toks rtoken.Tokens, out string) error {
+	got, err := scanner.Scan(out)
+	if err != nil {
+		return err
+	}
+	exp := significant(toks)
+	if got = significant(got); len(exp) != len(got) {
+		return fmt.Errorf(`exp %d tokens; got %d`, len(exp), len(got))
+	}
+	for i := range exp {
+		if exp[i].Lex != got[i].Lex || exp[i].Lit != got[i].Lit {
+			return fmt.Errorf(`exp %v; got %v`, exp[i], got[i])
+		}
+	}
+	return nil
+}
+
+func significant(toks rtoken.Tokens) (out rtoken.Tokens) {
+	for _, tok := range toks {
+		if tok.Lex != rtoken.WHITESPACE {
+			out = append(out, tok)
+		}
+	}
+	return
+}
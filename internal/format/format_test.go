@@ -0,0 +1,188 @@
+package format
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		pat string
+		exp string
+	}{
+		// already canonical
+		{`/`, `/`},
+		{`GET /`, `GET /`},
+		{`/users/:user`, `/users/:user`},
+		{`/users/:user([a-zA-Z]{6,20})`, `/users/:user([a-zA-Z]{6,20})`},
+		{`GET /static/:file*{2-3}`, `GET /static/:file*{2-3}`},
+		{`/users/:user?since&age{18-120}`, `/users/:user?since&age{18-120}`},
+		{`/files/:name.:ext`, `/files/:name.:ext`},
+		{`pre-{aaa}-and-{bbb}-post`, `pre-{aaa}-and-{bbb}-post`},
+
+		// leading whitespace
+		{"  /users", `/users`},
+		{"\n\t/users/:user", `/users/:user`},
+
+		// template pairs
+		{`:aaa{min:7,max:15}`, `:aaa{min: 7, max: 15}`},
+		{`:aaa{min :7 ,  max:   15}`, `:aaa{min: 7, max: 15}`},
+		{`{ name: aaa }`, `{name: aaa}`},
+		{"{name: aaa}-and-{name:`bbb`, regexp: `[a-z0-9]{1-3}`, max: 25}",
+			`{name: aaa}-and-{name: 'bbb', regexp: '[a-z0-9]{1-3}', max: 25}`},
+		{`:aaa{'regex': .+?}`, `:aaa{'regex': .+?}`},
+		{`:month?{default:1}`, `:month?{default: 1}`},
+		{`:month?{default: a:b}`, `:month?{default: a:b}`},
+		{`:month?{default:a: b,min:1}`, `:month?{default: a:b, min: 1}`},
+
+		// strings
+		{`:aaa{"min":7}`, `:aaa{'min': 7}`},
+		{"{aaa: `it's`}", "{aaa: `it's`}"},
+		{`{aaa: "it's"}`, "{aaa: `it's`}"},
+
+		// regexps
+		{":aa(`lit`)", `:aa(lit)`},
+		{`:aa("lit")`, `:aa(lit)`},
+		{`:aa('[0-9]+')`, `:aa([0-9]+)`},
+		{`:aa(l(i)t)`, `:aa(l(i)t)`},
+		{":aa(`a)`)", `:aa('a)')`},
+		{":aa(`(a`)", `:aa('(a')`},
+		{"/:aa(\n\t\t[a-z]{3,10}\n\t)", `/:aa([a-z]{3,10})`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from pat %q exp %q`, idx, test.pat, test.exp)
+		got, err := Pattern(test.pat)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf("unexpected Pattern() result:\nexp: %v\ngot: %v\n", exp, got)
+		}
+
+		// canonical form is stable
+		again, err := Pattern(got)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp := got; exp != again {
+			t.Fatalf("exp Pattern() to be idempotent:\nexp: %v\ngot: %v\n", exp, again)
+		}
+	}
+}
+
+func TestPatternNegative(t *testing.T) {
+	tests := []struct {
+		pat string
+		exp string
+	}{
+		{`GET`, `ambiguous`},
+		{`:a:b`, `adjacent`},
+		{`:aa([0-9]`, `unbalanced`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from pat %q exp err %q`, idx, test.pat, test.exp)
+		_, err := Pattern(test.pat)
+		if err == nil {
+			t.Fatal(`exp non-nil err`)
+		}
+		if exp, got := test.exp, err.Error(); !strings.Contains(got, exp) {
+			t.Fatalf(`exp err %v to contain %v`, got, exp)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		lit string
+		exp string
+	}{
+		// unchanged
+		{"`get:\"/\"`", "`get:\"/\"`"},
+		{"`json:\"name\"`", "`json:\"name\"`"},
+		{"`min:\"3\" max:\"20\"`", "`min:\"3\" max:\"20\"`"},
+		{"`not a valid tag`", "`not a valid tag`"},
+
+		// route patterns formatted, others preserved
+		{"`get:\"/:a{min:7}\" func:\"GetA\"`", "`get:\"/:a{min: 7}\" func:\"GetA\"`"},
+		{"`path:\" /time\"  method:\"get\"`", "`path:\"/time\" method:\"get\"`"},
+		{`"get:\"/:a(` + "`b`" + `)\""`, "`get:\"/:a(b)\"`"},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from lit %v exp %v`, idx, test.lit, test.exp)
+		got, err := Tag(test.lit)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf("unexpected Tag() result:\nexp: %v\ngot: %v\n", exp, got)
+		}
+	}
+	t.Run(`Negative`, func(t *testing.T) {
+		_, err := Tag("`get:\"GET\"`")
+		if err == nil {
+			t.Fatal(`exp non-nil err`)
+		}
+		if exp, got := `get tag: ambiguous`, err.Error(); !strings.Contains(got, exp) {
+			t.Fatalf(`exp err %v to contain %v`, got, exp)
+		}
+	})
+}
+
+func TestSource(t *testing.T) {
+	const (
+		src = "package main\n\n" +
+			"// Router is a struct containing the routes.\n" +
+			"type Router struct {\n" +
+			"\t// The name may match against anything.\n" +
+			"\tRoot http.Handler `get:\"/\"`\n\n" +
+			"\tDate func(http.ResponseWriter, *http.Request) `path:\" /date\"` // date\n" +
+			"\tOrg  Orgs `get:\"/orgs/:org{min:3,max:20}\"  func:\"GetOrg\"`\n" +
+			"\tName string `json:\"name\"`\n" +
+			"}\n"
+		exp = "package main\n\n" +
+			"// Router is a struct containing the routes.\n" +
+			"type Router struct {\n" +
+			"\t// The name may match against anything.\n" +
+			"\tRoot http.Handler `get:\"/\"`\n\n" +
+			"\tDate func(http.ResponseWriter, *http.Request) `path:\"/date\"` // date\n" +
+			"\tOrg  Orgs `get:\"/orgs/:org{min: 3, max: 20}\" func:\"GetOrg\"`\n" +
+			"\tName string `json:\"name\"`\n" +
+			"}\n"
+	)
+	got, err := Source([]byte(src))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if exp, got := exp, string(got); exp != got {
+		t.Fatalf("unexpected Source() result:\nexp:\n%v\ngot:\n%v\n", exp, got)
+	}
+
+	again, err := Source(got)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if exp, got := exp, string(again); exp != got {
+		t.Fatalf("exp Source() to be idempotent:\nexp:\n%v\ngot:\n%v\n", exp, got)
+	}
+
+	t.Run(`Negative`, func(t *testing.T) {
+		tests := []struct {
+			src string
+			exp string
+		}{
+			{"package main\n\ntype R struct {\n\tA int `get:\"/:a:b\"`\n}\n",
+				`4:8: get tag: adjacent`},
+			{"package main\n\ntype R struct {\n", `expected`},
+		}
+		for idx, test := range tests {
+			t.Logf(`test #%.2d - exp err %q`, idx, test.exp)
+			_, err := Source([]byte(test.src))
+			if err == nil {
+				t.Fatal(`exp non-nil err`)
+			}
+			if exp, got := test.exp, err.Error(); !strings.Contains(got, exp) {
+				t.Fatalf(`exp err %v to contain %v`, got, exp)
+			}
+		}
+	})
+}
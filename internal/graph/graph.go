@@ -0,0 +1,300 @@
+// Package graph renders the routes of a router struct as a prefix tree of path
+// segments, in the Graphviz DOT and Mermaid flowchart formats.
+//
+// Each node below the root is a path segment, where params are listed beneath
+// the segment with their Go type, regexp and bounds. Segments share a node when
+// they have the same shape, that is the same literals and params with the same
+// regexp and bounds in the same order, however their params are named. The
+// leaves of a node are the routes ending at that segment, labeled with their
+// http method, handler and query params. Conflicts are highlighted: leaves for
+// the same method at the same node, a leaf for any method alongside any other
+// leaf, and sibling segments holding only a param without a regexp, which can
+// never be told apart.
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/introspect"
+	"github.com/cstockton/routepiler/internal/parser"
+)
+
+// Tree is the prefix tree of the path segments of a set of routes.
+type Tree struct {
+	Root *Node
+}
+
+// Node is a single path segment within a tree.
+type Node struct {
+	Label    string // path segments and their params, one per line
+	Children []*Node
+	Leaves   []*Leaf
+	Conflict bool // ambiguous with a sibling segment
+	key      segKey
+	shape    string   // identifies the segments sharing the node
+	segs     []string // distinct segments sharing the node
+	params   []string // distinct params of those segments
+}
+
+// Leaf is a route ending at a node.
+type Leaf struct {
+	Label    string // http method, handler and query params, one per line
+	Method   string // upper case http method, empty for any method
+	Handler  string
+	Conflict bool // another leaf of the node has the same method or either has none
+}
+
+// segKey classifies a segment, where two sibling segments with a key of kindParam
+// are ambiguous.
+type segKey int
+
+const (
+	kindOther segKey = iota
+	kindParam
+	kindWild
+)
+
+// Load parses the non-test Go files within dir and returns the tree for the
+// routes of the named router struct.
+func Load(dir, router string) (*Tree, error) {
+	_, routes, err := introspect.ParseDir(dir, router)
+	if err != nil {
+		return nil, err
+	}
+	return New(router, routes)
+}
+
+// New returns the tree for a set of routes, with a root labeled name.
+func New(name string, routes []*introspect.Route) (*Tree, error) {
+	t := &Tree{Root: &Node{Label: name}}
+	for _, r := range routes {
+		pr, err := parser.Parse(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf(`%v: invalid pattern %q: %v`, r.Pos, r.Pattern, err)
+		}
+		types := make(map[string]string)
+		for _, p := range r.Params {
+			types[p.Name] = p.Type
+		}
+
+		n := t.Root
+		for _, seg := range pr.Path {
+			n = n.child(seg, types)
+		}
+		n.add(r, pr, types)
+	}
+	return t, nil
+}
+
+func (n *Node) child(seg parser.Segment, types map[string]string) *Node {
+	var c *Node
+	for _, sib := range n.Children {
+		if sib.shape == shape(seg) {
+			c = sib
+			break
+		}
+	}
+	if c == nil {
+		c = &Node{shape: shape(seg)}
+		if len(seg) == 1 && seg[0].Param != nil && seg[0].Param.Regexp == `` {
+			c.key = kindParam
+			if seg[0].Param.Wild {
+				c.key = kindWild
+			}
+		}
+		for _, sib := range n.Children {
+			if c.key != kindOther && sib.key == c.key {
+				sib.Conflict, c.Conflict = true, true
+			}
+		}
+		n.Children = append(n.Children, c)
+	}
+
+	c.segs = appendNew(c.segs, `/`+seg.String())
+	for _, part := range seg {
+		if part.Param != nil {
+			c.params = appendNew(c.params, param(part.Param, types))
+		}
+	}
+	c.Label = strings.Join(append([]string{strings.Join(c.segs, `, `)}, c.params...), "\n")
+	return c
+}
+
+// shape returns the literals of a segment along with the regexp and bounds of
+// each param, which are the same for segments matching the same paths.
+func shape(seg parser.Segment) string {
+	var b strings.Builder
+	for _, part := range seg {
+		if prm := part.Param; prm != nil {
+			fmt.Fprintf(&b, `{%q %d %d %v %d %v}`,
+				prm.Regexp, prm.Min, prm.Max, prm.Wild, prm.Depth, prm.Optional)
+		} else {
+			fmt.Fprintf(&b, `%q`, part.Lit)
+		}
+	}
+	return b.String()
+}
+
+// appendNew appends s to list when it is not already present.
+func appendNew(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func (n *Node) add(r *introspect.Route, pr *parser.Route, types map[string]string) {
+	method := r.Method
+	if method == `` {
+		method = `ANY`
+	}
+	lines := []string{method + ` ` + r.Handler}
+	for _, prm := range pr.Query {
+		lines = append(lines, `?`+param(prm, types))
+	}
+
+	l := &Leaf{Label: strings.Join(lines, "\n"), Method: r.Method, Handler: r.Handler}
+	for _, sib := range n.Leaves {
+		if sib.Method == l.Method || sib.Method == `` || l.Method == `` {
+			sib.Conflict, l.Conflict = true, true
+		}
+	}
+	n.Leaves = append(n.Leaves, l)
+}
+
+// param returns the description of a param, such as "id int [0-9]+ {1-8}".
+func param(prm *parser.Param, types map[string]string) string {
+	typ := types[prm.Name]
+	if typ == `` {
+		typ = `string`
+	}
+	out := prm.Name + ` ` + typ
+	if prm.Regexp != `` {
+		out += ` ` + prm.Regexp
+	}
+	switch {
+	case prm.Min > 0 && prm.Max > 0:
+		out += fmt.Sprintf(` {%d-%d}`, prm.Min, prm.Max)
+	case prm.Min > 0:
+		out += fmt.Sprintf(` {min %d}`, prm.Min)
+	case prm.Max > 0:
+		out += fmt.Sprintf(` {max %d}`, prm.Max)
+	}
+	if prm.Wild {
+		out += ` wild`
+		if prm.Depth > 0 {
+			out += fmt.Sprintf(` [%d]`, prm.Depth)
+		}
+	}
+	if prm.Optional {
+		out += ` optional`
+	}
+	if prm.Required {
+		out += ` required`
+	}
+	if prm.Default != `` {
+		out += fmt.Sprintf(` default %q`, prm.Default)
+	}
+	return out
+}
+
+// walk calls fn for each node of the tree in depth first order, along with the
+// id of the node and the id of its parent, which is -1 for the root. It returns
+// the next unused id.
+func (t *Tree) walk(fn func(n *Node, id, parent int)) int {
+	var (
+		next  int
+		visit func(n *Node, parent int)
+	)
+	visit = func(n *Node, parent int) {
+		id := next
+		next += 1 + len(n.Leaves)
+		fn(n, id, parent)
+		for _, c := range n.Children {
+			visit(c, id)
+		}
+	}
+	visit(t.Root, -1)
+	return next
+}
+
+// DOT writes the tree as a Graphviz DOT digraph.
+func (t *Tree) DOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph routes {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	t.walk(func(n *Node, id, parent int) {
+		fmt.Fprintf(&b, "\tn%d [label=%v%v];\n", id, dotQuote(n.Label), dotConflict(n.Conflict))
+		if parent >= 0 {
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", parent, id)
+		}
+		for i, l := range n.Leaves {
+			fmt.Fprintf(&b, "\tn%d [label=%v, shape=ellipse%v];\n",
+				id+1+i, dotQuote(l.Label), dotConflict(l.Conflict))
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", id, id+1+i)
+		}
+	})
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotConflict(conflict bool) string {
+	if conflict {
+		return `, color=red, fontcolor=red, penwidth=2`
+	}
+	return ``
+}
+
+// dotQuote returns s as a DOT string, with each line left justified.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\l`).Replace(s)
+	if strings.Contains(s, `\l`) {
+		s += `\l`
+	}
+	return `"` + s + `"`
+}
+
+// Mermaid writes the tree as a Mermaid flowchart.
+func (t *Tree) Mermaid(w io.Writer) error {
+	var (
+		b         strings.Builder
+		conflicts []string
+	)
+	b.WriteString("flowchart LR\n")
+	t.walk(func(n *Node, id, parent int) {
+		fmt.Fprintf(&b, "  n%d[%v]\n", id, mermaidQuote(n.Label))
+		if parent >= 0 {
+			fmt.Fprintf(&b, "  n%d --> n%d\n", parent, id)
+		}
+		if n.Conflict {
+			conflicts = append(conflicts, fmt.Sprintf(`n%d`, id))
+		}
+		for i, l := range n.Leaves {
+			fmt.Fprintf(&b, "  n%d([%v])\n", id+1+i, mermaidQuote(l.Label))
+			fmt.Fprintf(&b, "  n%d --> n%d\n", id, id+1+i)
+			if l.Conflict {
+				conflicts = append(conflicts, fmt.Sprintf(`n%d`, id+1+i))
+			}
+		}
+	})
+	if len(conflicts) > 0 {
+		b.WriteString("  classDef conflict stroke:#d00,stroke-width:2px,color:#d00\n")
+		fmt.Fprintf(&b, "  class %v conflict\n", strings.Join(conflicts, `,`))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidQuote returns s as a quoted Mermaid label, escaping the characters
+// Mermaid would otherwise interpret.
+func mermaidQuote(s string) string {
+	s = strings.NewReplacer(`&`, `#amp;`, `"`, `#quot;`, `<`, `#lt;`, `>`, `#gt;`,
+		`#`, `#35;`, "\n", `<br/>`).Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cstockton/routepiler/internal/introspect"
+)
+
+const testDir = `testdata/router`
+
+func TestLoad(t *testing.T) {
+	tree, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	tests := []struct {
+		golden string
+		render func(*Tree, *bytes.Buffer) error
+	}{
+		{`routes.dot`, func(t *Tree, buf *bytes.Buffer) error { return t.DOT(buf) }},
+		{`routes.mmd`, func(t *Tree, buf *bytes.Buffer) error { return t.Mermaid(buf) }},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp tree to render as %v`, idx, test.golden)
+
+		var buf bytes.Buffer
+		if err := test.render(tree, &buf); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		exp, err := ioutil.ReadFile(filepath.Join(testDir, test.golden))
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if got := buf.String(); string(exp) != got {
+			t.Fatalf("exp output:\n%s\ngot:\n%s", exp, got)
+		}
+	}
+
+	if _, err := Load(testDir, `Bogus`); err == nil {
+		t.Fatal(`exp non-nil err`)
+	}
+}
+
+func TestNew(t *testing.T) {
+	route := func(method, pattern string) *introspect.Route {
+		return &introspect.Route{Method: method, Pattern: pattern, Handler: `H`}
+	}
+	tests := []struct {
+		routes []*introspect.Route
+		exp    []string // lines of the Mermaid output
+	}{
+		{[]*introspect.Route{route(`GET`, `/`)},
+			[]string{`n0["r"]`, `n1["/"]`, `n2(["GET H"])`, `n0 --> n1`, `n1 --> n2`}},
+		{[]*introspect.Route{route(``, `/a/`)},
+			[]string{`n1["/a"]`, `n2["/"]`, `n3(["ANY H"])`}},
+		{[]*introspect.Route{route(`GET`, `/a`), route(`GET`, `/a`), route(`PUT`, `/a`)},
+			[]string{`n2(["GET H"])`, `n3(["GET H"])`, `n4(["PUT H"])`, `class n2,n3 conflict`}},
+		{[]*introspect.Route{route(`GET`, `/a`), route(``, `/a`)},
+			[]string{`n2(["GET H"])`, `n3(["ANY H"])`, `class n2,n3 conflict`}},
+		{[]*introspect.Route{route(`GET`, `/:a`), route(`PUT`, `/:b`), route(`GET`, `/:c([0-9]+)`)},
+			[]string{`n1["/{a}, /{b}<br/>a string<br/>b string"]`, `n2(["GET H"])`,
+				`n3(["PUT H"])`, `n4["/{c}<br/>c string [0-9]+"]`}},
+		{[]*introspect.Route{route(`GET`, `/:a`), route(`GET`, `/:b{2-3}`), route(`GET`, `/:c([0-9]+)`)},
+			[]string{`n1["/{a}<br/>a string"]`, `n3["/{b}<br/>b string {2-3}"]`,
+				`n5["/{c}<br/>c string [0-9]+"]`, `class n1,n3 conflict`}},
+		{[]*introspect.Route{route(`GET`, `/:a*`), route(`GET`, `/:b*`), route(`GET`, `/:c`)},
+			[]string{`n1["/{a}, /{b}<br/>a string wild<br/>b string wild"]`, `class n2,n3 conflict`}},
+		{[]*introspect.Route{route(`GET`, `/:a*`), route(`GET`, `/:b*[2]`), route(`GET`, `/:c`)},
+			[]string{`n1["/{a}<br/>a string wild"]`, `class n1,n3 conflict`}},
+		{[]*introspect.Route{route(`GET`, `/:a{2-3}/{b?}?q{required: true}&r{default: "<x>"}`)},
+			[]string{`n1["/{a}<br/>a string {2-3}"]`, `n2["/{b}<br/>b string optional"]`,
+				`n3(["GET H<br/>?q string required<br/>?r string default #quot;#lt;x#gt;#quot;"])`}},
+		{[]*introspect.Route{route(`GET`, `/a#b`)}, []string{`n1["/a#35;b"]`}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp tree of %d routes to contain %v`, idx, len(test.routes), test.exp)
+
+		tree, err := New(`r`, test.routes)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		var buf bytes.Buffer
+		if err := tree.Mermaid(&buf); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		got := "\n" + strings.Replace(buf.String(), `  `, ``, -1)
+		for _, exp := range test.exp {
+			if !strings.Contains(got, "\n"+exp+"\n") {
+				t.Fatalf("exp output to contain:\n%v\ngot:%v", exp, got)
+			}
+		}
+	}
+
+	if _, err := New(`r`, []*introspect.Route{route(`GET`, `/{a`)}); err == nil {
+		t.Fatal(`exp non-nil err`)
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	tests := []struct {
+		in, exp string
+	}{
+		{`/a`, `"/a"`},
+		{`/"a"\`, `"/\"a\"\\"`},
+		{"/{a}\na string", `"/{a}\la string\l"`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp dotQuote(%q) to return %v`, idx, test.in, test.exp)
+		if got := dotQuote(test.in); test.exp != got {
+			t.Fatalf(`exp %v; got %v`, test.exp, got)
+		}
+	}
+}
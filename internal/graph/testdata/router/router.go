@@ -0,0 +1,37 @@
+package main
+
+import "net/http"
+
+type Router struct {
+	Root  http.Handler `get:"/"`
+	Users Users        `get:"/users?page{default: 1}" post:"/users"`
+	User  Users        `get:"/users/:id([0-9]+){1-8}" func:"GetUser"`
+	Named Users        `get:"/users/:name" func:"GetNamed"`
+	Login Users        `get:"/users/:login" func:"GetLogin"`
+	Again http.Handler `get:"/"`
+	Files Files        `get:"/files/:path*[3]"`
+	Image Files        `get:"/img/{name}.{ext: ext, regex: 'png|jpg'}" func:"GetImage"`
+	Raw   http.Handler `path:"/img/{file}.{kind: kind, regex: 'png|jpg'}"`
+}
+
+type Users struct {
+	ID    int
+	Name  string
+	Login string
+	Page  int
+}
+
+func (h *Users) Get(w http.ResponseWriter, r *http.Request)      {}
+func (h *Users) Post(w http.ResponseWriter, r *http.Request)     {}
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request)  {}
+func (h *Users) GetNamed(w http.ResponseWriter, r *http.Request) {}
+func (h *Users) GetLogin(w http.ResponseWriter, r *http.Request) {}
+
+type Files struct {
+	Path string
+	Name string
+	Ext  string
+}
+
+func (h *Files) Get(w http.ResponseWriter, r *http.Request)      {}
+func (h *Files) GetImage(w http.ResponseWriter, r *http.Request) {}
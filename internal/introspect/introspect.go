@@ -0,0 +1,241 @@
+// Package introspect generates a route table for a router struct, so the routes
+// of a generated router may be listed at runtime on debugging and admin pages.
+//
+// The generated file declares the RouteInfo and ParamInfo types prefixed by the
+// name of the router struct, such as RouterRouteInfo, a Routes method returning
+// a RouteInfo for each route in declaration order and a RoutesHandler method
+// returning a http.Handler which renders the table as HTML or JSON. Since each
+// file declares its own types, any number of router structs in a package may be
+// introspected.
+package introspect
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/parser"
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+// Route describes a single route of a router struct.
+type Route struct {
+	Method  string // upper case http method, empty for any method
+	Pattern string // route pattern as written within the route tag
+	Params  []Param
+	Handler string         // such as GetOrg, Users.Get or the field name
+	Pos     token.Position // position of the pattern within the route tag
+}
+
+// Param describes a single path or query param of a route.
+type Param struct {
+	Name  string
+	Type  string // Go type of the bound struct field, string when unbound
+	Query bool
+}
+
+// Load parses the non-test Go files within dir and returns the Go source of
+// the route table for the named router struct.
+func Load(dir, router string) ([]byte, error) {
+	pkg, routes, err := ParseDir(dir, router)
+	if err != nil {
+		return nil, err
+	}
+	return Source(pkg, router, routes)
+}
+
+// ParseDir parses the non-test Go files within dir and returns the name of the
+// package declaring the named router struct along with its routes.
+func ParseDir(dir, router string) (string, []*Route, error) {
+	fset := token.NewFileSet()
+	name, files, err := source.ParseDir(fset, dir, router)
+	if err != nil {
+		return ``, nil, err
+	}
+	routes, err := Routes(fset, files, router)
+	return name, routes, err
+}
+
+// Routes returns each route of the named router struct declared within the
+// given files of a single package, with a route for each http method resolved
+// by source.Package.Routes.
+func Routes(fset *token.FileSet, files []*ast.File, router string) ([]*Route, error) {
+	pkg := source.New(files)
+	st := pkg.Structs[router]
+	if st == nil {
+		return nil, fmt.Errorf(`router struct %v not found`, router)
+	}
+
+	var out []*Route
+	for _, fd := range st.Fields.List {
+		if fd.Tag == nil {
+			continue
+		}
+		pos := fset.Position(fd.Tag.Pos())
+		str, err := tag.Unquote(fd.Tag.Value)
+		if err != nil {
+			return nil, fmt.Errorf(`%v: %v`, pos, err)
+		}
+		ps, err := tag.Parse(str)
+		if err != nil {
+			continue // tags which are not for routes are not our concern
+		}
+
+		for _, p := range ps.Routes() {
+			r, err := parser.Parse(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf(`%v: invalid %v pattern: %v`, pos, p.Key, err)
+			}
+			params := paramsOf(pkg, fd, r)
+
+			at := pos
+			if off := tag.ValueOffset(fd.Tag.Value, p); off >= 0 {
+				at = fset.Position(fd.Tag.Pos() + token.Pos(off))
+			}
+			rts, err := pkg.Routes(fd, ps, p)
+			if err != nil {
+				return nil, fmt.Errorf(`%v: %v`, pos, err)
+			}
+			for _, rt := range rts {
+				out = append(out, &Route{
+					Method:  strings.ToUpper(rt.Method),
+					Pattern: p.Value,
+					Params:  params,
+					Handler: rt.Handler.Name,
+					Pos:     at,
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+func paramsOf(pkg *source.Package, fd *ast.Field, r *parser.Route) (out []Param) {
+	typ := pkg.Struct(fd.Type)
+	for _, prm := range r.Params() {
+		p := Param{Name: prm.Name, Type: `string`, Query: prm.Query}
+		if f, ok := pkg.Field(typ, prm.Name); ok {
+			p.Type = types.ExprString(f.Field.Type)
+		}
+		out = append(out, p)
+	}
+	return
+}
+
+// Source returns the Go source of the route table for the named router struct.
+func Source(pkg, router string, routes []*Route) ([]byte, error) {
+	v := strings.ToLower(router[:1]) + router[1:] + `Routes`
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, header, router, pkg)
+	fmt.Fprintf(&buf, "var %v = []%vRouteInfo{\n", v, router)
+	for _, r := range routes {
+		fmt.Fprintf(&buf, "{Method: %q, Pattern: %v, ", r.Method, strconv.Quote(r.Pattern))
+		if len(r.Params) > 0 {
+			fmt.Fprintf(&buf, "Params: []%vParamInfo{", router)
+			for i, p := range r.Params {
+				if i > 0 {
+					buf.WriteString(`, `)
+				}
+				fmt.Fprintf(&buf, "{Name: %q, Type: %q", p.Name, p.Type)
+				if p.Query {
+					buf.WriteString(`, Query: true`)
+				}
+				buf.WriteString(`}`)
+			}
+			buf.WriteString("}, ")
+		}
+		pos := fmt.Sprintf(`%v:%d:%d`, filepath.Base(r.Pos.Filename), r.Pos.Line, r.Pos.Column)
+		fmt.Fprintf(&buf, "Handler: %q, Pos: %q},\n", r.Handler, pos)
+	}
+	buf.WriteString("}\n")
+	fmt.Fprintf(&buf, footer, router, v)
+	return gofmt.Source(buf.Bytes())
+}
+
+const header = `// Code generated by routepiler from %[1]v. DO NOT EDIT.
+
+package %[2]v
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// %[1]vRouteInfo describes a single route of %[1]v.
+type %[1]vRouteInfo struct {
+	Method  string      ` + "`json:\"method\"`" + ` // upper case http method, empty for any method
+	Pattern string      ` + "`json:\"pattern\"`" + ` // route pattern as written within the route tag
+	Params  []%[1]vParamInfo ` + "`json:\"params,omitempty\"`" + `
+	Handler string      ` + "`json:\"handler\"`" + ` // such as GetOrg, Users.Get or the field name
+	Pos     string      ` + "`json:\"pos\"`" + ` // file:line:col of the pattern
+}
+
+// %[1]vParamInfo describes a single path or query param of a route.
+type %[1]vParamInfo struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Type  string ` + "`json:\"type\"`" + ` // Go type of the bound struct field
+	Query bool   ` + "`json:\"query,omitempty\"`" + `
+}
+
+`
+
+const footer = `
+// Routes returns each route of %[1]v in order of declaration.
+func (r *%[1]v) Routes() []%[1]vRouteInfo {
+	out := make([]%[1]vRouteInfo, len(%[2]v))
+	copy(out, %[2]v)
+	return out
+}
+
+// RoutesHandler returns a http.Handler which serves the routes of %[1]v as JSON
+// when the request accepts application/json or has the query format=json,
+// otherwise as an HTML table. The table is rendered in full before it is
+// written, so a failure to render it is served as an internal server error.
+func (r *%[1]v) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var (
+			buf bytes.Buffer
+			err error
+			typ = "text/html; charset=utf-8"
+		)
+		if req.URL.Query().Get("format") == "json" ||
+			strings.Contains(req.Header.Get("Accept"), "application/json") {
+			typ, err = "application/json", json.NewEncoder(&buf).Encode(r.Routes())
+		} else {
+			err = %[2]vTemplate.Execute(&buf, r.Routes())
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", typ)
+		w.Write(buf.Bytes())
+	})
+}
+
+var %[2]vTemplate = template.Must(template.New("routes").Parse(` + "`" + `<!DOCTYPE html>
+<html>
+<head><title>%[1]v routes</title></head>
+<body>
+<table>
+<tr><th>Method</th><th>Pattern</th><th>Params</th><th>Handler</th><th>Source</th></tr>
+{{range .}}<tr><td>{{or .Method "ANY"}}</td><td><code>{{.Pattern}}</code></td>` +
+	`<td>{{range $i, $p := .Params}}{{if $i}}, {{end}}{{if .Query}}?{{end}}{{.Name}} {{.Type}}{{end}}</td>` +
+	`<td>{{.Handler}}</td><td>{{.Pos}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+` + "`" + `))
+`
@@ -0,0 +1,126 @@
+package introspect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDir holds the router shared with the other generators, the golden files
+// generated from it are checked in under testdata.
+const testDir = `../testdata/router`
+
+func TestLoad(t *testing.T) {
+	src, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	exp, err := ioutil.ReadFile(filepath.Join(`testdata`, `routes.golden`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if string(exp) != string(src) {
+		t.Fatalf("exp source:\n%s\ngot:\n%s", exp, src)
+	}
+	if _, err := Load(testDir, `Bogus`); err == nil {
+		t.Fatal(`exp non-nil err`)
+	}
+
+	// the generated file must type check alongside the router
+	fset := token.NewFileSet()
+	router, err := parser.ParseFile(fset, filepath.Join(testDir, `router.go`), nil, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	routes, err := parser.ParseFile(fset, `routes.go`, src, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, `source`, nil)}
+	if _, err := conf.Check(`main`, fset, []*ast.File{router, routes}, nil); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+}
+
+const testSrc = `package main
+
+import "net/http"
+
+type Router struct {
+	Users Users ` + "`%v`" + `
+	Other http.Handler
+}
+
+type Users struct {
+	User string
+	Age  uint8
+}
+
+func (h *Users) Get(w http.ResponseWriter, r *http.Request)       {}
+func (h *Users) Delete(w http.ResponseWriter, r *http.Request)    {}
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request)   {}
+func (h *Users) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+`
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{`get:"/users"`, `GET /users Users.Get 6:20`},
+		{`path:"/users"`,
+			"GET /users Users.Get 6:21\nDELETE /users Users.Delete 6:21\n /users Users.ServeHTTP 6:21"},
+		{`path:"POST /users" func:"GetUser"`, `POST POST /users GetUser 6:21`},
+		{`path:"/users" method:"put"`, `PUT /users Users.ServeHTTP 6:21`},
+		{`connect:"/users"`, `CONNECT /users Users.ServeHTTP 6:24`},
+		{`get:"/users/:user/:age?q" json:"users"`,
+			`GET /users/:user/:age?q Users.Get 6:20 user string, age uint8, ?q string`},
+
+		// errors
+		{`get:"/:a:bb"`, `router.go:6:14: invalid get pattern: adjacent COLON at byte 3, ` +
+			`params within a path segment must be separated by a literal`},
+		{`get:"/users" func:"Bogus"`,
+			`router.go:6:14: func tag names Bogus which is not a method of Users`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %v exp %v`, idx, test.tag, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testSrc, test.tag), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		var lines []string
+		routes, err := Routes(fset, []*ast.File{f}, `Router`)
+		for _, r := range routes {
+			line := fmt.Sprintf(`%v %v %v %d:%d`,
+				r.Method, r.Pattern, r.Handler, r.Pos.Line, r.Pos.Column)
+			var ps []string
+			for _, p := range r.Params {
+				q := ``
+				if p.Query {
+					q = `?`
+				}
+				ps = append(ps, q+p.Name+` `+p.Type)
+			}
+			if len(ps) > 0 {
+				line += ` ` + strings.Join(ps, `, `)
+			}
+			lines = append(lines, line)
+		}
+		got := strings.Join(lines, "\n")
+		if err != nil {
+			got = err.Error()
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf("exp:\n%v\ngot:\n%v", exp, got)
+		}
+	}
+}
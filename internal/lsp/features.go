@@ -0,0 +1,214 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cstockton/routepiler/internal/scanner"
+	"github.com/cstockton/routepiler/internal/vet"
+)
+
+// view returns the view for an open document or nil if it is not open.
+func (s *Server) view(uri string) *view {
+	doc := s.docs[uri]
+	if doc == nil {
+		return nil
+	}
+	return newView(doc, s.docs)
+}
+
+// hover returns the token stream and handler of the route or func tag at the
+// given position.
+func (s *Server) hover(p TextDocumentPositionParams) *Hover {
+	v := s.view(p.TextDocument.URI)
+	if v == nil {
+		return nil
+	}
+	doc := v.docs[0]
+	rt, ok := v.tagAt(doc.offset(p.Position))
+	if !ok || !rt.pair.Route() && rt.pair.Key != `func` {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if rt.pair.Route() {
+		fmt.Fprintf(&buf, "%v `%v`\n\n```text\n", rt.pair.Key, rt.pair.Value)
+		toks, err := scanner.Scan(rt.pair.Value)
+		if err != nil {
+			fmt.Fprintf(&buf, "%v\n", err)
+		}
+		for _, tok := range toks {
+			fmt.Fprintf(&buf, "%3d %-10v %q\n", tok.Beg.Offset(), tok.Lex, tok.Lit)
+		}
+		buf.WriteString("```\n\n")
+	}
+	if h, err := v.handler(rt); err != nil {
+		fmt.Fprintf(&buf, "handler: %v", err)
+	} else {
+		fmt.Fprintf(&buf, "handler: `%v`", h)
+	}
+
+	rng := doc.span(rt.beg, len(rt.pair.Value))
+	return &Hover{
+		Contents: MarkupContent{Kind: `markdown`, Value: buf.String()},
+		Range:    &rng,
+	}
+}
+
+// definition returns the location of the handler for the route or func tag at
+// the given position.
+func (s *Server) definition(p TextDocumentPositionParams) *Location {
+	v := s.view(p.TextDocument.URI)
+	if v == nil {
+		return nil
+	}
+	rt, ok := v.tagAt(v.docs[0].offset(p.Position))
+	if !ok || !rt.pair.Route() && rt.pair.Key != `func` {
+		return nil
+	}
+	h, err := v.handler(rt)
+	if err != nil {
+		return nil
+	}
+	return v.location(h.Ident)
+}
+
+// completion returns the names of the struct fields which may be bound to the
+// param being declared at the given position of a route tag.
+func (s *Server) completion(p TextDocumentPositionParams) *CompletionList {
+	list := &CompletionList{Items: []CompletionItem{}}
+	v := s.view(p.TextDocument.URI)
+	if v == nil {
+		return list
+	}
+	doc := v.docs[0]
+	off := doc.offset(p.Position)
+	rt, ok := v.tagAt(off)
+	if !ok || !rt.pair.Route() {
+		return list
+	}
+	typ := v.pkg.Struct(rt.field.Type)
+	if typ == `` {
+		return list
+	}
+
+	text := rt.pair.Value[:off-rt.beg]
+	beg := paramStart(text)
+	if beg < 0 {
+		return list
+	}
+
+	prefix := strings.ToLower(text[beg:])
+	for _, f := range v.pkg.Fields(typ) {
+		label := paramName(f.Name)
+		if !strings.HasPrefix(strings.ToLower(label), prefix) {
+			continue
+		}
+		list.Items = append(list.Items, CompletionItem{
+			Label:  label,
+			Kind:   fieldCompletion,
+			Detail: typ + `.` + f.Name + ` ` + types.ExprString(f.Field.Type),
+			TextEdit: &TextEdit{
+				Range:   doc.span(rt.beg+beg, len(prefix)),
+				NewText: label,
+			},
+		})
+	}
+	return list
+}
+
+// paramStart returns the offset of the partial param name which ends text, or
+// -1 when text does not end within the name of a param.
+func paramStart(text string) int {
+	i := len(text)
+	for i > 0 && isIdent(text[i-1]) {
+		i--
+	}
+	if i == 0 {
+		return -1
+	}
+
+	prev, lead := text[i-1], text[:i-1]
+	if strings.Count(lead, `{`) != strings.Count(lead, `}`) {
+		return -1 // within a template
+	}
+	switch {
+	case prev == '?', prev == '&':
+	case prev == ':' && !named(lead):
+	case prev == '{' && !named(lead) && (lead == `` ||
+		!strings.ContainsRune(`)]*?}`, rune(lead[len(lead)-1]))):
+	default:
+		return -1
+	}
+	return i
+}
+
+// named returns true if text ends with the name of a param, rather than a
+// literal such as the "v" of `v:version`.
+func named(text string) bool {
+	j := len(text)
+	for j > 0 && isIdent(text[j-1]) {
+		j--
+	}
+	return j > 0 && j < len(text) && strings.ContainsRune(`:?&`, rune(text[j-1]))
+}
+
+func isIdent(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' ||
+		b >= '0' && b <= '9'
+}
+
+// diagnose publishes the diagnostics for each document within the package of
+// the given document.
+func (s *Server) diagnose(uri string) error {
+	v := s.view(uri)
+	if v == nil {
+		return nil
+	}
+
+	var files []*ast.File
+	for _, f := range v.files {
+		if f != nil {
+			files = append(files, f)
+		}
+	}
+	diags, err := vet.Check(v.fset, files)
+	if err != nil {
+		return err
+	}
+
+	out := make(map[string][]Diagnostic)
+	for _, doc := range v.docs {
+		out[doc.uri] = []Diagnostic{}
+	}
+	for _, d := range diags {
+		doc, off := v.document(d.Pos)
+		if doc == nil {
+			continue
+		}
+		// empty ranges are widened to the rune at Pos to remain visible
+		n := v.fset.Position(d.End).Offset - off
+		if d.End <= d.Pos {
+			_, n = utf8.DecodeRuneInString(doc.text[off:])
+		}
+		out[doc.uri] = append(out[doc.uri], Diagnostic{
+			Range:    doc.span(off, n),
+			Severity: severityError,
+			Source:   `routepiler`,
+			Message:  d.Message,
+		})
+	}
+
+	for _, doc := range v.docs {
+		err := s.notify(`textDocument/publishDiagnostics`,
+			PublishDiagnosticsParams{URI: doc.uri, Diagnostics: out[doc.uri]})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package lsp implements a language server for the route struct tags of Go
+// source files, speaking the language server protocol over a single stream such
+// as stdio:
+//
+//	lsp.NewServer().Serve(os.Stdin, os.Stdout)
+//
+// It provides hover with the token stream and resolved handler of a route tag,
+// go-to-definition from a tag to its handler, completion of param names from
+// struct fields and diagnostics from the vet package. Open documents within the
+// same directory and package are analyzed together, the server never reads from
+// the file system or network.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Server is a language server for route struct tags.
+type Server struct {
+	docs map[string]*document
+	w    io.Writer
+}
+
+// NewServer returns a new Server with no open documents.
+func NewServer() *Server {
+	return &Server{docs: make(map[string]*document)}
+}
+
+// Serve reads messages from r and writes messages to w until an exit
+// notification is received or r returns io.EOF.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	s.w = w
+	for {
+		body, err := read(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		msg := new(message)
+		if err = json.Unmarshal(body, msg); err != nil {
+			if err = s.reply(nil, nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == `exit` {
+			return nil
+		}
+		if err = s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+var capabilities = map[string]interface{}{
+	`capabilities`: map[string]interface{}{
+		`textDocumentSync`:   1, // full
+		`hoverProvider`:      true,
+		`definitionProvider`: true,
+		`completionProvider`: map[string]interface{}{
+			`triggerCharacters`: []string{`:`, `{`, `?`, `&`},
+		},
+	},
+	`serverInfo`: map[string]string{`name`: `routepiler`},
+}
+
+func (s *Server) handle(msg *message) error {
+	if msg.Method == `` {
+		return nil // responses to requests we never send
+	}
+
+	var (
+		res interface{}
+		err error
+	)
+	switch msg.Method {
+	case `initialize`:
+		res = capabilities
+	case `shutdown`:
+	case `textDocument/didOpen`:
+		var p DidOpenTextDocumentParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			s.docs[p.TextDocument.URI] = &document{
+				uri: p.TextDocument.URI, text: p.TextDocument.Text}
+			return s.diagnose(p.TextDocument.URI)
+		}
+	case `textDocument/didChange`:
+		var p DidChangeTextDocumentParams
+		err = json.Unmarshal(msg.Params, &p)
+		if doc := s.docs[p.TextDocument.URI]; err == nil && doc != nil {
+			if n := len(p.ContentChanges); n > 0 {
+				doc.text = p.ContentChanges[n-1].Text
+			}
+			return s.diagnose(doc.uri)
+		}
+	case `textDocument/didClose`:
+		var p DidCloseTextDocumentParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			delete(s.docs, p.TextDocument.URI)
+			return s.notify(`textDocument/publishDiagnostics`,
+				PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		}
+	case `textDocument/hover`:
+		var p TextDocumentPositionParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			res = s.hover(p)
+		}
+	case `textDocument/definition`:
+		var p TextDocumentPositionParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			res = s.definition(p)
+		}
+	case `textDocument/completion`:
+		var p TextDocumentPositionParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			res = s.completion(p)
+		}
+	default:
+		if msg.ID == nil {
+			return nil // notifications may be ignored
+		}
+		return s.reply(msg.ID, nil, codeMethodNotFound,
+			`method not found: `+msg.Method)
+	}
+
+	switch {
+	case msg.ID == nil:
+		return nil
+	case err != nil:
+		return s.reply(msg.ID, nil, codeInvalidParams, err.Error())
+	default:
+		return s.reply(msg.ID, res, 0, ``)
+	}
+}
+
+// reply writes the response to a request, an error response is written when
+// code is non-zero.
+func (s *Server) reply(id *json.RawMessage, res interface{}, code int, errMsg string) error {
+	msg := &message{ID: id}
+	if id == nil {
+		null := json.RawMessage(`null`)
+		msg.ID = &null
+	}
+	if code != 0 {
+		msg.Error = &responseError{Code: code, Message: errMsg}
+		return s.write(msg)
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	msg.Result = b
+	return s.write(msg)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&message{Method: method, Params: b})
+}
+
+func (s *Server) write(msg *message) error {
+	msg.JSONRPC = `2.0`
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+// read returns the body of the next message, or io.EOF if r ends before the
+// headers of a message begin.
+func read(r *bufio.Reader) ([]byte, error) {
+	n := -1
+	for first := true; ; first = false {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && first && line == `` {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == `` {
+			break
+		}
+		if v := strings.TrimPrefix(line, `Content-Length:`); v != line {
+			if n, err = strconv.Atoi(strings.TrimSpace(v)); err != nil || n < 0 {
+				return nil, fmt.Errorf(`invalid Content-Length header %q`, line)
+			}
+		}
+	}
+	if n < 0 {
+		return nil, errors.New(`message is missing the Content-Length header`)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return body, nil
+}
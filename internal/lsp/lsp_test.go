@@ -0,0 +1,358 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const routerURI = `file:///src/app/router.go`
+
+const routerSrc = `package main
+
+import "net/http"
+
+type Router struct {
+	Root  http.Handler ` + "`get:\"/\"`" + `
+	Time  http.Handler ` + "`path:\"/time\" func:\"handleTime\"`" + `
+	Org   Orgs         ` + "`get:\"/orgs/:org\" func:\"GetOrg\"`" + `
+	Users Users        ` + "`path:\"/orgs/:org/users\"`" + `
+	User  Users        ` + "`get:\"/orgs/:org/users/:\" func:\"GetUser\"`" + `
+	Bad   Orgs         ` + "`get:\"/orgs/:a:bb\"`" + `
+	Team  Orgs         ` + "`get:\"/orgs/:org/teams/:team\"`" + `
+}
+
+var handleTime = http.NotFoundHandler()
+
+type Orgs struct {
+	Org string
+}
+
+func (h *Orgs) GetOrg(w http.ResponseWriter, r *http.Request) {}
+`
+
+const usersURI = `file:///src/app/users.go`
+
+const usersSrc = `package main
+
+import "net/http"
+
+type Users struct {
+	*Orgs
+	User  string
+	Since string
+	db    bool
+}
+
+func (h *Users) Get(w http.ResponseWriter, r *http.Request) {}
+
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+`
+
+// at returns the position of the first occurrence of marker within src plus
+// the given number of bytes.
+func at(src, marker string, delta int) Position {
+	off := strings.Index(src, marker)
+	if off < 0 {
+		panic(`marker not found: ` + marker)
+	}
+	return (&document{text: src}).position(off + delta)
+}
+
+func params(uri string, pos Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri}, Position: pos}
+}
+
+func testServer() *Server {
+	s := NewServer()
+	s.docs[routerURI] = &document{uri: routerURI, text: routerSrc}
+	s.docs[usersURI] = &document{uri: usersURI, text: usersSrc}
+	return s
+}
+
+func TestPosition(t *testing.T) {
+	doc := &document{text: "ab\ncé\U0001F600d\n"}
+	tests := []struct {
+		off int
+		pos Position
+	}{
+		{0, Position{0, 0}},
+		{2, Position{0, 2}},
+		{3, Position{1, 0}},
+		{4, Position{1, 1}},
+		{6, Position{1, 2}},
+		{10, Position{1, 4}},
+		{12, Position{2, 0}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp offset %v at %v`, idx, test.off, test.pos)
+		if exp, got := test.pos, doc.position(test.off); exp != got {
+			t.Fatalf(`exp position %v; got %v`, exp, got)
+		}
+		if exp, got := test.off, doc.offset(test.pos); exp != got {
+			t.Fatalf(`exp offset %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	tests := []struct {
+		pos Position
+		exp []string
+	}{
+		{at(routerSrc, `/orgs/:org"`, 3),
+			[]string{"get `/orgs/:org`", `6 COLON      ":"`, `7 IDENT      "org"`,
+				"handler: `func (*Orgs) GetOrg(w http.ResponseWriter, r *http.Request)`"}},
+		{at(routerSrc, `GetOrg"`, 0),
+			[]string{"handler: `func (*Orgs) GetOrg"}},
+		{at(routerSrc, `/orgs/:org/users"`, 0),
+			[]string{"handler: `func (*Users) Get(w"}},
+		{at(routerSrc, `/time"`, 0),
+			[]string{"handler: `var handleTime`"}},
+		{at(routerSrc, `"/"`, 1),
+			[]string{"handler: `field Root http.Handler`"}},
+		{at(routerSrc, `/orgs/:a:bb`, 0),
+			[]string{`adjacent COLON at byte 8`}},
+		{at(routerSrc, `type Router`, 0), nil},
+	}
+	s := testServer()
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp hover at %v to contain %q`, idx, test.pos, test.exp)
+		h := s.hover(params(routerURI, test.pos))
+		if test.exp == nil {
+			if h != nil {
+				t.Fatalf(`exp nil hover; got %v`, h.Contents.Value)
+			}
+			continue
+		}
+		if h == nil {
+			t.Fatal(`exp non-nil hover`)
+		}
+		for _, exp := range test.exp {
+			if got := h.Contents.Value; !strings.Contains(got, exp) {
+				t.Fatalf("exp hover to contain %q; got:\n%v", exp, got)
+			}
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	tests := []struct {
+		pos Position
+		exp *Location
+	}{
+		{at(routerSrc, `GetOrg"`, 2), &Location{URI: routerURI, Range: Range{
+			at(routerSrc, `GetOrg(`, 0), at(routerSrc, `GetOrg(`, 6)}}},
+		{at(routerSrc, `/orgs/:org/users/:`, 0), &Location{URI: usersURI, Range: Range{
+			at(usersSrc, `GetUser(`, 0), at(usersSrc, `GetUser(`, 7)}}},
+		{at(routerSrc, `/time`, 0), &Location{URI: routerURI, Range: Range{
+			at(routerSrc, `handleTime =`, 0), at(routerSrc, `handleTime =`, 10)}}},
+		{at(routerSrc, `Root`, 0), nil},
+	}
+	s := testServer()
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp definition at %v to be %v`, idx, test.pos, test.exp)
+		if exp, got := test.exp, s.definition(params(routerURI, test.pos)); !reflect.DeepEqual(exp, got) {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	tests := []struct {
+		pos Position
+		exp []string
+	}{
+		{at(routerSrc, `/users/:"`, 8), []string{`user`, `since`, `db`, `org`}},
+		{at(routerSrc, `/users"`, 1), nil},
+		{at(routerSrc, `:org/users"`, 1), []string{`user`, `since`, `db`, `org`}},
+		{at(routerSrc, `:org/users"`, 3), []string{`org`}},
+		{at(routerSrc, `:org"`, 1), []string{`org`}},
+		{at(routerSrc, `:bb"`, 1), nil},
+		{at(routerSrc, `"/"`, 1), nil},
+	}
+	s := testServer()
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp completion at %v to be %v`, idx, test.pos, test.exp)
+		var got []string
+		for _, item := range s.completion(params(routerURI, test.pos)).Items {
+			got = append(got, item.Label)
+		}
+		if exp := test.exp; !reflect.DeepEqual(exp, got) {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestParamStart(t *testing.T) {
+	tests := []struct {
+		text string
+		exp  int
+	}{
+		{`/:`, 2},
+		{`/:us`, 2},
+		{`/{us`, 2},
+		{`/{from}-{`, 9},
+		{`/files/:name.:e`, 14},
+		{`/users?since&a`, 13},
+		{`/users?s`, 7},
+		{``, -1},
+		{`/users`, -1},
+		{`/:a{mi`, -1},
+		{`/:a{min: 3, ma`, -1},
+		{`/:a([a-z]){`, -1},
+		{`/pre:a`, 5},
+		{`/v{ver`, 3},
+		{`/:a:b`, -1},
+		{`/:a{b`, -1},
+		{`/?a{b`, -1},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp paramStart(%q) to return %v`, idx, test.text, test.exp)
+		if exp, got := test.exp, paramStart(test.text); exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+// frame returns the given messages framed for Serve.
+func frame(msgs ...string) string {
+	var buf bytes.Buffer
+	for _, msg := range msgs {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	return buf.String()
+}
+
+func TestServe(t *testing.T) {
+	open, err := json.Marshal(DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: routerURI, Version: 1, Text: routerSrc}})
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	hover, err := json.Marshal(params(routerURI, at(routerSrc, `GetOrg"`, 0)))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	in := frame(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":`+string(open)+`}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":`+string(hover)+`}`,
+		`{"jsonrpc":"2.0","id":3,"method":"workspace/symbol","params":{}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/hover","params":[]}`,
+		`{"jsonrpc":"2.0","id":5,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+	)
+	var out bytes.Buffer
+	if err := NewServer().Serve(strings.NewReader(in), &out); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	var msgs []*message
+	for r := bufio.NewReader(&out); ; {
+		body, err := read(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		msg := new(message)
+		if err := json.Unmarshal(body, msg); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	if exp, got := 6, len(msgs); exp != got {
+		t.Fatalf(`exp %v messages; got %v`, exp, got)
+	}
+
+	tests := []struct {
+		id     string
+		method string
+		code   int
+		exp    string
+	}{
+		{`1`, ``, 0, `"hoverProvider":true`},
+		{``, `textDocument/publishDiagnostics`, 0,
+			`"range":{"start":{"line":10,"character":34},"end":{"line":10,"character":35}},` +
+				`"severity":1,"source":"routepiler","message":"invalid get pattern: ` +
+				`adjacent COLON at byte 8, params within a path segment must be separated ` +
+				`by a literal"},{"range":{"start":{"line":11,"character":43},` +
+				`"end":{"line":11,"character":48}},"severity":1,"source":"routepiler",` +
+				`"message":"param \"team\" of get pattern has no matching field in Orgs"}]`},
+		{`2`, ``, 0, `handler: ` + "`" + `func (*Orgs) GetOrg`},
+		{`3`, ``, codeMethodNotFound, `method not found: workspace/symbol`},
+		{`4`, ``, codeInvalidParams, `cannot unmarshal array`},
+		{`5`, ``, 0, `null`},
+	}
+	for idx, test := range tests {
+		msg := msgs[idx]
+		t.Logf(`test #%.2d - exp message %v %v to contain %v`,
+			idx, test.id, test.method, test.exp)
+
+		var id string
+		if msg.ID != nil {
+			id = string(*msg.ID)
+		}
+		if exp, got := test.id, id; exp != got {
+			t.Fatalf(`exp id %q; got %q`, exp, got)
+		}
+		if exp, got := test.method, msg.Method; exp != got {
+			t.Fatalf(`exp method %q; got %q`, exp, got)
+		}
+
+		got := string(msg.Result) + string(msg.Params)
+		if msg.Error != nil {
+			if exp, got := test.code, msg.Error.Code; exp != got {
+				t.Fatalf(`exp error code %v; got %v`, exp, got)
+			}
+			got = msg.Error.Message
+		}
+		if !strings.Contains(got, test.exp) {
+			t.Fatalf("exp message to contain %v; got:\n%v", test.exp, got)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp string
+	}{
+		{"Content-Length: 2\r\n\r\n{}", ``},
+		{"Content-Type: x\r\nContent-Length: 2\r\n\r\n{}", ``},
+		{"", `EOF`},
+		{"Content-Length: 2\r\n", `unexpected EOF`},
+		{"Content-Length: 3\r\n\r\n{}", `unexpected EOF`},
+		{"Content-Length: x\r\n\r\n{}", `invalid Content-Length header`},
+		{"\r\n{}", `missing the Content-Length header`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from %q exp err %q`, idx, test.in, test.exp)
+		body, err := read(bufio.NewReader(strings.NewReader(test.in)))
+		if test.exp == `` {
+			if err != nil {
+				t.Fatalf(`exp nil err; got %v`, err)
+			}
+			if exp, got := `{}`, string(body); exp != got {
+				t.Fatalf(`exp body %v; got %v`, exp, got)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.exp) {
+			t.Fatalf(`exp err %v; got %v`, test.exp, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package lsp
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+// document is the current text of a single open Go source file.
+type document struct {
+	uri  string
+	text string
+}
+
+// offset returns the byte offset of the given position, clamped to the line.
+func (d *document) offset(p Position) int {
+	off := 0
+	for line := 0; line < p.Line; line++ {
+		i := strings.IndexByte(d.text[off:], '\n')
+		if i < 0 {
+			return len(d.text)
+		}
+		off += i + 1
+	}
+	for n := p.Character; n > 0 && off < len(d.text) && d.text[off] != '\n'; {
+		r, w := utf8.DecodeRuneInString(d.text[off:])
+		n -= utf16Len(r)
+		off += w
+	}
+	return off
+}
+
+// position returns the position of the given byte offset.
+func (d *document) position(off int) Position {
+	if off > len(d.text) {
+		off = len(d.text)
+	}
+	beg := strings.LastIndexByte(d.text[:off], '\n') + 1
+	p := Position{Line: strings.Count(d.text[:beg], "\n")}
+	for _, r := range d.text[beg:off] {
+		p.Character += utf16Len(r)
+	}
+	return p
+}
+
+// span returns the range from off spanning n bytes.
+func (d *document) span(off, n int) Range {
+	return Range{Start: d.position(off), End: d.position(off + n)}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// view is every open document within the same package as a given document,
+// parsed into a single file set.
+type view struct {
+	fset  *token.FileSet
+	docs  []*document // docs[0] is the document the view was created for
+	files []*ast.File // parallel to docs, nil if the source has no package
+	pkg   *source.Package
+}
+
+func newView(doc *document, docs map[string]*document) *view {
+	v := &view{fset: token.NewFileSet()}
+	v.add(doc)
+	if v.files[0] != nil {
+		for _, other := range docs {
+			if other.uri == doc.uri || path.Dir(other.uri) != path.Dir(doc.uri) {
+				continue
+			}
+			if f := v.add(other); f == nil || f.Name.Name != v.files[0].Name.Name {
+				v.docs, v.files = v.docs[:len(v.docs)-1], v.files[:len(v.files)-1]
+			}
+		}
+	}
+	v.pkg = source.New(v.files)
+	return v
+}
+
+// add parses the given document, keeping the partial syntax tree of a source
+// with errors as the document is often in the middle of an edit.
+func (v *view) add(doc *document) *ast.File {
+	f, _ := parser.ParseFile(v.fset, doc.uri, doc.text, parser.AllErrors)
+	if f != nil && f.Name == nil {
+		f = nil
+	}
+	v.docs, v.files = append(v.docs, doc), append(v.files, f)
+	return f
+}
+
+// document returns the document and byte offset of a position in the view.
+func (v *view) document(pos token.Pos) (*document, int) {
+	p := v.fset.Position(pos)
+	for _, doc := range v.docs {
+		if doc.uri == p.Filename {
+			return doc, p.Offset
+		}
+	}
+	return nil, 0
+}
+
+// location returns the location of the given identifier.
+func (v *view) location(id *ast.Ident) *Location {
+	doc, off := v.document(id.Pos())
+	if doc == nil {
+		return nil
+	}
+	return &Location{URI: doc.uri, Range: doc.span(off, len(id.Name))}
+}
+
+// routeTag is a single pair within the struct tag of a route field.
+type routeTag struct {
+	field *ast.Field
+	pairs tag.Pairs
+	pair  tag.Pair
+	beg   int // byte offset of the pair value within the document
+}
+
+// tagAt returns the struct tag pair with a value containing the byte offset
+// within the first document of the view.
+func (v *view) tagAt(off int) (rt routeTag, ok bool) {
+	f := v.files[0]
+	if f == nil {
+		return rt, false
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		fd, isField := n.(*ast.Field)
+		if ok || !isField || fd.Tag == nil {
+			return !ok
+		}
+		_, lit := v.document(fd.Tag.Pos())
+		if off < lit || off > lit+len(fd.Tag.Value) {
+			return true
+		}
+
+		str, err := tag.Unquote(fd.Tag.Value)
+		if err != nil {
+			return false
+		}
+		ps, err := tag.Parse(str)
+		if err != nil {
+			return false
+		}
+		for _, p := range ps {
+			voff := tag.ValueOffset(fd.Tag.Value, p)
+			if voff < 0 {
+				continue
+			}
+			if beg := lit + voff; off >= beg && off <= beg+len(p.Value) {
+				rt, ok = routeTag{field: fd, pairs: ps, pair: p, beg: beg}, true
+			}
+		}
+		return false
+	})
+	return rt, ok
+}
+
+// handler returns the handler of the route field, resolving a route pair to
+// the handler of its http method.
+func (v *view) handler(rt routeTag) (source.Handler, error) {
+	var method string
+	if rt.pair.Route() {
+		method = source.Method(rt.pairs, rt.pair)
+	}
+	return v.pkg.Handler(rt.field, rt.pairs, method)
+}
+
+// paramName returns the conventional param name for a struct field.
+func paramName(field string) string {
+	r, w := utf8.DecodeRuneInString(field)
+	return string(unicode.ToLower(r)) + field[w:]
+}
@@ -0,0 +1,229 @@
+package match
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/backend"
+	"github.com/cstockton/routepiler/internal/backend/backendtest"
+	"github.com/cstockton/routepiler/internal/backend/gosrc"
+	"github.com/cstockton/routepiler/internal/parser"
+)
+
+// strategies are the routers generated for each pattern set, each a router
+// struct of the same name holding the same routes.
+var strategies = []struct {
+	name     string
+	strategy backend.Strategy
+}{
+	{`Linear`, backend.Linear},
+	{`Radix`, backend.Radix},
+}
+
+// routerSource returns the source of a main package declaring a router struct
+// for each strategy, with a route field for each pattern whose handler responds
+// with the index of the route field and the value of each of its params as
+// JSON. It returns false when a param may not be declared as a field.
+func routerSource(patterns []string, routes []*parser.Route) (string, bool) {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n\t\"encoding/json\"\n\t\"net/http\"\n)\n")
+	for _, s := range strategies {
+		fmt.Fprintf(&b, "\ntype %v struct {\n", s.name)
+		for i, pattern := range patterns {
+			fmt.Fprintf(&b, "\tR%d H%d %v\n", i, i, strconv.Quote(`path:`+strconv.Quote(pattern)))
+		}
+		b.WriteString("}\n")
+	}
+	b.WriteString(`
+func newHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Strategy") == "Radix" {
+			new(Radix).ServeHTTP(w, r)
+			return
+		}
+		new(Linear).ServeHTTP(w, r)
+	})
+}
+`)
+
+	for i, r := range routes {
+		var fields, values []string
+		for _, prm := range r.Params() {
+			if !token.IsIdentifier(prm.Name) || prm.Name == `_` || prm.Name == `ServeHTTP` {
+				return ``, false
+			}
+			fields = append(fields, fmt.Sprintf("\t%v string\n", prm.Name))
+			values = append(values, fmt.Sprintf(`%q: h.%v`, prm.Name, prm.Name))
+		}
+		fmt.Fprintf(&b, "\ntype H%d struct {\n%v}\n", i, strings.Join(fields, ``))
+		fmt.Fprintf(&b, "\nfunc (h *H%d) ServeHTTP(w http.ResponseWriter, r *http.Request) {\n", i)
+		fmt.Fprintf(&b, "\tjson.NewEncoder(w).Encode(map[string]interface{}{\"route\": %d, "+
+			"\"params\": map[string]string{%v}})\n}\n", i, strings.Join(values, `, `))
+	}
+	return b.String(), true
+}
+
+// FuzzMatch matches random pattern sets against random paths with the
+// reference matcher and with the router generated by each strategy of the
+// gosrc backend, which must serve the request with the winner, extracting the
+// same params.
+func FuzzMatch(f *testing.F) {
+	seeds := []struct {
+		patterns, path string
+	}{
+		{"/", `/`},
+		{"/a\n/:a", `/a`},
+		{"/:a\n/a", `/b`},
+		{"/users/:id\n/users/:id.json\n/users/me", `/users/a.b.json`},
+		{"/:a-:b\n/:a-:b-:c", `/x-y-z`},
+		{"/files/:path*\n/files/:path*/edit", `/files/a/b/edit`},
+		{"/files/:path*[2]\n/files/:path*", `/files/a/b/c`},
+		{"/:a*/:b*", `/a/b/c/d`},
+		{"/a/:b?\n/a/{b: b, optional: true, default: x}", `/a/`},
+		{"/a/:b?{default: x}", `/a`},
+		{"/a/:b?", `/a/`},
+		{"/:a([0-9]+){2-3}\n/:a{1-2}", `/123`},
+		{"/a?b{required: true}&c{default: 1}", `/a`},
+		{"/a?b{required: true}&c{default: 1}", `/a?b=x%20y`},
+		{"GET /a/:b\nPOST /a/:b", `/a/x`},
+		{"/{a}x{b}y", `/axxbyy`},
+		{"/dl/:id\n/dl/:id/:ext", `/dl/a%2Fb`},
+		{"/caf%C3%A9/:a\n/:b/:a", `/caf%c3%a9/%C3%A9t%C3%A9`},
+		{"/a%3Ab/:c", `/a:b/c%3Ad`},
+		{"/x/:a\n/x/:b{1-2}", `/x/y`},
+		{"/files/:path*\n/files/:path*/raw", `/files/a/raw`},
+	}
+	for _, seed := range seeds {
+		f.Add(seed.patterns, seed.path)
+	}
+
+	f.Fuzz(func(t *testing.T, patterns, path string) {
+		if testing.Short() {
+			t.Skip(`builds a program for each pattern set`)
+		}
+		if !strings.HasPrefix(path, `/`) || strings.HasPrefix(path, `//`) ||
+			strings.Contains(path, `#`) {
+			t.Skip(`path must be an absolute path`)
+		}
+		req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(
+			"GET " + path + " HTTP/1.0\r\n\r\n")))
+		if err != nil {
+			t.Skip(`path is not a valid request target`)
+		}
+
+		var valid []string
+		var routes []*parser.Route
+		for _, pattern := range strings.Split(patterns, "\n") {
+			if r, err := parser.Parse(pattern); err == nil {
+				valid, routes = append(valid, pattern), append(routes, r)
+			}
+		}
+		if len(routes) == 0 || len(routes) > 8 {
+			t.Skip(`exp between 1 and 8 valid patterns`)
+		}
+
+		src, ok := routerSource(valid, routes)
+		if !ok {
+			t.Skip(`param names may not be declared as fields`)
+		}
+		fset := token.NewFileSet()
+		file, err := goparser.ParseFile(fset, `router.go`, src, 0)
+		if err != nil {
+			t.Fatalf("exp nil err; got %v from:\n%s", err, src)
+		}
+		files := map[string][]byte{`router.go`: []byte(src)}
+		var rt *Router
+		for _, s := range strategies {
+			r, err := analyze.Analyze(fset, []*ast.File{file}, `main`, s.name)
+			if err != nil {
+				t.Skip(`routes are rejected by analysis`)
+			}
+			r.Strategy = s.strategy
+			if files[strings.ToLower(s.name)+`.go`], err = gosrc.Source(r); err != nil {
+				t.Fatalf(`exp nil err; got %v`, err)
+			}
+			rt = New(r) // each strategy holds the same routes
+		}
+
+		res, err := Match(rt, req)
+		if err != nil {
+			t.Skip(`invalid path`)
+		}
+
+		var matched bool
+		for _, c := range res.Candidates {
+			matched = matched || c.Matched()
+		}
+		if matched != (res.Winner != nil) {
+			t.Fatalf(`exp winner when any candidate matched; got %v`, res.Winner)
+		}
+		if w := res.Winner; w != nil && !w.Matched() {
+			t.Fatalf(`exp winner %v to have matched; got reason %q`, w.Route, w.Reason)
+		}
+
+		var reqs []backendtest.Request
+		for _, s := range strategies {
+			reqs = append(reqs, backendtest.Request{Method: `GET`, Target: path,
+				Header: map[string]string{`Strategy`: s.name}})
+		}
+		for i, resp := range backendtest.Build(t, files).Serve(t, reqs) {
+			served(t, strategies[i].name, res, resp)
+		}
+	})
+}
+
+// served fails the test when the response of a generated router differs from
+// the result of the reference matcher.
+func served(t *testing.T, strategy string, res *Result, resp backendtest.Response) {
+	t.Helper()
+	if resp.Code != http.StatusOK {
+		if w := res.Winner; w != nil {
+			t.Fatalf(`%v: exp %v %v to be served by %v; got %v %q`,
+				strategy, res.Method, res.Target, w.Route, resp.Code, resp.Body)
+		}
+		return
+	}
+
+	var got struct {
+		Route  int
+		Params map[string]string
+	}
+	if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+		t.Fatalf(`%v: exp nil err; got %v decoding %q`, strategy, err, resp.Body)
+	}
+	if res.Winner == nil {
+		t.Fatalf(`%v: exp %v %v to not be served; got route field R%d`,
+			strategy, res.Method, res.Target, got.Route)
+	}
+	c := res.Winner
+	if field := fmt.Sprintf(`R%d`, got.Route); c.Route.Field != field {
+		t.Fatalf(`%v: exp %v %v to be served by %v of %v; got route field %v`,
+			strategy, res.Method, res.Target, c.Route, c.Route.Field, field)
+	}
+
+	// unset fields of absent params are empty, as are empty query params
+	exp := make(map[string]string)
+	for _, p := range c.Params {
+		if p.Value != `` {
+			exp[p.Name] = p.Value
+		}
+	}
+	for name, v := range got.Params {
+		if v == `` {
+			delete(got.Params, name)
+		}
+	}
+	if fmt.Sprint(exp) != fmt.Sprint(got.Params) {
+		t.Fatalf(`%v: exp %v %v served by %v to have params %v; got %v`,
+			strategy, res.Method, res.Target, c.Route, exp, got.Params)
+	}
+}
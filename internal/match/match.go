@@ -0,0 +1,612 @@
+// Package match implements a reference matcher for the analyzed routes of a
+// router struct, which explains which route a request matches and why each
+// other route does not.
+//
+// Routes are tried in order of precedence as analyze orders them, where the
+// first route whose http method, header predicates and path match a request
+// serves it, unless its Accept or Consumes predicates reject the request and a
+// route of lower precedence is tried instead. Optional params are matched by the
+// routes analyze expands them into, and the case, clean and slash options of the
+// router struct are applied as the generated router applies them. The nfc
+// option is not, so a path is matched as if it were already in Unicode NFC.
+//
+// Literals must match exactly, while params match at least one byte within a
+// single path segment, or one or more whole segments for a wildcard, and must
+// match their regexp and length bounds. Params followed by a literal within a
+// segment match greedily. The path is split into segments before it is
+// unescaped, so a percent-encoded slash within a param value does not separate
+// segments unless the slash option decodes it. States of the search which
+// failed are recorded, so a request is matched in polynomial time however many
+// params a route holds.
+package match
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/backend"
+	"github.com/cstockton/routepiler/internal/parser"
+)
+
+// Router is the analyzed routes of a router struct to match requests against.
+type Router struct {
+	Name   string
+	Routes []*Route // in order of precedence
+	Match  backend.Match
+}
+
+// New returns the router matching requests against the analyzed routes of r.
+func New(r *backend.Router) *Router {
+	rt := &Router{Name: r.Name, Match: r.Match}
+	for _, br := range r.Routes {
+		rt.Routes = append(rt.Routes, &Route{Name: br.Handler.Name, Route: br})
+	}
+	return rt
+}
+
+// Load parses the non-test Go files within dir and returns the router of the
+// named router struct, with each route named after its handler.
+func Load(dir, router string) (*Router, error) {
+	r, err := analyze.Load(dir, router)
+	if err != nil {
+		return nil, err
+	}
+	return New(r), nil
+}
+
+// Route is a single analyzed route to match requests against.
+type Route struct {
+	Name string // identifies the route within traces, such as its handler
+	*backend.Route
+}
+
+// Param is a param extracted from a request.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Candidate is the result of matching a request against a single route.
+type Candidate struct {
+	Route    *Route
+	Params   []Param // params of a matching route
+	Reason   string  // why the route did not match, empty when it matched
+	Redirect string  // path in the casing of a route with the redirect case option
+}
+
+// Matched returns true if the route matched the request.
+func (c *Candidate) Matched() bool { return c.Reason == `` }
+
+// Result is the result of matching a request against a router.
+type Result struct {
+	Method     string // http method of the request
+	Target     string // path and query of the request
+	Candidates []*Candidate
+	Winner     *Candidate // nil when no route serves the request
+
+	// Redirect is the path and query the request is redirected to, by the clean
+	// option of the router or the case option of the winner, or empty.
+	Redirect string
+
+	// Status is the status of the response when no route serves the request or
+	// it is redirected, such as 406 Not Acceptable when each route matching the
+	// path rejected the Accept header of the request.
+	Status int
+}
+
+// Match matches a request against each route of a router, in order of
+// precedence.
+func Match(rt *Router, r *http.Request) (*Result, error) {
+	res := &Result{Method: r.Method, Target: r.URL.RequestURI(), Status: http.StatusNotFound}
+	if !strings.HasPrefix(r.URL.EscapedPath(), `/`) {
+		return nil, fmt.Errorf(`path of %q does not begin with a slash`, res.Target)
+	}
+	query := ``
+	if r.URL.RawQuery != `` {
+		query = `?` + r.URL.RawQuery
+	}
+
+	p, rejected := canonicalPath(r.URL.EscapedPath()), ``
+	switch rt.Match.Slash {
+	case backend.Reject:
+		if strings.Contains(p, `%2F`) {
+			rejected = `path holds a percent-encoded slash, which the slash option rejects`
+		}
+	case backend.Decode:
+		p = strings.ReplaceAll(p, `%2F`, `/`)
+	}
+	switch clean, ok := cleanPath(p); {
+	case rt.Match.Clean == backend.Redirect && ok && rejected == ``:
+		res.Redirect, res.Status = clean+query, redirectStatus(r.Method)
+		return res, nil
+	case rt.Match.Clean == backend.Lenient:
+		p = clean
+	}
+
+	segs := strings.Split(p[1:], `/`)
+	vals := queryValues(r.URL.RawQuery)
+	for _, route := range rt.Routes {
+		c := &Candidate{Route: route, Reason: rejected}
+		if c.Reason == `` {
+			status := rt.match(c, r, p, segs, vals)
+			if status != http.StatusNotFound && res.Status == http.StatusNotFound {
+				res.Status = status
+			}
+		}
+		res.Candidates = append(res.Candidates, c)
+		if res.Winner == nil && c.Matched() {
+			res.Winner, res.Status = c, http.StatusOK
+			if c.Redirect != `` {
+				res.Redirect, res.Status = c.Redirect+query, redirectStatus(r.Method)
+			}
+		}
+	}
+	return res, nil
+}
+
+// match sets the params of a candidate when its route matches a request, or
+// the reason it does not. It returns the status of the response when the route
+// matches the path of the request but its Accept or Consumes predicates reject
+// it, and otherwise 404 Not Found.
+func (rt *Router) match(c *Candidate, r *http.Request, p string, segs []string,
+	vals map[string]string) int {
+	route := c.Route
+	if route.Method != `` && route.Method != r.Method {
+		c.Reason = fmt.Sprintf(`method %v does not match %v`, r.Method, route.Method)
+		return http.StatusNotFound
+	}
+	for _, pred := range route.Predicates {
+		if v := r.Header.Get(pred.Header); pred.Kind == backend.Header && v != pred.Values[0] {
+			c.Reason = fmt.Sprintf(`header %v value %q does not equal %q`,
+				pred.Header, v, pred.Values[0])
+			return http.StatusNotFound
+		}
+	}
+
+	m := &matcher{route: route.Route, segs: segs, runes: rt.Match.Runes,
+		fail: [2]int{-1, -1}, dead: make([]bool, (len(route.Path)+1)*(len(segs)+1))}
+	if !m.match(0, 0) {
+		c.Reason = m.reason
+		return http.StatusNotFound
+	}
+	for _, prm := range route.Params {
+		if !prm.Query && !present(route.Route, prm) {
+			m.params = append(m.params, Param{prm.Name, prm.Default})
+		}
+	}
+	for _, prm := range route.Params {
+		if !prm.Query {
+			continue
+		}
+		v, ok := vals[prm.Name]
+		switch {
+		case ok:
+			if reason := check(prm.Param, v, rt.Match.Runes); reason != `` {
+				c.Reason = `query ` + reason
+				return http.StatusNotFound
+			}
+			m.params = append(m.params, Param{prm.Name, v})
+		case prm.Required:
+			c.Reason = fmt.Sprintf(`query param %q is required`, prm.Name)
+			return http.StatusNotFound
+		case prm.Default != ``:
+			m.params = append(m.params, Param{prm.Name, prm.Default})
+		}
+	}
+
+	if route.Case == backend.FoldRedirect {
+		if cp := casing(route.Route, segs); cp != p {
+			c.Params, c.Redirect = m.params, cp
+			return http.StatusNotFound
+		}
+	}
+	for _, pred := range route.Predicates {
+		switch {
+		case pred.Kind == backend.Consumes && !consumes(r, pred.Values):
+			c.Reason = fmt.Sprintf(`Content-Type %q is not one of %v`,
+				r.Header.Get(`Content-Type`), strings.Join(pred.Values, `, `))
+			return http.StatusUnsupportedMediaType
+		case pred.Kind == backend.Accept && !accepts(r, pred.Values):
+			c.Reason = fmt.Sprintf(`Accept %q does not include any of %v`,
+				strings.Join(r.Header.Values(`Accept`), `, `), strings.Join(pred.Values, `, `))
+			return http.StatusNotAcceptable
+		}
+	}
+	c.Params = m.params
+	return http.StatusNotFound
+}
+
+// canonicalPath returns an escaped path with the percent-encodings of each
+// segment made canonical, as the generated router does before matching.
+func canonicalPath(p string) string {
+	segs := strings.Split(p, `/`)
+	for i, seg := range segs {
+		segs[i] = canonical(seg)
+	}
+	return strings.Join(segs, `/`)
+}
+
+// canonical returns an escaped path segment with each byte which may not appear
+// unescaped within a path segment percent-encoded with upper case hex digits,
+// so it may be compared however it was escaped. A segment which is not validly
+// escaped is returned as is.
+func canonical(s string) string {
+	const hex = `0123456789ABCDEF`
+	if strings.IndexByte(s, '%') < 0 {
+		return s
+	}
+	v, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	for _, c := range []byte(v) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			strings.IndexByte(`-._~!$&'()*+,;=:@[]`, c) >= 0:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+// unescape returns the unescaped form of an escaped path segment, or s when it
+// is not validly escaped.
+func unescape(s string) string {
+	if v, err := url.PathUnescape(s); err == nil {
+		return v
+	}
+	return s
+}
+
+// cleanPath returns the clean form of an escaped path, without repeated
+// slashes, dot segments or a trailing slash, along with true if it differs.
+func cleanPath(p string) (string, bool) {
+	clean := path.Clean(p)
+	return clean, clean != p
+}
+
+// redirectStatus returns the status of a redirect, which keeps the method and
+// body of a request other than GET or HEAD.
+func redirectStatus(method string) int {
+	if method == http.MethodGet || method == http.MethodHead {
+		return http.StatusMovedPermanently
+	}
+	return http.StatusPermanentRedirect
+}
+
+// queryValues returns the unescaped value of the first occurrence of each param
+// of a raw query, skipping the pairs which fail to unescape or hold a semicolon
+// as the generated router does.
+func queryValues(q string) map[string]string {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(q, `&`) {
+		if pair == `` || strings.IndexByte(pair, ';') >= 0 {
+			continue
+		}
+		key, val := pair, ``
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			key, val = pair[:i], pair[i+1:]
+		}
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			continue
+		}
+		if _, ok := out[key]; ok {
+			continue
+		}
+		if v, err := url.QueryUnescape(val); err == nil {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// consumes returns true if the media type of the Content-Type header of r is
+// one of types, where a request without the header is application/octet-stream.
+func consumes(r *http.Request, types []string) bool {
+	ct := r.Header.Get(`Content-Type`)
+	if ct == `` {
+		ct = `application/octet-stream`
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, typ := range types {
+		if mt == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// accepts returns true if a media range of the Accept header of r with a
+// non-zero quality includes one of types, where a request without the header
+// accepts any type.
+func accepts(r *http.Request, types []string) bool {
+	vs := r.Header.Values(`Accept`)
+	if len(vs) == 0 {
+		return true
+	}
+	for _, v := range vs {
+		for _, rng := range strings.Split(v, `,`) {
+			mt, params, err := mime.ParseMediaType(rng)
+			if err != nil {
+				continue
+			}
+			if q, ok := params[`q`]; ok {
+				if f, err := strconv.ParseFloat(q, 64); err != nil || f <= 0 {
+					continue
+				}
+			}
+			for _, typ := range types {
+				if mt == `*/*` || mt == typ ||
+					strings.HasSuffix(mt, `/*`) && strings.HasPrefix(typ, mt[:len(mt)-1]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// present returns true if the path of a route holds a path param, which is
+// otherwise an optional param omitted by an expanded route.
+func present(rt *backend.Route, p *backend.Param) bool {
+	for _, seg := range rt.Path {
+		for _, part := range seg {
+			if part.Param == p.Param {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// casing returns the path matched by a route with each static segment in the
+// casing of the route.
+func casing(rt *backend.Route, segs []string) string {
+	var b strings.Builder
+	si := 0
+	for ri, seg := range rt.Path {
+		end := si + 1
+		if analyze.Wild(seg) != nil {
+			end = len(segs) - (len(rt.Path) - ri - 1)
+		}
+		b.WriteString(`/`)
+		if seg.Static() {
+			b.WriteString(seg.String())
+		} else {
+			b.WriteString(strings.Join(segs[si:end], `/`))
+		}
+		si = end
+	}
+	return b.String()
+}
+
+// fold returns true if the escaped segment s equals the escaped static segment
+// lit regardless of case. Literals of ASCII fold only ASCII letters, while
+// others are unescaped and fold under Unicode.
+func fold(s, lit string) bool {
+	u := unescape(lit)
+	for i := 0; i < len(u); i++ {
+		if u[i] >= utf8.RuneSelf {
+			return strings.EqualFold(unescape(s), u)
+		}
+	}
+	return len(s) == len(lit) && strings.EqualFold(s, lit)
+}
+
+// matcher matches the path segments of a request against a route, recording
+// the reason for the failure which got furthest into the route.
+type matcher struct {
+	route  *backend.Route
+	segs   []string // canonical escaped segments of the request
+	runes  bool     // the runes match option of the router
+	params []Param
+	dead   []bool // route and request segment pairs which failed to match
+	fail   [2]int // route segment and part of the recorded failure
+	reason string
+}
+
+// failed records why part pi of route segment ri failed to match when it is
+// further into the route than any prior failure, then returns false.
+func (m *matcher) failed(ri, pi int, msg string, args ...interface{}) bool {
+	if ri > m.fail[0] || ri == m.fail[0] && pi > m.fail[1] {
+		m.fail = [2]int{ri, pi}
+		m.reason = fmt.Sprintf(`segment %d: `, ri) + fmt.Sprintf(msg, args...)
+	}
+	return false
+}
+
+// match returns true if the route segments from ri match the request segments
+// from si. Params are only ever appended, so a pair which failed to match fails
+// however it was reached.
+func (m *matcher) match(ri, si int) bool {
+	state := ri*(len(m.segs)+1) + si
+	if m.dead[state] {
+		return false
+	}
+	if m.segment(ri, si) {
+		return true
+	}
+	m.dead[state] = true
+	return false
+}
+
+// segment returns true if route segment ri matches request segment si and
+// those following it match the remaining route segments.
+func (m *matcher) segment(ri, si int) bool {
+	if ri == len(m.route.Path) {
+		if si == len(m.segs) {
+			return true
+		}
+		return m.failed(ri-1, 0, `request has %d more segments`, len(m.segs)-si)
+	}
+
+	seg, n := m.route.Path[ri], len(m.params)
+	if si >= len(m.segs) {
+		return m.failed(ri, 0, `request has no more segments`)
+	}
+	if seg.Static() && m.route.Case != backend.Exact {
+		if !fold(m.segs[si], seg.String()) {
+			return m.failed(ri, 0, `literal %q does not match %q regardless of case`,
+				unescape(seg.String()), unescape(m.segs[si]))
+		}
+		return m.match(ri+1, si+1)
+	}
+	if analyze.Wild(seg) == nil {
+		if m.parts(ri, m.segs[si]) && m.match(ri+1, si+1) {
+			return true
+		}
+		m.params = m.params[:n]
+		return false
+	}
+	for end := len(m.segs); end > si; end-- {
+		if m.parts(ri, strings.Join(m.segs[si:end], `/`)) && m.match(ri+1, end) {
+			return true
+		}
+		m.params = m.params[:n]
+	}
+	return false
+}
+
+// parts returns true if the parts of route segment ri match all of the escaped
+// path s, which holds more than one request segment for a wildcard.
+func (m *matcher) parts(ri int, s string) bool {
+	dead := make([]bool, len(m.route.Path[ri])*(len(s)+1))
+	return m.partsAt(ri, 0, s, 0, dead)
+}
+
+// partsAt returns true if the parts of a route segment from pi match all of s
+// from off, recording each state of the search which failed in dead.
+func (m *matcher) partsAt(ri, pi int, s string, off int, dead []bool) bool {
+	seg := m.route.Path[ri]
+	if pi == len(seg) {
+		if off < len(s) {
+			return m.failed(ri, pi, `unmatched %q`, unescape(s[off:]))
+		}
+		return true
+	}
+	state := pi*(len(s)+1) + off
+	if dead[state] {
+		return false
+	}
+	if m.part(ri, pi, s, off, dead) {
+		return true
+	}
+	dead[state] = true
+	return false
+}
+
+func (m *matcher) part(ri, pi int, s string, off int, dead []bool) bool {
+	seg, rest := m.route.Path[ri], s[off:]
+	part := seg[pi]
+	if part.Param == nil {
+		lit := part.Lit
+		if pi == len(seg)-1 && rest != lit || !strings.HasPrefix(rest, lit) {
+			return m.failed(ri, pi, `literal %q does not match %q`, unescape(lit), unescape(rest))
+		}
+		return m.partsAt(ri, pi+1, s, off+len(lit), dead)
+	}
+
+	prm, n := part.Param, len(m.params)
+	if rest == `` {
+		return m.failed(ri, pi, `param %q is empty`, prm.Name)
+	}
+	if pi == len(seg)-1 {
+		v, reason := value(prm, rest, m.runes)
+		if reason != `` {
+			return m.failed(ri, pi, `%v`, reason)
+		}
+		m.params = append(m.params, Param{prm.Name, v})
+		return true
+	}
+
+	// params within a segment are always followed by a literal
+	lit, found := seg[pi+1].Lit, false
+	for end := len(s) - 1; end > off; end-- {
+		if !strings.HasPrefix(s[end:], lit) {
+			continue
+		}
+		found = true
+		v, reason := value(prm, s[off:end], m.runes)
+		if reason != `` {
+			m.failed(ri, pi, `%v`, reason)
+			continue
+		}
+		m.params = append(m.params, Param{prm.Name, v})
+		if m.partsAt(ri, pi+1, s, end, dead) {
+			return true
+		}
+		m.params = m.params[:n]
+	}
+	if !found {
+		return m.failed(ri, pi+1, `literal %q not found in %q`, unescape(lit), unescape(rest))
+	}
+	return false
+}
+
+// value returns the unescaped value of a path param matching the escaped path
+// s, or why it does not meet the bounds of the param.
+func value(prm *parser.Param, s string, runes bool) (string, string) {
+	v := unescape(s)
+	switch {
+	case !prm.Wild && strings.Contains(s, `/`):
+		return ``, fmt.Sprintf(`param %q value %q contains a slash`, prm.Name, v)
+	case prm.Wild && prm.Depth > 0 && strings.Count(s, `/`) >= prm.Depth:
+		return ``, fmt.Sprintf(`param %q value %q spans %d segments, exceeding its depth of %d`,
+			prm.Name, v, strings.Count(s, `/`)+1, prm.Depth)
+	}
+	return v, check(prm, v, runes)
+}
+
+// check returns why a value does not meet the bounds of a param, or an empty
+// string. Bounds count bytes, or runes when runes is true in which case a value
+// checked against bounds or a regexp must be valid UTF-8.
+func check(prm *parser.Param, v string, runes bool) string {
+	n := len(v)
+	if runes {
+		n = utf8.RuneCountInString(v)
+	}
+	switch {
+	case runes && (prm.Min > 0 || prm.Max > 0 || prm.Regexp != ``) && !utf8.ValidString(v):
+		return fmt.Sprintf(`param %q value %q is not valid UTF-8`, prm.Name, v)
+	case prm.Regexp != `` && !compile(prm.Regexp).MatchString(v):
+		return fmt.Sprintf(`param %q value %q does not match regexp %v`, prm.Name, v, prm.Regexp)
+	case prm.Min > 0 && n < prm.Min:
+		return fmt.Sprintf(`param %q value %q is shorter than its min length of %d`,
+			prm.Name, v, prm.Min)
+	case prm.Max > 0 && n > prm.Max:
+		return fmt.Sprintf(`param %q value %q is longer than its max length of %d`,
+			prm.Name, v, prm.Max)
+	}
+	return ``
+}
+
+var regexps sync.Map
+
+// compile returns the anchored form of a param regexp, which the parser has
+// already verified compiles.
+func compile(expr string) *regexp.Regexp {
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(`^(?:` + expr + `)$`)
+	regexps.Store(expr, re)
+	return re
+}
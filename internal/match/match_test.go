@@ -0,0 +1,368 @@
+package match
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+const testDir = `testdata/router`
+
+// newRouter returns the router analyzed from a router struct with a field for
+// each tag, where a tag declaring routes is a field of type http.Handler named
+// R0, R1 and so on and any other tag is a blank field holding options.
+func newRouter(t *testing.T, tags ...string) *Router {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("package main\n\nimport \"net/http\"\n\ntype Router struct {\n")
+	for i, str := range tags {
+		if ps, err := tag.Parse(str); err == nil && len(ps.Routes()) == 0 {
+			fmt.Fprintf(&b, "\t_ struct{} %v\n", strconv.Quote(str))
+			continue
+		}
+		fmt.Fprintf(&b, "\tR%d http.Handler %v\n", i, strconv.Quote(str))
+	}
+	b.WriteString("}\n")
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, b.String(), 0)
+	if err != nil {
+		t.Fatalf("exp nil err; got %v from:\n%v", err, b.String())
+	}
+	r, err := analyze.Analyze(fset, []*ast.File{f}, `main`, `Router`)
+	if err != nil {
+		t.Fatalf("exp nil err; got %v from:\n%v", err, b.String())
+	}
+	return New(r)
+}
+
+// newRequest returns a request with headers given as Name: value pairs.
+func newRequest(t *testing.T, method, target string, header ...string) *http.Request {
+	t.Helper()
+	r, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	for _, h := range header {
+		i := strings.IndexByte(h, ':')
+		r.Header.Add(h[:i], strings.TrimSpace(h[i+1:]))
+	}
+	return r
+}
+
+func TestLoad(t *testing.T) {
+	rt, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	var got []string
+	for _, r := range rt.Routes {
+		got = append(got, r.String()+` `+r.Name)
+	}
+	exp := []string{
+		`GET / Root`,
+		`GET /files/:path* Files.Get`,
+		`GET /items GetV2`,
+		`GET /items Items.Get`,
+		`GET /users/me GetMe`,
+		`GET /users/:id([0-9]+) Users.Get`,
+		`PUT /users/:id([0-9]+) Users.Put`,
+		`GET /users/:name{2-8} GetNamed`,
+	}
+	if fmt.Sprint(exp) != fmt.Sprint(got) {
+		t.Fatalf("exp routes:\n%v\ngot:\n%v", strings.Join(exp, "\n"), strings.Join(got, "\n"))
+	}
+	if _, err := Load(testDir, `Bogus`); err == nil {
+		t.Fatal(`exp non-nil err`)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		routes []string // method pattern, method may be empty
+		method string
+		target string
+		exp    string // pattern and params of the winner
+		reason string // reason of the last route when no route matched
+	}{
+		{[]string{` /`}, `GET`, `/`, `/`, ``},
+		{[]string{` /a`, ` /:a`}, `GET`, `/a`, `/a`, ``},
+		{[]string{` /:a`, ` /a`}, `GET`, `/a`, `/a`, ``},
+		{[]string{` /:a`, ` /a`}, `GET`, `/b`, `/:a a="b"`, ``},
+		{[]string{` /:a*`, ` /:a`}, `GET`, `/b`, `/:a a="b"`, ``},
+		{[]string{` /:a`, ` /:a([a-z]+)`}, `GET`, `/b`, `/:a([a-z]+) a="b"`, ``},
+		{[]string{` /:a`, `get /:b`}, `GET`, `/x`, `/:b b="x"`, ``},
+		{[]string{` /x/:a`, ` /x/:b{1-2}`}, `GET`, `/x/y`, `/x/:b{1-2} b="y"`, ``},
+		{[]string{` /x/:a`, ` /x/:b{1-2}`}, `GET`, `/x/yyy`, `/x/:a a="yyy"`, ``},
+		{[]string{` /files/:path*`, ` /files/:path*/raw`}, `GET`, `/files/a/raw`,
+			`/files/:path*/raw path="a"`, ``},
+		{[]string{` /a/:b`, ` /:a/b`}, `GET`, `/a/b`, `/a/:b b="b"`, ``},
+		{[]string{` /users/:id.json`}, `GET`, `/users/a.b.json`,
+			`/users/:id.json id="a.b"`, ``},
+		{[]string{` /:a-:b`}, `GET`, `/x-y-z`, `/:a-:b a="x-y" b="z"`, ``},
+		{[]string{` /:a([a-z]+)-:b`}, `GET`, `/x1-y`, ``,
+			`segment 0: param "a" value "x1" does not match regexp [a-z]+`},
+		{[]string{` /:a([0-9]+)-:b`}, `GET`, `/x-y-z`, ``,
+			`segment 0: param "a" value "x-y" does not match regexp [0-9]+`},
+		{[]string{` /files/:path*`}, `GET`, `/files/a/b/c`,
+			`/files/:path* path="a/b/c"`, ``},
+		{[]string{` /files/:path*/edit`}, `GET`, `/files/a/b/edit`,
+			`/files/:path*/edit path="a/b"`, ``},
+		{[]string{` /files/:path*[2]`}, `GET`, `/files/a/b`,
+			`/files/:path*[2] path="a/b"`, ``},
+		{[]string{` /files/:path*[2]`}, `GET`, `/files/a/b/c`, ``,
+			`segment 1: param "path" value "a/b/c" spans 3 segments, ` +
+				`exceeding its depth of 2`},
+		{[]string{` /files/:path*`}, `GET`, `/files/a%2Fb/c%20d`,
+			`/files/:path* path="a/b/c d"`, ``},
+		{[]string{` /a/:b?`}, `GET`, `/a`, `/a/:b?`, ``},
+		{[]string{` /a/:b?`}, `GET`, `/a/x`, `/a/:b? b="x"`, ``},
+		{[]string{` /a/{b: b, optional: true, default: x}`}, `GET`, `/a`,
+			`/a/{b: b, optional: true, default: x} b="x"`, ``},
+		{[]string{` /a?b&c{default: 2}`}, `GET`, `/a?b=1`, `/a?b&c{default: 2} b="1" c="2"`, ``},
+		{[]string{` /a?b`}, `GET`, `/a?b=1&b=2&x;y`, `/a?b b="1"`, ``},
+		{[]string{` /a/:b`}, `GET`, `https://example.com/a/x?y=z`, `/a/:b b="x"`, ``},
+		{[]string{` /dl/:id`}, `GET`, `/dl/a%2Fb`, `/dl/:id id="a/b"`, ``},
+		{[]string{` /dl/:id.:ext`}, `GET`, `/dl/a%2Eb.c%20d`, `/dl/:id.:ext id="a.b" ext="c d"`, ``},
+		{[]string{` /a%3Ab/caf%c3%a9`}, `GET`, `/a:b/caf%C3%A9`, `/a%3Ab/caf%c3%a9`, ``},
+		{[]string{` /login?next`}, `GET`, `/login?next=/home`, `/login?next next="/home"`, ``},
+		{[]string{` /:a{2}`}, `GET`, `/%C3%A9`, `/:a{2} a="é"`, ``},
+
+		// failures
+		{[]string{` /a`}, `GET`, `/b`, ``, `segment 0: literal "a" does not match "b"`},
+		{[]string{` /a`}, `GET`, `/a/b`, ``, `segment 0: request has 1 more segments`},
+		{[]string{` /a/b`}, `GET`, `/a`, ``, `segment 1: request has no more segments`},
+		{[]string{` /a/:b`}, `GET`, `/a/`, ``, `segment 1: param "b" is empty`},
+		{[]string{` /a/:b?`}, `GET`, `/a/`, ``, `segment 1: param "b" is empty`},
+		{[]string{` /a.json`}, `GET`, `/a.jsonp`, ``,
+			`segment 0: literal "a.json" does not match "a.jsonp"`},
+		{[]string{`post /a`}, `GET`, `/a`, ``, `method GET does not match POST`},
+		{[]string{` /:a([0-9]+)`}, `GET`, `/x`, ``,
+			`segment 0: param "a" value "x" does not match regexp [0-9]+`},
+		{[]string{` /:a{2-3}`}, `GET`, `/x`, ``,
+			`segment 0: param "a" value "x" is shorter than its min length of 2`},
+		{[]string{` /:a{2-3}`}, `GET`, `/wxyz`, ``,
+			`segment 0: param "a" value "wxyz" is longer than its max length of 3`},
+		{[]string{` /:a{1}`}, `GET`, `/%C3%A9`, ``,
+			`segment 0: param "a" value "é" is longer than its max length of 1`},
+		{[]string{` /:a.json`}, `GET`, `/a.xml`, ``,
+			`segment 0: literal ".json" not found in "a.xml"`},
+		{[]string{` /a?b{required: true}`}, `GET`, `/a`, ``, `query param "b" is required`},
+		{[]string{` /a?b{regex: '[0-9]+'}`}, `GET`, `/a?b=x`, ``,
+			`query param "b" value "x" does not match regexp [0-9]+`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp %v %v against %v to match %q`,
+			idx, test.method, test.target, test.routes, test.exp)
+
+		var tags []string
+		for _, s := range test.routes {
+			i := strings.IndexByte(s, ' ')
+			key := s[:i]
+			if key == `` {
+				key = `path`
+			}
+			tags = append(tags, key+`:`+strconv.Quote(s[i+1:]))
+		}
+		res, err := Match(newRouter(t, tags...), newRequest(t, test.method, test.target))
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+
+		got := ``
+		if c := res.Winner; c != nil {
+			got = c.Route.Pattern + params(c.Params)
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp winner %q; got %q`, exp, got)
+		}
+		if test.exp == `` {
+			c := res.Candidates[len(res.Candidates)-1]
+			if exp, got := test.reason, c.Reason; exp != got {
+				t.Fatalf(`exp reason %q; got %q`, exp, got)
+			}
+		}
+	}
+}
+
+func TestMatchOptions(t *testing.T) {
+	tests := []struct {
+		tags   []string
+		req    []string // method, target and each header
+		exp    string   // field and params of the winner, or none
+		status int
+		redir  string
+	}{
+		{[]string{`get:"/items" header:"X-Api-Version: 2"`, `get:"/items"`},
+			[]string{`GET`, `/items`}, `R1`, 200, ``},
+		{[]string{`get:"/items" header:"X-Api-Version: 2"`, `get:"/items"`},
+			[]string{`GET`, `/items`, `X-Api-Version: 2`}, `R0`, 200, ``},
+		{[]string{`get:"/items" header:"X-Api-Version: 2"`},
+			[]string{`GET`, `/items`}, ``, 404, ``},
+		{[]string{`post:"/items" consumes:"application/json"`, `post:"/items"`},
+			[]string{`POST`, `/items`, `Content-Type: text/plain`}, `R1`, 200, ``},
+		{[]string{`post:"/items" consumes:"application/json"`},
+			[]string{`POST`, `/items`, `Content-Type: application/json; charset=utf-8`}, `R0`, 200, ``},
+		{[]string{`post:"/items" consumes:"application/json"`},
+			[]string{`POST`, `/items`, `Content-Type: text/plain`}, ``, 415, ``},
+		{[]string{`get:"/items" accept:"application/json"`},
+			[]string{`GET`, `/items`, `Accept: text/html, application/*;q=0.5`}, `R0`, 200, ``},
+		{[]string{`get:"/items" accept:"application/json"`},
+			[]string{`GET`, `/items`, `Accept: text/html, application/json;q=0`}, ``, 406, ``},
+		{[]string{`get:"/Items"`}, []string{`GET`, `/items`}, ``, 404, ``},
+		{[]string{`get:"/Items/:id" case:"fold"`},
+			[]string{`GET`, `/ITEMS/Ab`}, `R0 id="Ab"`, 200, ``},
+		{[]string{`get:"/caf%C3%A9" case:"fold"`}, []string{`GET`, `/CAF%C3%89`}, `R0`, 200, ``},
+		{[]string{`get:"/Items/:id" case:"redirect"`},
+			[]string{`GET`, `/items/Ab?x=1`}, `R0 id="Ab"`, 301, `/Items/Ab?x=1`},
+		{[]string{`case:"redirect"`, `post:"/Items"`},
+			[]string{`POST`, `/Items`}, `R1`, 200, ``},
+		{[]string{`case:"redirect"`, `post:"/Items"`},
+			[]string{`POST`, `/items`}, `R1`, 308, `/Items`},
+		{[]string{`get:"/a/b"`}, []string{`GET`, `/a//b`}, ``, 404, ``},
+		{[]string{`clean:"lenient"`, `get:"/a/b"`}, []string{`GET`, `/a/./x/..//b/`}, `R1`, 200, ``},
+		{[]string{`clean:"redirect"`, `get:"/a/b"`},
+			[]string{`GET`, `/a//b?c=d`}, ``, 301, `/a/b?c=d`},
+		{[]string{`get:"/a/:b"`}, []string{`GET`, `/a/x%2Fy`}, `R0 b="x/y"`, 200, ``},
+		{[]string{`slash:"reject"`, `get:"/a/:b"`}, []string{`GET`, `/a/x%2fy`}, ``, 404, ``},
+		{[]string{`slash:"decode"`, `get:"/a/:b/:c"`},
+			[]string{`GET`, `/a/x%2Fy`}, `R1 b="x" c="y"`, 200, ``},
+		{[]string{`match:"runes"`, `get:"/:a{1}"`}, []string{`GET`, `/%C3%A9`}, `R1 a="é"`, 200, ``},
+		{[]string{`match:"runes"`, `get:"/:a{1}"`}, []string{`GET`, `/%FF`}, ``, 404, ``},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp %v against %v to be served by %q`, idx, test.req, test.tags, test.exp)
+
+		r := newRequest(t, test.req[0], test.req[1], test.req[2:]...)
+		res, err := Match(newRouter(t, test.tags...), r)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		got := ``
+		if c := res.Winner; c != nil {
+			got = c.Route.Field + params(c.Params)
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp winner %q; got %q`, exp, got)
+		}
+		if exp, got := test.status, res.Status; exp != got {
+			t.Fatalf(`exp status %v; got %v`, exp, got)
+		}
+		if exp, got := test.redir, res.Redirect; exp != got {
+			t.Fatalf(`exp redirect %q; got %q`, exp, got)
+		}
+	}
+}
+
+// TestBacktracking ensures routes with many params which can not match are
+// rejected in polynomial time.
+func TestBacktracking(t *testing.T) {
+	tests := []struct {
+		pattern, target string
+	}{
+		{`/:pa*/{a}-{b}-{c}-{d}-{e}-{f}x`, strings.Repeat(`/a`, 50) + `/` + strings.Repeat(`-`, 60)},
+		{`/{a}-{b}-{c}-{d}-{e}-{f}x`, `/` + strings.Repeat(`-`, 60)},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp %v to not match %v in time`, idx, test.pattern, test.target)
+		rt := newRouter(t, `get:`+strconv.Quote(test.pattern))
+		r := newRequest(t, `GET`, test.target)
+
+		done := make(chan *Result, 1)
+		go func() {
+			res, err := Match(rt, r)
+			if err != nil {
+				t.Errorf(`exp nil err; got %v`, err)
+			}
+			done <- res
+		}()
+		select {
+		case res := <-done:
+			if res != nil && res.Winner != nil {
+				t.Fatalf(`exp no winner; got %v`, res.Winner.Route)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal(`exp match to return within 5s`)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	rt, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	tests := []struct {
+		req []string // method, target and each header
+		exp []string
+	}{
+		{[]string{`GET`, `/users/42`}, []string{
+			`GET /users/42`,
+			`║   1 │ GET /                  │ Root      │ segment 0: unmatched "users"                      ║`,
+			`║   2 │ GET /files/:path*      │ Files.Get │ segment 0: literal "files" does not match "users" ║`,
+			`║   5 │ GET /users/me          │ GetMe     │ segment 1: literal "me" does not match "42"       ║`,
+			`║   6 │ GET /users/:id([0-9]+) │ Users.Get │ matched id="42"                                   ║`,
+			`║   7 │ PUT /users/:id([0-9]+) │ Users.Put │ method GET does not match PUT                     ║`,
+			`║   8 │ GET /users/:name{2-8}  │ GetNamed  │ matched name="42"                                 ║`,
+			`winner: GET /users/:id([0-9]+) (Users.Get)`,
+			`  id = "42"`}},
+		{[]string{`GET`, `/users/me`}, []string{`winner: GET /users/me (GetMe)`}},
+		{[]string{`GET`, `/users/bob`}, []string{`winner: GET /users/:name{2-8} (GetNamed)`}},
+		{[]string{`GET`, `/users/b`}, []string{
+			`is shorter than its min length of 2`,
+			`winner: none`}},
+		{[]string{`GET`, `/files/a/b`}, []string{
+			`winner: GET /files/:path* (Files.Get)`,
+			`  path = "a/b"`}},
+		{[]string{`GET`, `/items`}, []string{
+			`│ GetV2     │ header X-Api-Version value "" does not equal "2"`,
+			`│ Items.Get │ matched`,
+			`winner: GET /items (Items.Get)`}},
+		{[]string{`GET`, `/items`, `X-Api-Version: 2`}, []string{
+			`winner: GET /items (GetV2)`}},
+		{[]string{`GET`, `/items`, `Accept: text/html`}, []string{
+			`│ Items.Get │ Accept "text/html" does not include any of application/json`,
+			`winner: none, responds 406 Not Acceptable`}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp trace of %v to contain %v`, idx, test.req, test.exp)
+
+		var buf bytes.Buffer
+		r := newRequest(t, test.req[0], test.req[1], test.req[2:]...)
+		if _, err := Trace(&buf, rt, r); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		for _, exp := range test.exp {
+			if got := buf.String(); !strings.Contains(got, exp) {
+				t.Fatalf("exp trace to contain:\n%v\ngot:\n%v", exp, got)
+			}
+		}
+	}
+
+	if _, err := Trace(new(bytes.Buffer), rt, newRequest(t, `GET`, `users`)); err == nil {
+		t.Fatal(`exp non-nil err`)
+	}
+
+	// redirects are reported along with their status
+	var buf bytes.Buffer
+	r := newRouter(t, `clean:"redirect"`, `get:"/a/b"`)
+	if _, err := Trace(&buf, r, newRequest(t, `GET`, `/a//b`)); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	exp := "╜\nredirect: 301 Moved Permanently to /a/b\n"
+	if got := buf.String(); !strings.HasSuffix(got, exp) {
+		t.Fatalf("exp trace to end with:\n%v\ngot:\n%v", exp, got)
+	}
+}
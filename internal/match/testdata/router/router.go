@@ -0,0 +1,34 @@
+package main
+
+import "net/http"
+
+type Router struct {
+	Root    http.Handler `get:"/"`
+	Me      Users        `get:"/users/me" func:"GetMe"`
+	User    Users        `get:"/users/:id([0-9]+)" put:"/users/:id([0-9]+)"`
+	Named   Users        `get:"/users/:name{2-8}" func:"GetNamed"`
+	Files   Files        `get:"/files/:path*"`
+	ItemsV2 Items        `get:"/items" header:"X-Api-Version: 2" func:"GetV2"`
+	Items   Items        `get:"/items" accept:"application/json"`
+}
+
+type Users struct {
+	ID   int
+	Name string
+}
+
+func (h *Users) Get(w http.ResponseWriter, r *http.Request)      {}
+func (h *Users) Put(w http.ResponseWriter, r *http.Request)      {}
+func (h *Users) GetMe(w http.ResponseWriter, r *http.Request)    {}
+func (h *Users) GetNamed(w http.ResponseWriter, r *http.Request) {}
+
+type Files struct {
+	Path string
+}
+
+func (h *Files) Get(w http.ResponseWriter, r *http.Request) {}
+
+type Items struct{}
+
+func (h *Items) Get(w http.ResponseWriter, r *http.Request)   {}
+func (h *Items) GetV2(w http.ResponseWriter, r *http.Request) {}
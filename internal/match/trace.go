@@ -0,0 +1,87 @@
+package match
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Trace will match a request against each route of a router while writing a
+// table of each candidate route and why it did or did not match, followed by
+// the winner and its params or the response when no route serves the request.
+func Trace(w io.Writer, rt *Router, r *http.Request) (*Result, error) {
+	res, err := Match(rt, r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][3]string, len(res.Candidates))
+	width := [3]int{12, 7, 45}
+	for i, c := range res.Candidates {
+		result := `matched` + params(c.Params)
+		if !c.Matched() {
+			result = c.Reason
+		}
+		rows[i] = [3]string{c.Route.String(), c.Route.Name, result}
+		for j, s := range rows[i] {
+			if n := utf8.RuneCountInString(s); n > width[j] {
+				width[j] = n
+			}
+		}
+	}
+
+	line := func(beg, mid, end, fill string) string {
+		return beg + strings.Repeat(fill, 5) + mid +
+			strings.Repeat(fill, width[0]+2) + mid +
+			strings.Repeat(fill, width[1]+2) + mid +
+			strings.Repeat(fill, width[2]+2) + end + "\n"
+	}
+	row := func(n interface{}, cols [3]string) string {
+		return fmt.Sprintf("║ %3v │ %-*v │ %-*v │ %-*v ║\n",
+			n, width[0], cols[0], width[1], cols[1], width[2], cols[2])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v %v\n", res.Method, res.Target)
+	b.WriteString(line(`╔`, `╤`, `╗`, `═`))
+	b.WriteString(row(`#`, [3]string{`Route`, `Handler`, `Result`}))
+	b.WriteString(line(`╠`, `╪`, `╣`, `═`))
+	for i, cols := range rows {
+		b.WriteString(row(i+1, cols))
+		if i < len(rows)-1 {
+			b.WriteString(line(`║`, `┼`, `╢`, `─`))
+		}
+	}
+	b.WriteString(line(`╙`, `┴`, `╜`, `─`))
+
+	switch c := res.Winner; {
+	case c != nil:
+		fmt.Fprintf(&b, "winner: %v (%v)\n", c.Route, c.Route.Name)
+		for _, p := range c.Params {
+			fmt.Fprintf(&b, "  %v = %q\n", p.Name, p.Value)
+		}
+	case res.Redirect != ``:
+		// the request is redirected before any route is tried
+	case res.Status != http.StatusNotFound:
+		fmt.Fprintf(&b, "winner: none, responds %d %v\n", res.Status, http.StatusText(res.Status))
+	default:
+		b.WriteString("winner: none\n")
+	}
+	if res.Redirect != `` {
+		fmt.Fprintf(&b, "redirect: %d %v to %v\n", res.Status, http.StatusText(res.Status), res.Redirect)
+	}
+	if _, err = io.WriteString(w, b.String()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func params(ps []Param) string {
+	var b strings.Builder
+	for _, p := range ps {
+		fmt.Fprintf(&b, ` %v=%q`, p.Name, p.Value)
+	}
+	return b.String()
+}
@@ -0,0 +1,724 @@
+// Package migrate converts the route registrations of httprouter, chi and
+// gorilla/mux within Go source files into the route fields of a router struct.
+//
+// Registrations are recognized by the shape of their calls within files which
+// import one of the libraries, such as router.GET("/users/:user", h) for
+// httprouter, r.Get("/users/{user}", h) for chi and
+// r.HandleFunc("/users/{user:[a-z]+}", h).Methods("GET") for gorilla/mux. The
+// prefixes of chi Route and Group funcs and of gorilla/mux subrouters assigned
+// to a variable are followed. Anything which can not be expressed as a route
+// pattern is reported as a Diagnostic rather than converted, as is each
+// httprouter.Handle since its params must be read from struct fields instead.
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/format"
+	"github.com/cstockton/routepiler/internal/parser"
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+// Route is a route registration converted to a route pattern.
+type Route struct {
+	Pos     token.Position
+	Methods []string // lower case http methods, empty for any method
+	Pattern string
+	Handler string   // source of the handler expression
+	Global  bool     // handler is a package level func or var named by a func tag
+	Func    bool     // handler was registered as a func rather than http.Handler
+	Notes   []string // changes made while converting
+}
+
+// Diagnostic is a registration which could not be converted, or a part of one
+// which was dropped while converting.
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+// String returns the diagnostic in the form of file:line:col: message.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf(`%v: %v`, d.Pos, d.Message)
+}
+
+// Load parses the non-test Go files within dir and returns the Go source of a
+// router struct with the given name for the routes registered within them.
+func Load(dir, router string) ([]byte, []Diagnostic, error) {
+	fset := token.NewFileSet()
+	name, files, err := source.ParseDir(fset, dir, ``)
+	if err != nil {
+		return nil, nil, err
+	}
+	return Convert(fset, files, name, router)
+}
+
+// Convert returns the Go source of a router struct with the given name and
+// package for the routes registered within the files of a single package.
+func Convert(fset *token.FileSet, files []*ast.File, pkg, router string) ([]byte, []Diagnostic, error) {
+	routes, diags := Routes(fset, files)
+	src, err := Source(pkg, router, routes)
+	return src, diags, err
+}
+
+// Routes returns the routes registered within the files of a single package in
+// order of position, along with a diagnostic for each registration which could
+// not be converted.
+func Routes(fset *token.FileSet, files []*ast.File) ([]*Route, []Diagnostic) {
+	c := &converter{
+		fset:     fset,
+		pkg:      source.New(files),
+		prefixes: make(map[*ast.Object]string),
+	}
+	for _, f := range files {
+		if c.libs = libraries(f); c.libs == 0 {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ExprStmt:
+				if call, ok := n.X.(*ast.CallExpr); ok {
+					c.chain(call)
+				}
+			case *ast.AssignStmt:
+				c.subrouter(n)
+			}
+			return true
+		})
+	}
+	return c.routes, c.diags
+}
+
+// library is a set of routing libraries imported by a file.
+type library int
+
+const (
+	httprouter library = 1 << iota
+	chi
+	gorilla
+)
+
+func libraries(f *ast.File) (libs library) {
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		switch {
+		case err != nil:
+		case strings.HasSuffix(path, `/julienschmidt/httprouter`):
+			libs |= httprouter
+		case strings.Contains(path, `/go-chi/chi`):
+			libs |= chi
+		case strings.HasSuffix(path, `/gorilla/mux`):
+			libs |= gorilla
+		}
+	}
+	return
+}
+
+type converter struct {
+	fset     *token.FileSet
+	pkg      *source.Package
+	libs     library
+	prefixes map[*ast.Object]string // path prefix of subrouter variables
+	routes   []*Route
+	diags    []Diagnostic
+}
+
+func (c *converter) report(pos token.Pos, msg string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{
+		Pos: c.fset.Position(pos), Message: fmt.Sprintf(msg, args...)})
+}
+
+// registration is the state gathered from the calls of a single method chain.
+type registration struct {
+	pos       token.Pos
+	colon     bool // path uses the :param syntax of httprouter
+	prefix    string
+	path      ast.Expr
+	partial   bool // path is a gorilla/mux PathPrefix
+	methods   []ast.Expr
+	queries   []ast.Expr
+	handler   ast.Expr
+	fn        bool
+	handle    bool // handler is a httprouter.Handle taking the params
+	subrouter bool
+	matchers  []string // unsupported gorilla/mux matchers
+}
+
+// call is a single method call within a chain such as r.With(mw).Get("/", h).
+type call struct {
+	name string
+	args []ast.Expr
+	pos  token.Pos
+}
+
+// calls returns the calls of the method chain ending in e in order of
+// evaluation, with the expression the first method is called on.
+func calls(e ast.Expr) (root ast.Expr, out []call) {
+	for root = e; ; {
+		ce, ok := root.(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		sel, ok := ce.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return
+		}
+		out = append([]call{{sel.Sel.Name, ce.Args, sel.Sel.Pos()}}, out...)
+		root = sel.X
+	}
+}
+
+// prefix returns the path prefix of the value a chain is called on.
+func (c *converter) prefix(root ast.Expr) string {
+	if id, ok := root.(*ast.Ident); ok && id.Obj != nil {
+		return c.prefixes[id.Obj]
+	}
+	return ``
+}
+
+var httprouterMethods = map[string]bool{
+	`GET`: true, `HEAD`: true, `POST`: true, `PUT`: true, `PATCH`: true,
+	`DELETE`: true, `OPTIONS`: true,
+}
+
+var chiMethods = map[string]bool{
+	`Get`: true, `Head`: true, `Post`: true, `Put`: true, `Patch`: true,
+	`Delete`: true, `Connect`: true, `Options`: true, `Trace`: true,
+}
+
+// chain converts the method chain of an expression statement.
+func (c *converter) chain(e *ast.CallExpr) {
+	root, cs := calls(e)
+	if id, ok := root.(*ast.Ident); ok && id.Name == `http` && id.Obj == nil {
+		return // the net/http package
+	}
+
+	r := &registration{pos: e.Pos(), prefix: c.prefix(root)}
+	for _, cl := range cs {
+		c.call(r, cl)
+	}
+	if r.handler != nil {
+		c.add(r)
+	}
+}
+
+func (c *converter) call(r *registration, cl call) {
+	name, args := cl.name, cl.args
+	switch {
+	case c.libs&httprouter != 0 && httprouterMethods[name] && len(args) == 2:
+		r.colon, r.fn, r.handle = true, true, true
+		r.methods = []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(name)}}
+		r.path, r.handler = args[0], args[1]
+	case c.libs&httprouter != 0 && len(args) == 3 &&
+		(name == `Handle` || name == `Handler` || name == `HandlerFunc`):
+		r.colon, r.fn, r.handle = true, name != `Handler`, name == `Handle`
+		r.methods = args[:1]
+		r.path, r.handler = args[1], args[2]
+	case c.libs&httprouter != 0 && name == `ServeFiles`:
+		c.report(cl.pos, `ServeFiles registrations are not converted`)
+
+	case c.libs&chi != 0 && chiMethods[name] && len(args) == 2:
+		r.fn = true
+		r.methods = []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(name)}}
+		r.path, r.handler = args[0], args[1]
+	case c.libs&chi != 0 && (name == `Method` || name == `MethodFunc`) && len(args) == 3:
+		r.fn = name == `MethodFunc`
+		r.methods = args[:1]
+		r.path, r.handler = args[1], args[2]
+	case c.libs&chi != 0 && name == `Route` && len(args) == 2:
+		path, ok := literal(args[0])
+		if !ok {
+			c.report(args[0].Pos(), `path is not a string literal`)
+			return
+		}
+		c.scope(args[1], join(r.prefix, path))
+	case c.libs&chi != 0 && name == `Group` && len(args) == 1:
+		c.scope(args[0], r.prefix)
+	case c.libs&chi != 0 && name == `Mount`:
+		c.report(cl.pos, `mounted handlers are not converted`)
+	case c.libs&chi != 0 && name == `With`:
+		c.report(cl.pos, `middleware passed to With is not converted`)
+
+	case c.libs&(chi|gorilla) != 0 && (name == `Handle` || name == `HandleFunc`) && len(args) == 2:
+		r.fn = name == `HandleFunc`
+		r.path, r.handler = args[0], args[1]
+	case c.libs&gorilla != 0 && name == `Path` && len(args) == 1:
+		r.path = args[0]
+	case c.libs&gorilla != 0 && name == `PathPrefix` && len(args) == 1:
+		r.path, r.partial = args[0], true
+	case c.libs&gorilla != 0 && name == `Methods`:
+		r.methods = append(r.methods, args...)
+	case c.libs&gorilla != 0 && name == `Queries`:
+		r.queries = append(r.queries, args...)
+	case c.libs&gorilla != 0 && (name == `Handler` || name == `HandlerFunc`) && len(args) == 1:
+		r.fn = name == `HandlerFunc`
+		r.handler = args[0]
+	case c.libs&gorilla != 0 && name == `Subrouter`:
+		r.subrouter = true
+	case c.libs&gorilla != 0 && (name == `Host` || name == `Schemes` ||
+		name == `Headers` || name == `HeadersRegexp` || name == `MatcherFunc`):
+		r.matchers = append(r.matchers, name)
+	}
+}
+
+// scope records the prefix of the router param of a chi Route or Group func.
+func (c *converter) scope(e ast.Expr, prefix string) {
+	fl, ok := e.(*ast.FuncLit)
+	if !ok || len(fl.Type.Params.List) == 0 || len(fl.Type.Params.List[0].Names) == 0 {
+		c.report(e.Pos(), `routes of a func which is not a func literal are not converted`)
+		return
+	}
+	if obj := fl.Type.Params.List[0].Names[0].Obj; obj != nil {
+		c.prefixes[obj] = prefix
+	}
+}
+
+// subrouter records the prefix of a gorilla/mux subrouter assigned to a var,
+// such as api := r.PathPrefix("/api").Subrouter().
+func (c *converter) subrouter(as *ast.AssignStmt) {
+	if c.libs&gorilla == 0 || len(as.Lhs) != 1 || len(as.Rhs) != 1 {
+		return
+	}
+	id, ok := as.Lhs[0].(*ast.Ident)
+	call, isCall := as.Rhs[0].(*ast.CallExpr)
+	if !ok || !isCall || id.Obj == nil {
+		return
+	}
+
+	root, cs := calls(call)
+	r := &registration{pos: call.Pos(), prefix: c.prefix(root)}
+	for _, cl := range cs {
+		c.call(r, cl)
+	}
+	if !r.subrouter {
+		if r.handler != nil {
+			c.add(r)
+		}
+		return
+	}
+	switch {
+	case len(r.methods) > 0 || len(r.queries) > 0 || len(r.matchers) > 0:
+		c.report(r.pos, `subrouters with matchers other than PathPrefix are not converted`)
+	case r.path == nil:
+		c.prefixes[id.Obj] = r.prefix
+	default:
+		path, ok := literal(r.path)
+		if !ok {
+			c.report(r.path.Pos(), `path is not a string literal`)
+			return
+		}
+		c.prefixes[id.Obj] = join(r.prefix, path)
+	}
+}
+
+// add converts a registration to a route, merging it with a prior route of the
+// same pattern and handler.
+func (c *converter) add(r *registration) {
+	for _, m := range r.matchers {
+		c.report(r.pos, `%v matchers are not converted`, m)
+	}
+	if len(r.matchers) > 0 {
+		return
+	}
+	if r.path == nil {
+		c.report(r.pos, `routes without a path are not converted`)
+		return
+	}
+	if r.partial {
+		c.report(r.pos, `PathPrefix routes are not converted`)
+		return
+	}
+	path, ok := literal(r.path)
+	if !ok {
+		c.report(r.path.Pos(), `path is not a string literal`)
+		return
+	}
+
+	var methods []string
+	for _, e := range r.methods {
+		m, ok := method(e)
+		if !ok {
+			c.report(e.Pos(), `method is not a constant`)
+			return
+		}
+		methods = append(methods, m)
+	}
+
+	path = join(r.prefix, path)
+	pattern, notes, err := convert(path, r.colon)
+	if err == nil {
+		var query string
+		query, err = c.queries(r.queries)
+		pattern += query
+	}
+	if err == nil {
+		pattern, err = validate(pattern)
+	}
+	if err != nil {
+		c.report(r.path.Pos(), `%v`, err)
+		return
+	}
+
+	rt := &Route{
+		Pos:     c.fset.Position(r.pos),
+		Methods: methods,
+		Pattern: pattern,
+		Handler: types.ExprString(r.handler),
+		Global:  c.global(r.handler),
+		Func:    r.fn,
+		Notes:   notes,
+	}
+	if r.handle && !c.handlerFunc(r.handler) {
+		// a httprouter.Handle can not be named by a func tag, since the params
+		// it reads from httprouter.Params are bound to struct fields instead.
+		msg := fmt.Sprintf(`handler %v is a httprouter.Handle, rewrite it as a `+
+			`http.HandlerFunc reading its params from the fields of a struct`, rt.Handler)
+		c.report(r.handler.Pos(), `%v`, msg)
+		rt.Global, rt.Notes = false, append(rt.Notes, msg)
+	}
+	for _, prev := range c.routes {
+		if prev.mergeable(rt) {
+			prev.Methods = append(prev.Methods, rt.Methods...)
+			return
+		}
+	}
+	c.routes = append(c.routes, rt)
+}
+
+func (r *Route) mergeable(o *Route) bool {
+	if r.Pattern != o.Pattern || r.Handler != o.Handler || r.Func != o.Func ||
+		len(r.Methods) == 0 || len(o.Methods) == 0 {
+		return false
+	}
+	for _, m := range o.Methods {
+		for _, n := range r.Methods {
+			if m == n {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// global returns true if e names a package level func or var.
+func (c *converter) global(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	decl := c.pkg.Vars[id.Name]
+	if fd := c.pkg.Funcs[id.Name]; fd != nil {
+		decl = fd.Name
+	}
+	return decl != nil && (id.Obj == nil || id.Obj == decl.Obj)
+}
+
+// handlerFunc returns true if e is a package level func declared with the
+// signature of a http.HandlerFunc.
+func (c *converter) handlerFunc(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	if !ok || !c.global(e) || c.pkg.Funcs[id.Name] == nil {
+		return false
+	}
+	ft := c.pkg.Funcs[id.Name].Type
+	var params []string
+	for _, fd := range ft.Params.List {
+		for n := 0; n == 0 || n < len(fd.Names); n++ {
+			params = append(params, types.ExprString(fd.Type))
+		}
+	}
+	return ft.Results == nil && len(params) == 2 &&
+		params[0] == `http.ResponseWriter` && params[1] == `*http.Request`
+}
+
+// queries returns the query of a pattern for the key value pairs of a gorilla/mux
+// Queries matcher, which requires each key to be present.
+func (c *converter) queries(args []ast.Expr) (string, error) {
+	if len(args)%2 != 0 {
+		return ``, fmt.Errorf(`Queries has an odd number of arguments`)
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(args); i += 2 {
+		key, ok := literal(args[i])
+		val, isLit := literal(args[i+1])
+		if !ok || !isLit {
+			return ``, fmt.Errorf(`query is not a string literal`)
+		}
+		if source.ParamName(key) != key {
+			return ``, fmt.Errorf(`query %q is not a valid param name`, key)
+		}
+		if !strings.HasPrefix(val, `{`) || !strings.HasSuffix(val, `}`) {
+			return ``, fmt.Errorf(`query %q must equal %q which is not converted`, key, val)
+		}
+
+		sep := `&`
+		if i == 0 {
+			sep = `?`
+		}
+		buf.WriteString(sep + key + `{`)
+		if _, re := variable(val[1 : len(val)-1]); re != `` {
+			buf.WriteString(`regex: ` + format.Quote(re) + `, `)
+		}
+		buf.WriteString(`required: true}`)
+	}
+	return buf.String(), nil
+}
+
+// literal returns the value of a string literal.
+func literal(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ``, false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// method returns the lower case http method of a string literal such as "GET"
+// or a net/http constant such as http.MethodGet.
+func method(e ast.Expr) (string, bool) {
+	if s, ok := literal(e); ok && tag.IsRoute(strings.ToLower(s)) && s != `path` {
+		return strings.ToLower(s), true
+	}
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || types.ExprString(sel.X) != `http` || !strings.HasPrefix(sel.Sel.Name, `Method`) {
+		return ``, false
+	}
+	m := strings.ToLower(strings.TrimPrefix(sel.Sel.Name, `Method`))
+	return m, tag.IsRoute(m) && m != `path`
+}
+
+// join returns path appended to the prefix of a chi Route or gorilla/mux
+// subrouter, where the path "/" is the prefix itself.
+func join(prefix, path string) string {
+	if prefix == `` {
+		return path
+	}
+	if path == `/` {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, `/`) + path
+}
+
+// convert returns the route pattern for a path in the :param syntax of
+// httprouter when colon is true, otherwise in the {param} syntax of chi and
+// gorilla/mux.
+func convert(path string, colon bool) (pattern string, notes []string, err error) {
+	if !strings.HasPrefix(path, `/`) {
+		return ``, nil, fmt.Errorf(`path %q does not begin with a slash`, path)
+	}
+
+	var buf bytes.Buffer
+	segs := segments(path[1:])
+	for i, seg := range segs {
+		buf.WriteByte('/')
+		last := i == len(segs)-1
+
+		switch {
+		case colon && (strings.HasPrefix(seg, `:`) || strings.HasPrefix(seg, `*`)):
+			name := rename(seg[1:], &notes)
+			buf.WriteString(`:` + name)
+			if seg[0] == '*' {
+				buf.WriteString(`*`)
+			}
+		case colon:
+			// httprouter begins a param at any colon as does the pattern syntax
+			buf.WriteString(seg)
+		case seg == `*` && last:
+			notes = append(notes, `wildcard "*" is named "rest"`)
+			buf.WriteString(`:rest*`)
+		default:
+			parts := splitBraces(seg)
+			for _, part := range parts {
+				if !strings.HasPrefix(part, `{`) {
+					// chi and gorilla/mux match a colon literally
+					buf.WriteString(strings.ReplaceAll(part, `:`, `%3A`))
+					continue
+				}
+				if !strings.HasSuffix(part, `}`) {
+					return ``, nil, fmt.Errorf(`path %q has an unterminated param`, path)
+				}
+
+				name, re := variable(part[1 : len(part)-1])
+				if strings.Contains(re, `/`) {
+					return ``, nil, fmt.Errorf(`regexp of param %q matches a slash`, name)
+				}
+				name = rename(name, &notes)
+				switch {
+				case len(parts) > 1 && re == ``:
+					buf.WriteString(`{` + name + `}`)
+				case len(parts) > 1:
+					buf.WriteString(`{name: ` + name + `, regex: ` + format.Quote(re) + `}`)
+				case last && (re == `.*` || re == `.+`):
+					buf.WriteString(`:` + name + `*`)
+				case re != ``:
+					buf.WriteString(`:` + name + `(` + format.Quote(re) + `)`)
+				default:
+					buf.WriteString(`:` + name)
+				}
+			}
+		}
+	}
+	return buf.String(), notes, nil
+}
+
+// rename returns name as a valid param name, noting any change.
+func rename(name string, notes *[]string) string {
+	out := source.ParamName(name)
+	if out == `` {
+		out = `param`
+	}
+	if out != name {
+		*notes = append(*notes, fmt.Sprintf(`param %q is renamed to %q`, name, out))
+	}
+	return out
+}
+
+// segments splits a path on each slash which is not within braces.
+func segments(path string) (out []string) {
+	var depth, beg int
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				out = append(out, path[beg:i])
+				beg = i + 1
+			}
+		}
+	}
+	return append(out, path[beg:])
+}
+
+// splitBraces splits a path segment into literals and brace expressions.
+func splitBraces(seg string) (out []string) {
+	var depth, beg int
+	for i := 0; i < len(seg); i++ {
+		switch seg[i] {
+		case '{':
+			if depth++; depth == 1 {
+				if i > beg {
+					out = append(out, seg[beg:i])
+				}
+				beg = i
+			}
+		case '}':
+			if depth--; depth == 0 {
+				out = append(out, seg[beg:i+1])
+				beg = i + 1
+			}
+		}
+	}
+	if beg < len(seg) {
+		out = append(out, seg[beg:])
+	}
+	return
+}
+
+// variable returns the name and regexp of a chi or gorilla/mux variable.
+func variable(s string) (name, re string) {
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ``
+}
+
+// validate returns the canonical form of a converted pattern after checking it
+// parses to the path it was converted from.
+func validate(pat string) (string, error) {
+	out, err := format.Pattern(pat)
+	if err != nil {
+		return ``, fmt.Errorf(`invalid pattern %q: %v`, pat, err)
+	}
+	r, err := parser.Parse(out)
+	if err != nil {
+		return ``, fmt.Errorf(`invalid pattern %q: %v`, out, err)
+	}
+	for _, seg := range r.Path {
+		for _, part := range seg {
+			if part.Param == nil && strings.ContainsAny(part.Lit, `:{}*`) {
+				return ``, fmt.Errorf(`pattern %q contains an unconverted param`, out)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Source returns the Go source of a router struct with a field for each route.
+// Fields for package level handlers name them with a func tag, any other field
+// must be assigned the handler noted in its comment.
+func Source(pkg, router string, routes []*Route) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %v\n\nimport \"net/http\"\n\n", pkg)
+	fmt.Fprintf(&buf, "// %v holds the routes converted from httprouter, chi or "+
+		"gorilla/mux.\ntype %v struct {\n", router, router)
+
+	names := map[string]bool{router: true}
+	for i, r := range routes {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		pos := fmt.Sprintf(`%v:%d`, filepath.Base(r.Pos.Filename), r.Pos.Line)
+		if r.Global {
+			fmt.Fprintf(&buf, "\t// converted from %v\n", pos)
+		} else {
+			fmt.Fprintf(&buf, "\t// converted from %v, assign %v\n", pos, r.Handler)
+		}
+		for _, note := range r.Notes {
+			fmt.Fprintf(&buf, "\t// %v\n", note)
+		}
+
+		var ps tag.Pairs
+		for _, m := range r.Methods {
+			ps = append(ps, tag.Pair{Key: m, Value: r.Pattern})
+		}
+		if len(ps) == 0 {
+			ps = append(ps, tag.Pair{Key: `path`, Value: r.Pattern})
+		}
+		if r.Global {
+			ps = append(ps, tag.Pair{Key: `func`, Value: r.Handler})
+		}
+		typ := `http.Handler`
+		if r.Func {
+			typ = `http.HandlerFunc`
+		}
+		fmt.Fprintf(&buf, "\t%v %v %v\n", r.name(names), typ, tag.Quote(ps.String()))
+	}
+	buf.WriteString("}\n")
+	return gofmt.Source(buf.Bytes())
+}
+
+// name returns an unused field name for the route, the name of the handler when
+// it is an identifier or selector.
+func (r *Route) name(names map[string]bool) string {
+	var name string
+	if i := strings.LastIndexByte(r.Handler, '.'); i >= 0 {
+		name = source.ExportedName(r.Handler[i+1:])
+	} else {
+		name = source.ExportedName(r.Handler)
+	}
+	if name == `` || strings.ContainsAny(r.Handler, `(){} `) {
+		name = source.ExportedName(strings.Join(r.Methods, ` `) + ` ` + r.Pattern)
+	}
+	if name == `` {
+		name = `Root`
+	}
+	for i := 2; names[name]; i++ {
+		name = strings.TrimRight(name, `0123456789`) + strconv.Itoa(i)
+	}
+	names[name] = true
+	return name
+}
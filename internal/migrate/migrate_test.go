@@ -0,0 +1,200 @@
+package migrate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cstockton/routepiler/internal/vet"
+)
+
+const testDir = `testdata/routes`
+
+func handle(name string) string {
+	return `handler ` + name + ` is a httprouter.Handle, rewrite it as a ` +
+		`http.HandlerFunc reading its params from the fields of a struct`
+}
+
+func TestLoad(t *testing.T) {
+	src, diags, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	exp, err := ioutil.ReadFile(filepath.Join(testDir, `router.golden`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if string(exp) != string(src) {
+		t.Fatalf("exp source:\n%s\ngot:\n%s", exp, src)
+	}
+
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	expDiags := []string{
+		`testdata/routes/chi.go:22:6: middleware passed to With is not converted`,
+		`testdata/routes/chi.go:26:4: mounted handlers are not converted`,
+		`testdata/routes/gorilla.go:18:17: query "format" must equal "rss" which is not converted`,
+		`testdata/routes/gorilla.go:20:2: Host matchers are not converted`,
+		`testdata/routes/gorilla.go:21:2: PathPrefix routes are not converted`,
+		`testdata/routes/httprouter.go:17:18: ` + handle(`index`),
+		`testdata/routes/httprouter.go:18:29: ` + handle(`user`),
+		`testdata/routes/httprouter.go:19:30: ` + handle(`user`),
+		`testdata/routes/httprouter.go:20:54: ` + handle(`user`),
+		`testdata/routes/httprouter.go:23:9: ServeFiles registrations are not converted`,
+	}
+	if !reflect.DeepEqual(expDiags, got) {
+		t.Fatalf("exp diagnostics:\n%v\ngot:\n%v",
+			strings.Join(expDiags, "\n"), strings.Join(got, "\n"))
+	}
+
+	// the router must pass vet alongside the handlers it names
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, testDir, nil, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	f, err := parser.ParseFile(fset, `router.go`, src, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	files := []*ast.File{f}
+	for _, f := range pkgs[`main`].Files {
+		files = append(files, f)
+	}
+	vds, err := vet.Check(fset, files)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	for _, d := range vds {
+		t.Errorf(`exp no vet diagnostics; got %v: %v`, fset.Position(d.Pos), d.Message)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		path  string
+		colon bool
+		exp   string
+	}{
+		{`/`, true, `/`},
+		{`/users/:user`, true, `/users/:user`},
+		{`/users/:user/`, true, `/users/:user/`},
+		{`/src/*filepath`, true, `/src/:filepath*`},
+		{`/users/:user-id`, true, `/users/:userId`},
+		{`/users/{user}`, false, `/users/:user`},
+		{`/users/{id:[0-9]+}`, false, `/users/:id([0-9]+)`},
+		{`/users/{id:[0-9]{3}}/x`, false, `/users/:id([0-9]{3})/x`},
+		{`/files/{name}.{ext}`, false, `/files/{name}.{ext}`},
+		{`/files/v{v:[0-9]+}`, false, `/files/v{name: v, regex: '[0-9]+'}`},
+		{`/assets/{path:.*}`, false, `/assets/:path*`},
+		{`/assets/{path:.*}/x`, false, `/assets/:path(.*)/x`},
+		{`/files/*`, false, `/files/:rest*`},
+
+		// errors
+		{`users`, false, `path "users" does not begin with a slash`},
+		{`/users/{user`, false, `path "/users/{user" has an unterminated param`},
+		{`/users/{a:[}`, false, `invalid pattern`},
+		{`/a/{b:c/d}`, false, `regexp of param "b" matches a slash`},
+		{`/a:b`, true, `/a:b`},
+		{`/a:b`, false, `/a%3Ab`},
+		{`/a/{b}:c`, false, `/a/{b}%3Ac`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp convert(%v, %v) to return %v`,
+			idx, test.path, test.colon, test.exp)
+		got, _, err := convert(test.path, test.colon)
+		if err == nil {
+			got, err = validate(got)
+		}
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.HasPrefix(got, test.exp) {
+			t.Fatalf(`exp %v; got %v`, test.exp, got)
+		}
+	}
+}
+
+const testSrc = `package main
+
+import (
+	"net/http"
+
+	%q
+)
+
+func h(w http.ResponseWriter, r *http.Request) {}
+
+func routes(r *router, path string) {
+	%v
+}
+`
+
+func TestRoutes(t *testing.T) {
+	const (
+		hr = `github.com/julienschmidt/httprouter`
+		ch = `github.com/go-chi/chi`
+		gm = `github.com/gorilla/mux`
+	)
+	tests := []struct {
+		lib  string
+		stmt string
+		exp  string
+	}{
+		{hr, `r.POST("/users", h)`, `post /users h`},
+		{hr, `r.Get("/users", h)`, ``},
+		{hr, `r.HandlerFunc("GET", "/users", h)`, `get /users h`},
+		{ch, `r.Connect("/users", h)`, `connect /users h`},
+		{ch, `r.MethodFunc(http.MethodPatch, "/users", h)`, `patch /users h`},
+		{ch, `r.HandleFunc("/users/{user}", h)`, ` /users/:user h`},
+		{ch, `r.Group(func(r chi.Router) { r.Get("/users", h) })`, `get /users h`},
+		{ch, `r.Route("/a", func(r chi.Router) { r.Route("/b", func(r chi.Router) {` +
+			`r.Get("/", h) }) })`, `get /a/b h`},
+		{ch, `http.HandleFunc("/users", h)`, ``},
+		{gm, `r.Path("/users").Methods("get", "post").HandlerFunc(h)`, `get,post /users h`},
+		{gm, `r.Handle("/users", http.HandlerFunc(h))`, ` /users http.HandlerFunc(h)`},
+		{gm, `s := r.PathPrefix("/a").Subrouter(); t := s.PathPrefix("/b").Subrouter();` +
+			`t.HandleFunc("/c", h)`, ` /a/b/c h`},
+
+		// diagnostics
+		{hr, `r.GET(path, h)`, `12:8: path is not a string literal`},
+		{ch, `r.Method(r.method, "/", h)`, `12:11: method is not a constant`},
+		{ch, `r.Route("/a", routes)`, `12:16: routes of a func which is not a func literal are not converted`},
+		{gm, `r.HandleFunc("/", h).Queries("a")`, `12:15: Queries has an odd number of arguments`},
+		{gm, `r.HandleFunc("/", h).Queries("a-b", "{a}")`, `12:15: query "a-b" is not a valid param name`},
+		{gm, `r.Schemes("https").HandlerFunc(h)`, `12:2: Schemes matchers are not converted`},
+		{gm, `r.Methods("GET").Handler(h)`, `12:2: routes without a path are not converted`},
+		{gm, `s := r.Methods("GET").Subrouter()`,
+			`12:7: subrouters with matchers other than PathPrefix are not converted`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp %v using %v to convert to %v`,
+			idx, test.stmt, test.lib, test.exp)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, `routes.go`, fmt.Sprintf(testSrc, test.lib, test.stmt), 0)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		routes, diags := Routes(fset, []*ast.File{f})
+
+		var got []string
+		for _, r := range routes {
+			got = append(got, fmt.Sprintf(`%v %v %v`,
+				strings.Join(r.Methods, `,`), r.Pattern, r.Handler))
+		}
+		for _, d := range diags {
+			got = append(got, fmt.Sprintf(`%v:%v: %v`, d.Pos.Line, d.Pos.Column, d.Message))
+		}
+		if exp := test.exp; exp != strings.Join(got, "\n") {
+			t.Fatalf(`exp %q; got %q`, exp, got)
+		}
+	}
+}
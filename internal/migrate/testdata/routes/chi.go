@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Orgs struct{}
+
+func (h *Orgs) List(w http.ResponseWriter, r *http.Request) {}
+func (h *Orgs) Get(w http.ResponseWriter, r *http.Request)  {}
+
+func listFiles(w http.ResponseWriter, r *http.Request) {}
+
+func chiRouter(orgs *Orgs, mw func(http.Handler) http.Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Route("/orgs", func(r chi.Router) {
+		r.Get("/", orgs.List)
+		r.Route("/{org:[a-z]+}", func(r chi.Router) {
+			r.Get("/", orgs.Get)
+			r.With(mw).Get("/files/{name}.{ext:png|jpg}", listFiles)
+		})
+	})
+	r.Method("POST", "/files/*", http.HandlerFunc(listFiles))
+	r.Mount("/debug", http.DefaultServeMux)
+	return r
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var search http.HandlerFunc
+
+func article(w http.ResponseWriter, r *http.Request) {}
+
+func gorillaRouter() *mux.Router {
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/articles/{category}/{id:[0-9]+}", article).Methods("GET", "PUT")
+	api.HandleFunc("/search", search).Queries("q", "{q}", "page", "{page:[0-9]+}")
+	api.HandleFunc("/feed", article).Queries("format", "rss")
+	r.HandleFunc("/assets/{path:.*}", article)
+	r.Host("{sub}.example.com").HandlerFunc(article)
+	r.PathPrefix("/static/").Handler(http.FileServer(http.Dir(".")))
+	return r
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func index(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {}
+
+func user(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {}
+
+func health(w http.ResponseWriter, r *http.Request) {}
+
+func newRouter() *httprouter.Router {
+	router := httprouter.New()
+	router.GET("/", index)
+	router.GET("/users/:user", user)
+	router.HEAD("/users/:user", user)
+	router.Handle(http.MethodDelete, "/users/:user-id", user)
+	router.Handler("GET", "/src/*filepath", http.FileServer(http.Dir(".")))
+	router.HandlerFunc("GET", "/health", health)
+	router.ServeFiles("/static/*filepath", http.Dir("static"))
+	return router
+}
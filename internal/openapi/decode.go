@@ -0,0 +1,485 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// yamlJSON returns the JSON form of a YAML document, supporting the subset of
+// YAML written for OpenAPI documents: block mappings and sequences, flow
+// collections, plain, quoted and block scalars, and comments. Anchors, aliases,
+// tags and streams of more than one document are not supported.
+func yamlJSON(data []byte) ([]byte, error) {
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	d := &yamlDecoder{lines: strings.Split(text, "\n")}
+	d.skip()
+	if d.i < len(d.lines) && d.indent() != 0 {
+		return nil, d.errorf(`document must not be indented`)
+	}
+	v, err := d.node(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.skip(); d.i < len(d.lines) {
+		return nil, d.errorf(`unexpected %q`, d.text())
+	}
+	return json.Marshal(v)
+}
+
+// yamlDecoder decodes the lines of a YAML document, where i is the line being
+// decoded.
+type yamlDecoder struct {
+	lines []string
+	i     int
+}
+
+func (d *yamlDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(`yaml: line %d: %v`, d.i+1, fmt.Sprintf(format, args...))
+}
+
+// skip advances past blank lines, comments, directives and document markers.
+func (d *yamlDecoder) skip() {
+	for ; d.i < len(d.lines); d.i++ {
+		switch text := d.text(); {
+		case text == ``, text == `---`, text == `...`:
+		case d.indent() == 0 && strings.HasPrefix(text, `%`):
+		default:
+			return
+		}
+	}
+}
+
+// indent returns the number of spaces indenting the current line.
+func (d *yamlDecoder) indent() int {
+	line := d.lines[d.i]
+	return len(line) - len(strings.TrimLeft(line, ` `))
+}
+
+// text returns the current line without its indent, comment or trailing space.
+func (d *yamlDecoder) text() string {
+	return uncomment(strings.TrimLeft(d.lines[d.i], ` `))
+}
+
+// node returns the value beginning at the current line when it is indented by
+// at least min spaces, or nil.
+func (d *yamlDecoder) node(min int) (interface{}, error) {
+	if d.skip(); d.i >= len(d.lines) || d.indent() < min {
+		return nil, nil
+	}
+	text, ind := d.text(), d.indent()
+	switch {
+	case strings.HasPrefix(d.lines[d.i][ind:], "\t"):
+		return nil, d.errorf(`tabs may not indent`)
+	case text == `-` || strings.HasPrefix(text, `- `):
+		return d.seq(ind)
+	}
+	if _, _, ok := splitKey(text); ok {
+		return d.mapping(ind)
+	}
+	return d.value(ind, text)
+}
+
+// seq returns the block sequence of the items indented by ind spaces.
+func (d *yamlDecoder) seq(ind int) (interface{}, error) {
+	out := []interface{}{}
+	for d.skip(); d.i < len(d.lines) && d.indent() == ind; d.skip() {
+		text := d.text()
+		if text != `-` && !strings.HasPrefix(text, `- `) {
+			break
+		}
+		if text == `-` {
+			d.i++
+			v, err := d.node(ind + 1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+
+		// the item begins after the dash, as if it were on a line of its own
+		line := d.lines[d.i][ind+1:]
+		col := ind + 1 + len(line) - len(strings.TrimLeft(line, ` `))
+		d.lines[d.i] = strings.Repeat(` `, col) + strings.TrimLeft(line, ` `)
+		v, err := d.node(col)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// mapping returns the block mapping of the keys indented by ind spaces.
+func (d *yamlDecoder) mapping(ind int) (interface{}, error) {
+	out := make(map[string]interface{})
+	for d.skip(); d.i < len(d.lines) && d.indent() == ind; d.skip() {
+		key, rest, ok := splitKey(d.text())
+		if !ok {
+			return nil, d.errorf(`expected a mapping key, got %q`, d.text())
+		}
+		if _, dup := out[key]; dup {
+			return nil, d.errorf(`mapping key %q is declared more than once`, key)
+		}
+
+		var v interface{}
+		var err error
+		switch {
+		case rest == ``:
+			d.i++
+			if d.skip(); d.i < len(d.lines) && d.indent() == ind &&
+				(d.text() == `-` || strings.HasPrefix(d.text(), `- `)) {
+				v, err = d.seq(ind) // a sequence may share the indent of its key
+			} else {
+				v, err = d.node(ind + 1)
+			}
+		case rest[0] == '|' || rest[0] == '>':
+			d.i++
+			v, err = d.block(ind, rest)
+		default:
+			v, err = d.value(ind, rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// value returns the scalar or flow collection text of the current line, which
+// may continue on the following lines indented by more than ind spaces.
+func (d *yamlDecoder) value(ind int, text string) (interface{}, error) {
+	if strings.IndexByte(`&*!`, text[0]) >= 0 {
+		return nil, d.errorf(`anchors, aliases and tags are not supported`)
+	}
+	d.i++
+	more := func() bool {
+		d.skip()
+		return d.i < len(d.lines) && d.indent() > ind
+	}
+	switch text[0] {
+	case '[', '{':
+		for !closed(text) && more() {
+			text += ` ` + d.text()
+			d.i++
+		}
+		v, n, err := flow(text, 0)
+		if err == nil && strings.TrimSpace(text[n:]) != `` {
+			err = fmt.Errorf(`unexpected %q`, text[n:])
+		}
+		if err != nil {
+			d.i--
+			return nil, d.errorf(`%v`, err)
+		}
+		return v, nil
+	case '"', '\'':
+		for !closed(text) && d.i < len(d.lines) {
+			text += ` ` + strings.TrimSpace(d.lines[d.i])
+			d.i++
+		}
+		v, n, err := quoted(text, 0)
+		if err == nil && strings.TrimSpace(text[n:]) != `` {
+			err = fmt.Errorf(`unexpected %q`, text[n:])
+		}
+		if err != nil {
+			d.i--
+			return nil, d.errorf(`%v`, err)
+		}
+		return v, nil
+	}
+	// a plain scalar folds the lines which continue it into spaces
+	for more() {
+		if _, _, ok := splitKey(d.text()); ok {
+			return nil, d.errorf(`mapping values are not allowed here`)
+		}
+		text += ` ` + d.text()
+		d.i++
+	}
+	return plain(text), nil
+}
+
+// block returns the literal or folded block scalar following a key indented by
+// ind spaces, given the header such as |, >- or |+ following the key.
+func (d *yamlDecoder) block(ind int, header string) (interface{}, error) {
+	folded, chomp := header[0] == '>', byte(0)
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			// the indent is found from the first line instead
+		default:
+			return nil, d.errorf(`invalid block scalar header %q`, header)
+		}
+	}
+
+	var lines []string
+	content := -1
+	for ; d.i < len(d.lines); d.i++ {
+		line := d.lines[d.i]
+		if strings.TrimSpace(line) == `` {
+			lines = append(lines, ``)
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, ` `))
+		if content < 0 {
+			content = n
+		}
+		if n <= ind || n < content {
+			break
+		}
+		lines = append(lines, line[content:])
+	}
+
+	var trail int
+	for trail < len(lines) && lines[len(lines)-1-trail] == `` {
+		trail++
+	}
+	body := lines[:len(lines)-trail]
+	more := func(line string) bool { return strings.HasPrefix(line, ` `) }
+	var b strings.Builder
+	for i, line := range body {
+		prev := ``
+		if i > 0 {
+			prev = body[i-1]
+		}
+		switch {
+		case i == 0:
+		case !folded, prev == ``, more(prev), more(line):
+			b.WriteByte('\n')
+		case line != ``:
+			b.WriteByte(' ')
+		default:
+			// the break before blank lines is folded away, unless they precede
+			// a more indented line
+			j := i
+			for j < len(body) && body[j] == `` {
+				j++
+			}
+			if j < len(body) && more(body[j]) {
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(line)
+	}
+	switch {
+	case len(body) == 0:
+	case chomp == '-':
+	case chomp == '+':
+		b.WriteString(strings.Repeat("\n", trail+1))
+	default:
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// splitKey returns the key and the remaining text of a line holding a mapping
+// key, or false.
+func splitKey(text string) (key, rest string, ok bool) {
+	switch {
+	case text == ``, text[0] == '[', text[0] == '{', text[0] == '#':
+		return ``, ``, false
+	case text[0] == '"' || text[0] == '\'':
+		v, n, err := quoted(text, 0)
+		if err != nil || n == len(text) || text[n] != ':' {
+			return ``, ``, false
+		}
+		rest = text[n+1:]
+		if rest != `` && rest[0] != ' ' {
+			return ``, ``, false
+		}
+		return v, strings.TrimSpace(rest), true
+	}
+	if i := strings.Index(text, `: `); i >= 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:]), true
+	}
+	if strings.HasSuffix(text, `:`) {
+		return strings.TrimSpace(text[:len(text)-1]), ``, true
+	}
+	return ``, ``, false
+}
+
+// uncomment returns text without its trailing comment or space, where a comment
+// begins with a # at the start of the text or following a space.
+func uncomment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:", text[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+	return strings.TrimRight(text, " \t")
+}
+
+// closed returns true if the quoted scalar or flow collection beginning text
+// ends within it.
+func closed(text string) bool {
+	if text[0] == '"' || text[0] == '\'' {
+		_, _, err := quoted(text, 0)
+		return err == nil
+	}
+	_, _, err := flow(text, 0)
+	return err == nil
+}
+
+// flow returns the value of the flow collection or scalar at i of s along with
+// the offset following it.
+func flow(s string, i int) (interface{}, int, error) {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if i == len(s) {
+		return nil, i, fmt.Errorf(`unexpected end of flow collection`)
+	}
+	switch s[i] {
+	case '"', '\'':
+		return quoted(s, i)
+	case '[', '{':
+		open, end := s[i], byte(']')
+		if open == '{' {
+			end = '}'
+		}
+		seq, m := []interface{}{}, make(map[string]interface{})
+		for i++; ; {
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			if i < len(s) && s[i] == end {
+				if open == '[' {
+					return seq, i + 1, nil
+				}
+				return m, i + 1, nil
+			}
+			v, n, err := flow(s, i)
+			if err != nil {
+				return nil, n, err
+			}
+			for i = n; i < len(s) && s[i] == ' '; i++ {
+			}
+			if open == '{' {
+				key, ok := v.(string)
+				if !ok || i == len(s) || s[i] != ':' {
+					return nil, i, fmt.Errorf(`expected a key followed by a colon`)
+				}
+				if v, i, err = flow(s, i+1); err != nil {
+					return nil, i, err
+				}
+				m[key] = v
+			} else {
+				seq = append(seq, v)
+			}
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			switch {
+			case i == len(s):
+				return nil, i, fmt.Errorf(`unexpected end of flow collection`)
+			case s[i] == ',':
+				i++
+			case s[i] != end:
+				return nil, i, fmt.Errorf(`unexpected %q within flow collection`, s[i])
+			}
+		}
+	}
+
+	// a plain scalar within a flow collection ends at an indicator
+	beg := i
+	for i < len(s) && strings.IndexByte(`,[]{}`, s[i]) < 0 &&
+		!(s[i] == ':' && (i+1 == len(s) || strings.IndexByte(` ,]}`, s[i+1]) >= 0)) {
+		i++
+	}
+	return plain(strings.TrimSpace(s[beg:i])), i, nil
+}
+
+// quoted returns the value of the single or double quoted scalar at i of s
+// along with the offset following it.
+func quoted(s string, i int) (string, int, error) {
+	q := s[i]
+	var b strings.Builder
+	for i++; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == q:
+			return b.String(), i + 1, nil
+		case c == '\\' && q == '"':
+			if i++; i == len(s) {
+				return ``, i, fmt.Errorf(`unterminated escape`)
+			}
+			switch e := s[i]; e {
+			case 'x', 'u', 'U':
+				n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+				if i+n >= len(s) {
+					return ``, i, fmt.Errorf(`invalid escape \%c`, e)
+				}
+				r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return ``, i, fmt.Errorf(`invalid escape \%c%v`, e, s[i+1:i+1+n])
+				}
+				b.WriteRune(rune(r))
+				i += n
+			default:
+				r, ok := yamlEscapes[e]
+				if !ok {
+					return ``, i, fmt.Errorf(`invalid escape \%c`, e)
+				}
+				b.WriteString(r)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return ``, i, fmt.Errorf(`unterminated quoted scalar`)
+}
+
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v",
+	'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': `"`, '/': `/`, '\\': `\`,
+	'N': "\u0085", '_': " ", 'L': " ", 'P': " ",
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// plain returns the value of a plain scalar under the YAML core schema, where
+// numbers keep their form as a json.Number when JSON allows it.
+func plain(s string) interface{} {
+	switch s {
+	case ``, `~`, `null`, `Null`, `NULL`:
+		return nil
+	case `true`, `True`, `TRUE`:
+		return true
+	case `false`, `False`, `FALSE`:
+		return false
+	}
+	switch {
+	case yamlInt.MatchString(s):
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	case yamlFloat.MatchString(s):
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// JSON returns the document as indented JSON.
+func (d *Document) JSON() ([]byte, error) {
+	b, err := json.MarshalIndent(d, ``, `  `)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// YAML returns the document as YAML with fields in the same order as JSON.
+func (d *Document) YAML() ([]byte, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	n, err := decode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for i, key := range n.keys {
+		buf.WriteString(yamlString(key) + `:`)
+		n.vals[i].yaml(&buf, 0)
+	}
+	return buf.Bytes(), nil
+}
+
+// node is a JSON value which retains the order of object keys.
+type node struct {
+	scalar string // encoded scalar, empty for objects and arrays
+	array  bool
+	keys   []string
+	vals   []*node // object values or array items
+}
+
+func decode(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		n := &node{array: v == '['}
+		for dec.More() {
+			if !n.array {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			val, err := decode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.vals = append(n.vals, val)
+		}
+		_, err = dec.Token() // closing delim
+		return n, err
+	case string:
+		return &node{scalar: yamlString(v)}, nil
+	case json.Number:
+		return &node{scalar: v.String()}, nil
+	case bool:
+		if v {
+			return &node{scalar: `true`}, nil
+		}
+		return &node{scalar: `false`}, nil
+	default:
+		return &node{scalar: `null`}, nil
+	}
+}
+
+// yaml writes the node following a key or sequence indicator at the given
+// indentation.
+func (n *node) yaml(buf *bytes.Buffer, indent int) {
+	pad := strings.Repeat(` `, indent+2)
+	switch {
+	case n.scalar != ``:
+		buf.WriteString(` ` + n.scalar + "\n")
+	case len(n.vals) == 0 && n.array:
+		buf.WriteString(" []\n")
+	case len(n.vals) == 0:
+		buf.WriteString(" {}\n")
+	case n.array:
+		buf.WriteString("\n")
+		for _, item := range n.vals {
+			buf.WriteString(pad + `-`)
+			if item.array || item.scalar != `` || len(item.vals) == 0 {
+				item.yaml(buf, indent+2)
+				continue
+			}
+			for i, key := range item.keys {
+				if i == 0 {
+					buf.WriteString(` `)
+				} else {
+					buf.WriteString(pad + `  `)
+				}
+				buf.WriteString(yamlString(key) + `:`)
+				item.vals[i].yaml(buf, indent+4)
+			}
+		}
+	default:
+		buf.WriteString("\n")
+		for i, key := range n.keys {
+			buf.WriteString(pad + yamlString(key) + `:`)
+			n.vals[i].yaml(buf, indent+2)
+		}
+	}
+}
+
+var plainRe = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./{}-]*$`)
+
+// yamlString returns s as a plain scalar when it can not be mistaken for
+// another type or syntax, otherwise as a double quoted scalar.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case `true`, `false`, `yes`, `no`, `on`, `off`, `null`, `y`, `n`:
+	default:
+		if plainRe.MatchString(s) {
+			return s
+		}
+	}
+	b, _ := json.Marshal(s)
+	return string(b)
+}
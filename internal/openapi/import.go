@@ -0,0 +1,606 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	gofmt "go/format"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/format"
+	"github.com/cstockton/routepiler/internal/parser"
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+// importDoc is the subset of an OpenAPI 3 document read by Import.
+type importDoc struct {
+	OpenAPI string `json:"openapi"`
+	Info    Info   `json:"info"`
+
+	// each path item maps http methods to operations, with the key parameters
+	// holding the params shared by each operation
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+
+	Components struct {
+		Parameters map[string]*importParam  `json:"parameters"`
+		Schemas    map[string]*importSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type importOperation struct {
+	OperationID string         `json:"operationId"`
+	Parameters  []*importParam `json:"parameters"`
+}
+
+type importParam struct {
+	Ref      string        `json:"$ref"`
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required"`
+	Schema   *importSchema `json:"schema"`
+}
+
+// importSchema is the subset of a schema read by Import. Unlike Schema it
+// accepts the forms written by OpenAPI 3.0 and 3.1 documents which routes never
+// generate, such as a list of types or a fractional minimum.
+type importSchema struct {
+	Ref       string      `json:"$ref"`
+	Type      schemaType  `json:"type"`
+	Format    string      `json:"format"`
+	Pattern   string      `json:"pattern"`
+	MinLength *int        `json:"minLength"`
+	MaxLength *int        `json:"maxLength"`
+	Minimum   *float64    `json:"minimum"`
+	Maximum   *float64    `json:"maximum"`
+	Default   interface{} `json:"default"`
+}
+
+// schemaType is the type of a schema, given as a name or a list of names such
+// as ["string", "null"] of which the first besides null is used.
+type schemaType string
+
+func (t *schemaType) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		var name string
+		if err = json.Unmarshal(b, &name); err != nil {
+			return err
+		}
+		names = []string{name}
+	}
+	for _, name := range names {
+		if name != `null` {
+			*t = schemaType(name)
+			return nil
+		}
+	}
+	*t = ``
+	return nil
+}
+
+// str returns true if s is nil or describes a string.
+func (s *importSchema) str() bool {
+	return s == nil || s.Type == `` || s.Type == `string`
+}
+
+// importOp is a single operation converted to a route.
+type importOp struct {
+	name    string // Go type name
+	method  string
+	path    string // OpenAPI path
+	pattern string
+	fields  []importField
+	notes   []string // params which could not be converted
+}
+
+type importField struct {
+	name, typ, tag string
+}
+
+// Import returns the Go source of a router struct for the paths of an OpenAPI
+// 3 document in JSON or YAML form. Each operation becomes a struct type with a
+// field for each param and a method stub which serves it, declared as a field
+// of the router with a route tag. The pattern of each route is built from the
+// path template and the pattern, minLength and maxLength keywords of each param
+// schema, where a pattern keeps its meaning as an unanchored ECMA regexp, then
+// verified to scan and parse to the same path. Params and schema patterns which
+// can not be expressed in a pattern are reported in comments above their route.
+func Import(data []byte, pkg, router string) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte(`{`)) {
+		var err error
+		if data, err = yamlJSON(data); err != nil {
+			return nil, err
+		}
+	}
+	var doc importDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.OpenAPI, `3.`) {
+		return nil, fmt.Errorf(`unsupported OpenAPI version %q`, doc.OpenAPI)
+	}
+
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var ops []*importOp
+	names := map[string]bool{router: true}
+	for _, path := range paths {
+		item := doc.Paths[path]
+		var shared []*importParam
+		if raw, ok := item[`parameters`]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf(`%v: %v`, path, err)
+			}
+		}
+
+		for _, method := range source.Methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op importOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf(`%v %v: %v`, strings.ToUpper(method), path, err)
+			}
+			params, err := doc.params(shared, op.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf(`%v %v: %v`, strings.ToUpper(method), path, err)
+			}
+
+			io, err := convert(path, params)
+			if err != nil {
+				return nil, fmt.Errorf(`%v %v: %v`, strings.ToUpper(method), path, err)
+			}
+			io.method = method
+			io.name = unique(names, op.OperationID, method+` `+path)
+			ops = append(ops, io)
+		}
+	}
+	return render(&doc, pkg, router, ops)
+}
+
+// params returns the params of an operation with each reference resolved, the
+// operation params override shared params of the same name and location.
+func (doc *importDoc) params(shared, own []*importParam) ([]*importParam, error) {
+	var out []*importParam
+	for _, p := range append(shared[:len(shared):len(shared)], own...) {
+		p, err := doc.param(p)
+		if err != nil {
+			return nil, err
+		}
+		for i := range out {
+			if out[i] != nil && out[i].Name == p.Name && out[i].In == p.In {
+				out[i] = nil
+			}
+		}
+		out = append(out, p)
+	}
+
+	params := out[:0]
+	for _, p := range out {
+		if p != nil {
+			params = append(params, p)
+		}
+	}
+	return params, nil
+}
+
+func (doc *importDoc) param(p *importParam) (*importParam, error) {
+	if p.Ref != `` {
+		name := strings.TrimPrefix(p.Ref, `#/components/parameters/`)
+		if p = doc.Components.Parameters[name]; p == nil {
+			return nil, fmt.Errorf(`unresolved parameter reference %v`, name)
+		}
+	}
+	if p.Schema != nil && p.Schema.Ref != `` {
+		name := strings.TrimPrefix(p.Schema.Ref, `#/components/schemas/`)
+		s := doc.Components.Schemas[name]
+		if s == nil {
+			return nil, fmt.Errorf(`unresolved schema reference %v`, name)
+		}
+		cp := *p
+		cp.Schema, p = s, &cp
+	}
+	return p, nil
+}
+
+// convert returns the route pattern and fields for the params of a path.
+func convert(path string, params []*importParam) (*importOp, error) {
+	io := &importOp{path: path}
+	byName := make(map[string]*importParam)
+	for i, p := range params {
+		if p.In != `path` && p.In != `query` {
+			continue
+		}
+		if re := patternOf(p.Schema); re != `` {
+			if _, err := regexp.Compile(re); err != nil {
+				io.notes = append(io.notes, fmt.Sprintf(`%v param %q pattern %q is dropped: %v`,
+					p.In, p.Name, p.Schema.Pattern, err))
+				s := *p.Schema
+				s.Pattern = ``
+				cp := *p
+				cp.Schema, p = &s, &cp
+				params[i] = p
+			}
+		}
+		if p.In == `path` {
+			byName[p.Name] = p
+		}
+	}
+
+	var buf, exp bytes.Buffer
+	for _, seg := range strings.Split(strings.TrimPrefix(path, `/`), `/`) {
+		buf.WriteByte('/')
+		exp.WriteByte('/')
+
+		parts := splitTemplate(seg)
+		for _, part := range parts {
+			if part.param == `` {
+				// a colon within a literal would begin a param, as in `/items:batch`
+				lit := strings.ReplaceAll(part.lit, `:`, `%3A`)
+				buf.WriteString(lit)
+				exp.WriteString(lit)
+				continue
+			}
+			p := byName[part.param]
+			if p == nil {
+				p = &importParam{Name: part.param, In: `path`}
+			}
+
+			name := source.ParamName(p.Name)
+			if name == `` {
+				name = `param`
+			}
+			if name != p.Name {
+				io.notes = append(io.notes,
+					fmt.Sprintf(`path param %q is renamed to %q`, p.Name, name))
+			}
+			if len(parts) == 1 {
+				buf.WriteString(`:` + name + regexpOf(p.Schema) + suffix(p.Schema))
+			} else {
+				buf.WriteString(brace(name, p.Schema))
+			}
+			exp.WriteString(`{` + name + `}`)
+			io.fields = append(io.fields, fieldOf(name, p.Schema))
+		}
+	}
+
+	sep := `?`
+	for _, p := range params {
+		switch {
+		case p.In == `path`:
+		case p.In != `query`:
+			io.notes = append(io.notes,
+				fmt.Sprintf(`%v param %q is not supported`, p.In, p.Name))
+		case source.ParamName(p.Name) != p.Name:
+			io.notes = append(io.notes,
+				fmt.Sprintf(`query param %q is not a valid param name`, p.Name))
+		default:
+			buf.WriteString(sep + p.Name + query(p))
+			io.fields = append(io.fields, fieldOf(p.Name, p.Schema))
+			sep = `&`
+		}
+	}
+
+	pattern, err := format.Pattern(buf.String())
+	if err != nil {
+		return nil, fmt.Errorf(`invalid pattern %q: %v`, buf.String(), err)
+	}
+	r, err := parser.Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid pattern %q: %v`, pattern, err)
+	}
+	if got := pathOf(r.Path); got != exp.String() {
+		return nil, fmt.Errorf(`pattern %q matches path %v, want %v`,
+			pattern, got, exp.String())
+	}
+	io.pattern = pattern
+	return io, nil
+}
+
+type templatePart struct {
+	lit, param string
+}
+
+// splitTemplate splits a segment of an OpenAPI path template into literals and
+// {param} expressions.
+func splitTemplate(seg string) (out []templatePart) {
+	for seg != `` {
+		beg := strings.IndexByte(seg, '{')
+		end := strings.IndexByte(seg, '}')
+		if beg < 0 || end < beg {
+			return append(out, templatePart{lit: seg})
+		}
+		if beg > 0 {
+			out = append(out, templatePart{lit: seg[:beg]})
+		}
+		out = append(out, templatePart{param: seg[beg+1 : end]})
+		seg = seg[end+1:]
+	}
+	return
+}
+
+// unique returns the exported Go name for an operation which is not yet used.
+func unique(names map[string]bool, id, fallback string) string {
+	name := source.ExportedName(id)
+	if name == `` {
+		name = source.ExportedName(fallback)
+	}
+	for i := 2; names[name]; i++ {
+		name = strings.TrimRight(name, `0123456789`) + strconv.Itoa(i)
+	}
+	names[name] = true
+	return name
+}
+
+// patternOf returns the regexp of a string schema which matches a whole path
+// segment. A pattern is not implicitly anchored as a param regexp is, so any end
+// of it which is not anchored by ^ or $ matches anything with .* instead.
+func patternOf(s *importSchema) string {
+	if s == nil || s.Pattern == `` || !s.str() {
+		return ``
+	}
+	re := s.Pattern
+	if alternates(re) {
+		// the anchors only apply to some branches, as in `^a|b$`
+		return `.*(?:` + re + `).*`
+	}
+
+	lead := strings.HasPrefix(re, `^`)
+	if lead {
+		re = re[1:]
+	}
+	trail := strings.HasSuffix(re, `$`) && !escaped(re, len(re)-1)
+	if trail {
+		re = re[:len(re)-1]
+	}
+	switch {
+	case lead && trail && strings.HasPrefix(re, `(?:`) && strings.HasSuffix(re, `)`) &&
+		balanced(re[3:len(re)-1]):
+		re = re[3 : len(re)-1]
+	case !lead && !trail:
+		re = `.*` + re + `.*`
+	case !lead:
+		re = `.*` + re
+	case !trail:
+		re += `.*`
+	}
+	return re
+}
+
+// alternates returns true if re has a top level alternation.
+func alternates(re string) bool {
+	var depth int
+	var class bool
+	for i := 0; i < len(re); i++ {
+		switch c := re[i]; {
+		case c == '\\':
+			i++
+		case class:
+			class = c != ']'
+		case c == '[':
+			class = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			return true
+		}
+	}
+	return false
+}
+
+// escaped returns true if the byte at i of re is escaped by a backslash.
+func escaped(re string, i int) bool {
+	n := 0
+	for i > 0 && re[i-1] == '\\' {
+		n, i = n+1, i-1
+	}
+	return n%2 == 1
+}
+
+func balanced(s string) bool {
+	var depth int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+func regexpOf(s *importSchema) string {
+	if re := patternOf(s); re != `` {
+		return `(` + format.Quote(re) + `)`
+	}
+	return ``
+}
+
+// lengths returns the length bounds of a string schema.
+func lengths(s *importSchema) (min, max int) {
+	if s == nil || !s.str() {
+		return 0, 0
+	}
+	if s.MinLength != nil {
+		min = *s.MinLength
+	}
+	if s.MaxLength != nil {
+		max = *s.MaxLength
+	}
+	return
+}
+
+// suffix returns the template following a param for the given schema and
+// template pairs, using the short form of {min-max} when possible.
+func suffix(s *importSchema, pairs ...string) string {
+	min, max := lengths(s)
+	if len(pairs) == 0 {
+		switch {
+		case min > 0 && max > 0:
+			return fmt.Sprintf(`{%d-%d}`, min, max)
+		case max > 0:
+			return fmt.Sprintf(`{%d}`, max)
+		}
+	}
+	if max > 0 {
+		pairs = append([]string{`max: ` + strconv.Itoa(max)}, pairs...)
+	}
+	if min > 0 {
+		pairs = append([]string{`min: ` + strconv.Itoa(min)}, pairs...)
+	}
+	if len(pairs) == 0 {
+		return ``
+	}
+	return `{` + strings.Join(pairs, `, `) + `}`
+}
+
+// brace returns a param declared entirely within a template.
+func brace(name string, s *importSchema) string {
+	t := suffix(s, `name: `+name)
+	if re := patternOf(s); re != `` {
+		t = t[:len(t)-1] + `, regex: ` + format.Quote(re) + `}`
+	}
+	if t == `{name: `+name+`}` {
+		return `{` + name + `}`
+	}
+	return t
+}
+
+// query returns the template following a query param.
+func query(p *importParam) string {
+	var pairs []string
+	if re := patternOf(p.Schema); re != `` {
+		pairs = append(pairs, `regex: `+format.Quote(re))
+	}
+	if p.Required {
+		pairs = append(pairs, `required: true`)
+	}
+	if p.Schema != nil && p.Schema.Default != nil {
+		pairs = append(pairs, `default: `+format.Quote(fmt.Sprint(p.Schema.Default)))
+	}
+	return suffix(p.Schema, pairs...)
+}
+
+// fieldOf returns the struct field bound to a param.
+func fieldOf(name string, s *importSchema) importField {
+	f := importField{name: source.ExportedName(name), typ: `string`}
+	if s == nil {
+		return f
+	}
+	switch s.Type {
+	case `boolean`:
+		f.typ = `bool`
+	case `integer`:
+		f.typ = `int`
+		if s.Format == `int32` || s.Format == `int64` {
+			f.typ = s.Format
+		}
+	case `number`:
+		f.typ = `float64`
+		if s.Format == `float` {
+			f.typ = `float32`
+		}
+	case ``, `string`:
+		switch s.Format {
+		case `date-time`:
+			f.typ = `time.Time`
+		case `duration`:
+			f.typ = `time.Duration`
+		}
+	}
+
+	var ps tag.Pairs
+	if s.Minimum != nil {
+		ps = append(ps, tag.Pair{Key: `min`, Value: bound(f.typ, *s.Minimum, math.Ceil)})
+	}
+	if s.Maximum != nil {
+		ps = append(ps, tag.Pair{Key: `max`, Value: bound(f.typ, *s.Maximum, math.Floor)})
+	}
+	if len(ps) > 0 {
+		f.tag = tag.Quote(ps.String())
+	}
+	return f
+}
+
+// bound returns the min or max tag of a field of type typ for a schema bound,
+// rounding fractional bounds of integer fields to the nearest integer within.
+func bound(typ string, v float64, round func(float64) float64) string {
+	if strings.HasPrefix(typ, `float`) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(round(v), 'f', -1, 64)
+}
+
+func render(doc *importDoc, pkg, router string, ops []*importOp) ([]byte, error) {
+	var buf bytes.Buffer
+	imports := `"net/http"`
+	for _, op := range ops {
+		if uses(op, `time.`) {
+			imports = "(\n\t\"net/http\"\n\t\"time\"\n)"
+			break
+		}
+	}
+	fmt.Fprintf(&buf, "package %v\n\nimport %v\n\n", pkg, imports)
+
+	title := doc.Info.Title
+	if title == `` {
+		title = `an OpenAPI document`
+	}
+	fmt.Fprintf(&buf, "// %v serves the paths of %v.\ntype %v struct {\n",
+		router, title, router)
+	for i, op := range ops {
+		if i > 0 && len(op.notes) > 0 {
+			buf.WriteString("\n")
+		}
+		for _, note := range op.notes {
+			fmt.Fprintf(&buf, "\t// %v\n", note)
+		}
+		ps := tag.Pairs{{Key: op.method, Value: op.pattern}}
+		fmt.Fprintf(&buf, "\t%v %v %v\n", op.name, op.name, tag.Quote(ps.String()))
+	}
+	buf.WriteString("}\n")
+
+	for _, op := range ops {
+		desc := strings.ToUpper(op.method) + ` ` + op.path
+		fmt.Fprintf(&buf, "\n// %v holds the params of %v.\ntype %v struct{",
+			op.name, desc, op.name)
+		if len(op.fields) > 0 {
+			buf.WriteString("\n")
+		}
+		for _, f := range op.fields {
+			fmt.Fprintf(&buf, "\t%v %v %v\n", f.name, f.typ, f.tag)
+		}
+		fmt.Fprintf(&buf, "}\n\n// %v serves %v.\n", source.MethodName(op.method), desc)
+		fmt.Fprintf(&buf, "func (h *%v) %v(w http.ResponseWriter, r *http.Request) {\n",
+			op.name, source.MethodName(op.method))
+		buf.WriteString("\thttp.Error(w, http.StatusText(http.StatusNotImplemented), " +
+			"http.StatusNotImplemented)\n}\n")
+	}
+	return gofmt.Source(buf.Bytes())
+}
+
+func uses(op *importOp, s string) bool {
+	for _, f := range op.fields {
+		if strings.Contains(f.typ, s) {
+			return true
+		}
+	}
+	return false
+}
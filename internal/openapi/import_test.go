@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportRoundTrip(t *testing.T) {
+	exp, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(`testdata`, `openapi.json`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	src, err := Import(b, `main`, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, src, 0)
+	if err != nil {
+		t.Fatalf("exp nil err; got %v from:\n%s", err, src)
+	}
+	got, err := Generate(fset, []*ast.File{f}, `Router`)
+	if err != nil {
+		t.Fatalf("exp nil err; got %v from:\n%s", err, src)
+	}
+
+	// operations are named after the generated handlers
+	for _, doc := range []*Document{exp, got} {
+		for _, item := range doc.Paths {
+			for _, m := range []string{`get`, `post`, `put`} {
+				if op := *item.operation(m); op != nil {
+					op.OperationID = ``
+				}
+			}
+		}
+	}
+	expJSON, _ := json.Marshal(exp.Paths)
+	gotJSON, _ := json.Marshal(got.Paths)
+	if string(expJSON) != string(gotJSON) {
+		t.Fatalf("exp paths:\n%s\ngot:\n%s\nfrom:\n%s", expJSON, gotJSON, src)
+	}
+}
+
+func TestImportYAML(t *testing.T) {
+	var srcs []string
+	for _, name := range []string{`openapi.json`, `openapi.yaml`} {
+		b, err := ioutil.ReadFile(filepath.Join(`testdata`, name))
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		src, err := Import(b, `main`, `Router`)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		srcs = append(srcs, string(src))
+	}
+	if exp, got := srcs[0], srcs[1]; exp != got {
+		t.Fatalf("exp import of yaml to equal json:\n%v\ngot:\n%v", exp, got)
+	}
+
+	tests := []struct {
+		in  string
+		exp string
+	}{
+		{"a: 1\nb: x # note\n# skipped\nc: 'it''s'\nd: \"\\u00e9\\n\"\n",
+			`{"a":1,"b":"x","c":"it's","d":"é\n"}`},
+		{"---\na:\n- 1\n- {b: [true, null], c: \"d, e\"}\n-\n  f: g\n",
+			`{"a":[1,{"b":[true,null],"c":"d, e"},{"f":"g"}]}`},
+		{"a: |\n  one\n    two\n\nb: >-\n  one\n  two\n\n  three\n",
+			`{"a":"one\n  two\n","b":"one two\nthree"}`},
+		{"a: one\n  two\nb: \"3\"\nc: 3.5\nd: \"\"\n",
+			`{"a":"one two","b":"3","c":3.5,"d":""}`},
+		{"a: *b\n", `yaml: line 1: anchors, aliases and tags are not supported`},
+		{"a: 1\na: 2\n", `yaml: line 2: mapping key "a" is declared more than once`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp yaml %q as json %v`, idx, test.in, test.exp)
+		b, err := yamlJSON([]byte(test.in))
+		got := string(b)
+		if err != nil {
+			got = err.Error()
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	doc := func(paths string) string {
+		return `{"openapi": "3.0.3", "paths": {` + paths + `}}`
+	}
+	get := func(path, params string) string {
+		return doc(`"` + path + `": {"get": {"parameters": [` + params + `]}}`)
+	}
+	tests := []struct {
+		in  string
+		exp []string
+	}{
+		{get(`/users/{id}`,
+			`{"name": "id", "in": "path", "schema": {"type": "integer", "format": "int64"}}`),
+			[]string{"GetUsersId GetUsersId `get:\"/users/:id\"`", `Id int64`,
+				`func (h *GetUsersId) Get(w http.ResponseWriter, r *http.Request) {`}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": `+
+			`{"type": "string", "pattern": "^[a-z]+$", "minLength": 2, "maxLength": 8}}`),
+			[]string{"`get:\"/users/:name([a-z]+){2-8}\"`"}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": `+
+			`{"pattern": "^(?:[a-z]+)$", "maxLength": 8}}`),
+			[]string{"`get:\"/users/:name([a-z]+){8}\"`"}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": {"minLength": 2}}`),
+			[]string{"`get:\"/users/:name{min: 2}\"`"}},
+		{get(`/files/{name}.{ext}`, `{"name": "ext", "in": "path", "schema": `+
+			`{"pattern": "(png)|(jpg)"}}`),
+			[]string{"`get:\"/files/{name}.{name: ext, regex: '.*(?:(png)|(jpg)).*'}\"`",
+				`Name string`, `Ext  string`}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": {"pattern": "[a-z]+"}}`),
+			[]string{"`get:\"/users/:name(.*[a-z]+.*)\"`"}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": {"pattern": "^v[0-9]"}}`),
+			[]string{"`get:\"/users/:name(v[0-9].*)\"`"}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": {"pattern": "\\.json\\$"}}`),
+			[]string{"`get:\"/users/:name(.*\\\\.json\\\\$.*)\"`"}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": {"pattern": "^a|b$"}}`),
+			[]string{"`get:\"/users/:name(.*(?:^a|b$).*)\"`"}},
+		{get(`/users/{id}`, `{"name": "id", "in": "path", "schema": `+
+			`{"type": ["integer", "null"], "minimum": 1.5, "maximum": 9.5}}`),
+			[]string{"Id int `min:\"2\" max:\"9\"`"}},
+		{get(`/users/{name}`, `{"name": "name", "in": "path", "schema": `+
+			`{"type": ["null", "string"], "pattern": "^[a-z]+$", "maxLength": 8}}`),
+			[]string{"`get:\"/users/:name([a-z]+){8}\"`"}},
+		{get(`/scores/{score}`, `{"name": "score", "in": "path", "schema": `+
+			`{"type": "number", "minimum": 0.5}}`),
+			[]string{"Score float64 `min:\"0.5\"`"}},
+		{get(`/items:batch`, ``), []string{"`get:\"/items%3Abatch\"`"}},
+		{get(`/users/{user-id}`, ``),
+			[]string{`// path param "user-id" is renamed to "userId"`,
+				"`get:\"/users/:userId\"`", `UserId string`}},
+		{get(`/search`,
+			`{"name": "q", "in": "query", "required": true, "schema": {"pattern": "^[a-z]+$"}},`+
+				`{"name": "n", "in": "query", "schema": {"type": "integer", "default": 10, `+
+				`"minimum": 1}},`+
+				`{"name": "X-Id", "in": "header"},`+
+				`{"name": "a.b", "in": "query"}`),
+			[]string{
+				"`get:\"/search?q{regex: '[a-z]+', required: true}&n{default: '10'}\"`",
+				`// header param "X-Id" is not supported`,
+				`// query param "a.b" is not a valid param name`,
+				"N int `min:\"1\"`", `Q string`}},
+		{get(`/{a}`, `{"name": "a", "in": "path", "schema": {"pattern": "[a-z"}},`+
+			`{"name": "q", "in": "query", "schema": {"pattern": "(?<=a)b"}}`),
+			[]string{`// path param "a" pattern "[a-z" is dropped: error parsing regexp: `,
+				`// query param "q" pattern "(?<=a)b" is dropped: error parsing regexp: `,
+				"`get:\"/:a?q\"`"}},
+		{"openapi: 3.0.3\npaths:\n  /users/{id}:\n    get:\n      operationId: getUser\n" +
+			"      parameters:\n        - name: id\n          in: path\n" +
+			"          schema: {type: integer, format: int64}\n",
+			[]string{"GetUser GetUser `get:\"/users/:id\"`", `Id int64`}},
+		{doc(`"/a": {"get": {"operationId": "list"}}, "/b": {"get": {"operationId": "list"}}`),
+			[]string{"List  List  `get:\"/a\"`", "List2 List2 `get:\"/b\"`"}},
+		{doc(`"/at/{when}": {"parameters": [{"$ref": "#/components/parameters/when"}], ` +
+			`"get": {}, "put": {"parameters": [{"name": "when", "in": "path", ` +
+			`"schema": {"$ref": "#/components/schemas/dur"}}]}}}, "components": {` +
+			`"parameters": {"when": {"name": "when", "in": "path", "schema": ` +
+			`{"type": "string", "format": "date-time"}}}, ` +
+			`"schemas": {"dur": {"type": "string", "format": "duration"}}`),
+			[]string{`When time.Time`, `When time.Duration`, `"time"`,
+				`func (h *PutAtWhen) Put(w http.ResponseWriter, r *http.Request) {`}},
+
+		// errors
+		{`{"swagger": "2.0"}`, []string{`unsupported OpenAPI version ""`}},
+		{"openapi: 3.0.3\npaths:\n  /a: &a\n", []string{`yaml: line 3: anchors, aliases and tags`}},
+		{get(`/{a}`, `{"$ref": "#/components/parameters/a"}`),
+			[]string{`GET /{a}: unresolved parameter reference a`}},
+		{get(`/{a}`, `{"name": "a", "in": "path", "schema": {"$ref": "#/components/schemas/a"}}`),
+			[]string{`GET /{a}: unresolved schema reference a`}},
+		{get(`/{a}{b}`, ``),
+			[]string{`GET /{a}{b}: invalid pattern "/{a}{b}": adjacent LBRACE at byte 4`}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp import of %v to contain %v`, idx, test.in, test.exp)
+		b, err := Import([]byte(test.in), `main`, `Router`)
+		got := string(b)
+		if err != nil {
+			got = err.Error()
+		}
+		for _, exp := range test.exp {
+			if !strings.Contains(got, exp) {
+				t.Fatalf("exp result to contain:\n%v\ngot:\n%v", exp, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,423 @@
+// Package openapi generates OpenAPI 3.1 documents from the route struct tags of
+// a router struct, including a test helper which fails when a checked in
+// document no longer matches the routes. It may also import the paths of an
+// OpenAPI 3 document as the skeleton of a router struct.
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/parser"
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = `3.1.0`
+
+// Document is an OpenAPI document containing the subset of fields which may be
+// derived from routes.
+type Document struct {
+	OpenAPI string               `json:"openapi"`
+	Info    Info                 `json:"info"`
+	Paths   map[string]*PathItem `json:"paths"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
+}
+
+// Operation returns the operation for the given http method, or nil.
+func (p *PathItem) Operation(method string) *Operation {
+	if op := p.operation(method); op != nil {
+		return *op
+	}
+	return nil
+}
+
+// operation returns the operation field for the given http method, or nil if
+// OpenAPI does not support the method.
+func (p *PathItem) operation(method string) **Operation {
+	switch method {
+	case `get`:
+		return &p.Get
+	case `head`:
+		return &p.Head
+	case `post`:
+		return &p.Post
+	case `put`:
+		return &p.Put
+	case `patch`:
+		return &p.Patch
+	case `delete`:
+		return &p.Delete
+	case `options`:
+		return &p.Options
+	case `trace`:
+		return &p.Trace
+	}
+	return nil
+}
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters,omitempty"`
+
+	// Any is true for a route serving any http method, which OpenAPI can not
+	// describe. Omits are the names of the optional params omitted from the
+	// path of an operation expanded from a pattern declaring them.
+	Any   bool     `json:"-"`
+	Omits []string `json:"-"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref,omitempty"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+
+	// Wild is true for a path param matching multiple path segments and
+	// Optional for a path param which may be omitted along with the segments
+	// following it, which OpenAPI can not describe.
+	Wild     bool `json:"-"`
+	Optional bool `json:"-"`
+}
+
+type Schema struct {
+	Ref       string      `json:"$ref,omitempty"`
+	Type      string      `json:"type"`
+	Format    string      `json:"format,omitempty"`
+	Pattern   string      `json:"pattern,omitempty"`
+	MinLength *int        `json:"minLength,omitempty"`
+	MaxLength *int        `json:"maxLength,omitempty"`
+	Minimum   *int64      `json:"minimum,omitempty"`
+	Maximum   *int64      `json:"maximum,omitempty"`
+	Default   interface{} `json:"default,omitempty"`
+}
+
+// Load parses the non-test Go files within dir and returns the document for the
+// named router struct.
+func Load(dir, router string) (*Document, error) {
+	fset := token.NewFileSet()
+	_, files, err := source.ParseDir(fset, dir, router)
+	if err != nil {
+		return nil, err
+	}
+	return Generate(fset, files, router)
+}
+
+// Generate returns the document for the named router struct declared within the
+// given files of a single package. Each route resolved by source.Package.Routes
+// becomes an operation named after its handler, with a parameter for each param
+// of the route pattern. OpenAPI can not describe a route for any method, so it
+// becomes a get operation unless the route field also serves specific methods,
+// and methods such as connect are omitted unless named by the route tag.
+//
+// OpenAPI has no optional path params, so a pattern declaring them becomes a
+// path for each route it expands to, as by analyze.Expand. The operation of a
+// path omitting optional params is named with a Without suffix followed by the
+// names of the params it omits, and a param with a default documents it.
+func Generate(fset *token.FileSet, files []*ast.File, router string) (*Document, error) {
+	pkg := source.New(files)
+	st := pkg.Structs[router]
+	if st == nil {
+		return nil, fmt.Errorf(`router struct %v not found`, router)
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: router, Version: `0.0.0`},
+		Paths:   make(map[string]*PathItem),
+	}
+	ids := make(map[string]bool)
+	for _, fd := range st.Fields.List {
+		if fd.Tag == nil {
+			continue
+		}
+		pos := fset.Position(fd.Tag.Pos())
+		str, err := tag.Unquote(fd.Tag.Value)
+		if err != nil {
+			return nil, fmt.Errorf(`%v: %v`, pos, err)
+		}
+		ps, err := tag.Parse(str)
+		if err != nil {
+			continue // tags which are not for routes are not our concern
+		}
+
+		for _, p := range ps.Routes() {
+			r, err := parser.Parse(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf(`%v: invalid %v pattern: %v`, pos, p.Key, err)
+			}
+
+			rts, err := pkg.Routes(fd, ps, p)
+			if err != nil {
+				return nil, fmt.Errorf(`%v: %v`, pos, err)
+			}
+			paths, omits := analyze.Expand(r)
+			for i, segs := range paths {
+				path := pathOf(segs)
+				item := doc.Paths[path]
+				if item == nil {
+					item = new(PathItem)
+					doc.Paths[path] = item
+				}
+				for _, rt := range rts {
+					method := rt.Method
+					if method == `` {
+						// OpenAPI has no operation for any method
+						if len(rts) > 1 {
+							continue
+						}
+						method = `get`
+					}
+					op := item.operation(method)
+					if op == nil && source.Method(ps, p) == `` {
+						continue // such as the Connect method of a path route
+					}
+					id := strings.Replace(rt.Handler.Name, `.`, ``, -1)
+					if len(omits[i]) > 0 {
+						id += `Without`
+						for _, name := range omits[i] {
+							id += source.ExportedName(name)
+						}
+					}
+					switch {
+					case op == nil:
+						return nil, fmt.Errorf(`%v: %v routes can not be described by OpenAPI`,
+							pos, method)
+					case *op != nil:
+						return nil, fmt.Errorf(`%v: %v %v is declared more than once`,
+							pos, strings.ToUpper(method), path)
+					case ids[id]:
+						return nil, fmt.Errorf(`%v: operationId %v is used more than once`,
+							pos, id)
+					default:
+						ids[id] = true
+						*op = &Operation{OperationID: id, Parameters: parameters(pkg, fd, r, omits[i]),
+							Any: rt.Method == ``, Omits: omits[i]}
+					}
+				}
+			}
+		}
+	}
+	return doc, nil
+}
+
+// pathOf returns the OpenAPI path template of the segments of a route.
+func pathOf(path []parser.Segment) string {
+	segs := make([]string, len(path))
+	for i, seg := range path {
+		segs[i] = seg.String()
+	}
+	return `/` + strings.Join(segs, `/`)
+}
+
+// parameters returns the parameters of a route, other than the optional path
+// params omitted from its path.
+func parameters(pkg *source.Package, fd *ast.Field, r *parser.Route, omits []string) (out []*Parameter) {
+	typ := pkg.Struct(fd.Type)
+	for _, prm := range r.Params() {
+		if !prm.Query && omitted(omits, prm.Name) {
+			continue
+		}
+		s := &Schema{Type: `string`}
+		if f, ok := pkg.Field(typ, prm.Name); ok {
+			s = schemaOf(f.Field)
+		}
+		if prm.Regexp != `` && s.Type == `string` {
+			s.Pattern = `^(?:` + prm.Regexp + `)$`
+		}
+		if prm.Min > 0 && s.Type == `string` {
+			s.MinLength = intPtr(prm.Min)
+		}
+		if prm.Max > 0 && s.Type == `string` {
+			s.MaxLength = intPtr(prm.Max)
+		}
+		if prm.Default != `` {
+			s.Default = defaultOf(s, prm.Default)
+		}
+
+		p := &Parameter{Name: prm.Name, In: `path`, Required: true, Schema: s,
+			Wild: prm.Wild, Optional: prm.Optional}
+		if prm.Query {
+			p.In, p.Required = `query`, prm.Required
+		}
+		out = append(out, p)
+	}
+	return
+}
+
+// schemaOf returns the schema for the type of a struct field, bounded by its min
+// and max tags when they are numbers.
+func schemaOf(fd *ast.Field) *Schema {
+	s := &Schema{Type: `string`}
+	switch typ := strings.TrimPrefix(types.ExprString(fd.Type), `*`); typ {
+	case `bool`:
+		s.Type = `boolean`
+	case `int`, `int8`, `int16`, `uint`, `uint8`, `uint16`, `uint32`, `uint64`:
+		s.Type = `integer`
+	case `int32`, `int64`:
+		s.Type, s.Format = `integer`, typ
+	case `float32`:
+		s.Type, s.Format = `number`, `float`
+	case `float64`:
+		s.Type, s.Format = `number`, `double`
+	case `time.Time`:
+		s.Format = `date-time`
+	case `time.Duration`:
+		s.Format = `duration`
+	}
+
+	if fd.Tag == nil {
+		return s
+	}
+	str, err := tag.Unquote(fd.Tag.Value)
+	if err != nil {
+		return s
+	}
+	ps, err := tag.Parse(str)
+	if err != nil {
+		return s
+	}
+	for _, key := range []string{`min`, `max`} {
+		p, ok := ps.Lookup(key)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(p.Value, 10, 64)
+		switch {
+		case err != nil:
+		case s.Type == `integer` && key == `min`:
+			s.Minimum = &n
+		case s.Type == `integer`:
+			s.Maximum = &n
+		case s.Type == `string` && s.Format == `` && key == `min`:
+			s.MinLength = intPtr(int(n))
+		case s.Type == `string` && s.Format == ``:
+			s.MaxLength = intPtr(int(n))
+		}
+	}
+	return s
+}
+
+// defaultOf returns the default value of a param as the type of its schema.
+func defaultOf(s *Schema, v string) interface{} {
+	switch s.Type {
+	case `boolean`:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case `integer`:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case `number`:
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+func intPtr(n int) *int { return &n }
+
+// omitted returns true if name is within names.
+func omitted(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode returns the document encoded as YAML when path ends in .yaml or .yml,
+// otherwise as JSON.
+func Encode(doc *Document, path string) ([]byte, error) {
+	if strings.HasSuffix(path, `.yaml`) || strings.HasSuffix(path, `.yml`) {
+		return doc.YAML()
+	}
+	return doc.JSON()
+}
+
+// WriteFile writes the encoded document to path.
+func WriteFile(path string, doc *Document) error {
+	b, err := Encode(doc, path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// TB is the subset of testing.TB used by Check.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// Check fails t when the document checked in at path is stale, meaning it
+// differs from the encoded form of doc. It is meant to be called from the tests
+// of the package declaring the router:
+//
+//	func TestOpenAPI(t *testing.T) {
+//		doc, err := openapi.Load(`.`, `Router`)
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		doc.Info.Title = `My API`
+//		openapi.Check(t, `openapi.yaml`, doc)
+//	}
+func Check(t TB, path string, doc *Document) {
+	t.Helper()
+	exp, err := Encode(doc, path)
+	if err != nil {
+		t.Fatalf(`openapi: %v`, err)
+		return
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(`openapi: %v, create it with openapi.WriteFile`, err)
+		return
+	}
+	if bytes.Equal(exp, got) {
+		return
+	}
+
+	expLines, gotLines := strings.Split(string(exp), "\n"), strings.Split(string(got), "\n")
+	for i := range expLines {
+		if i >= len(gotLines) || expLines[i] != gotLines[i] {
+			var line string
+			if i < len(gotLines) {
+				line = gotLines[i]
+			}
+			t.Fatalf("openapi: %v is stale, regenerate it with openapi.WriteFile, "+
+				"line %d differs:\nexp: %v\ngot: %v", path, i+1, expLines[i], line)
+			return
+		}
+	}
+	t.Fatalf(`openapi: %v is stale, regenerate it with openapi.WriteFile, `+
+		`it has %d extra lines`, path, len(gotLines)-len(expLines))
+}
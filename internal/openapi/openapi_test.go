@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDir holds the router shared with the other generators, the golden files
+// generated from it are checked in under testdata.
+const testDir = `../testdata/router`
+
+func TestLoad(t *testing.T) {
+	doc, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	Check(t, filepath.Join(`testdata`, `openapi.json`), doc)
+	Check(t, filepath.Join(`testdata`, `openapi.yaml`), doc)
+
+	if _, err := Load(testDir, `Bogus`); err == nil {
+		t.Fatal(`exp non-nil err`)
+	}
+}
+
+// tb records the failure of a Check.
+type tb struct{ msg string }
+
+func (t *tb) Helper() {}
+
+func (t *tb) Fatalf(format string, args ...interface{}) {
+	t.msg = fmt.Sprintf(format, args...)
+}
+
+func TestCheck(t *testing.T) {
+	doc, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	dir, err := ioutil.TempDir(``, `openapi`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, `openapi.yml`)
+	rec := new(tb)
+	if Check(rec, path, doc); !strings.Contains(rec.msg, `create it with openapi.WriteFile`) {
+		t.Fatalf(`exp missing file failure; got %q`, rec.msg)
+	}
+	if err := WriteFile(path, doc); err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	rec = new(tb)
+	if Check(rec, path, doc); rec.msg != `` {
+		t.Fatalf(`exp no failure; got %q`, rec.msg)
+	}
+
+	doc.Info.Version = `1.0.0`
+	exp := "is stale, regenerate it with openapi.WriteFile, line 4 differs:\n" +
+		"exp:   version: \"1.0.0\"\ngot:   version: \"0.0.0\""
+	if Check(rec, path, doc); !strings.Contains(rec.msg, exp) {
+		t.Fatalf("exp stale failure %q; got:\n%v", exp, rec.msg)
+	}
+}
+
+const testSrc = `package main
+
+import "net/http"
+
+type Router struct {
+	Users  Users        ` + "`%v`" + `
+	Other  http.Handler ` + "`get:\"/other\"`" + `
+}
+
+type Users struct {
+	User    string
+	Age     uint8 ` + "`min:\"18\" max:\"120\"`" + `
+	Admin   bool
+	Score   *float64
+	Visits  int64
+}
+
+func (h *Users) Get(w http.ResponseWriter, r *http.Request) {}
+func (h *Users) Delete(w http.ResponseWriter, r *http.Request) {}
+func (h *Users) Connect(w http.ResponseWriter, r *http.Request) {}
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request) {}
+`
+
+func generate(tag string) (*Document, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, fmt.Sprintf(testSrc, tag), 0)
+	if err != nil {
+		return nil, err
+	}
+	return Generate(fset, []*ast.File{f}, `Router`)
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{`path:"/users"`,
+			`"/users":{"get":{"operationId":"UsersGet"},"delete":{"operationId":"UsersDelete"}}`},
+		{`path:"/any" func:"GetUser"`, `"/any":{"get":{"operationId":"GetUser"}}`},
+		{`path:"DELETE /users"`,
+			`"/users":{"delete":{"operationId":"UsersDelete"}}`},
+		{`get:"/users/"`,
+			`"/users/":{"get":{"operationId":"UsersGet"}}`},
+		{`get:"/users/:user/:age" func:"GetUser"`,
+			`"parameters":[` +
+				`{"name":"user","in":"path","required":true,"schema":{"type":"string"}},` +
+				`{"name":"age","in":"path","required":true,` +
+				`"schema":{"type":"integer","minimum":18,"maximum":120}}]`},
+		{`get:"/users/:user(u-[0-9]+)?admin{default: true}&score{required: true}"`,
+			`{"name":"user","in":"path","required":true,` +
+				`"schema":{"type":"string","pattern":"^(?:u-[0-9]+)$"}},` +
+				`{"name":"admin","in":"query","schema":{"type":"boolean","default":true}},` +
+				`{"name":"score","in":"query","required":true,` +
+				`"schema":{"type":"number","format":"double"}}`},
+		{`get:"/users/:user{4-8}/:visits/:other?{default: x}"`,
+			`{"name":"user","in":"path","required":true,` +
+				`"schema":{"type":"string","minLength":4,"maxLength":8}},` +
+				`{"name":"visits","in":"path","required":true,` +
+				`"schema":{"type":"integer","format":"int64"}},` +
+				`{"name":"other","in":"path","required":true,` +
+				`"schema":{"type":"string","default":"x"}}`},
+		{`get:"/users/{file}/:path*"`,
+			`"/users/{file}/{path}"`},
+
+		// errors
+		{`get:"/:a:bb"`, `router.go:6:22: invalid get pattern: adjacent COLON at byte 3`},
+		{`get:"/users" func:"Bogus"`,
+			`router.go:6:22: func tag names Bogus which is not a method of Users`},
+		{`connect:"/users"`,
+			`router.go:6:22: connect routes can not be described by OpenAPI`},
+		{`get:"/other"`, `router.go:7:22: GET /other is declared more than once`},
+		{`get:"/users" put:"/users/:user" func:"GetUser"`,
+			`router.go:6:22: operationId GetUser is used more than once`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %v exp %v`, idx, test.tag, test.exp)
+		doc, err := generate(test.tag)
+		if err != nil {
+			if got := err.Error(); !strings.Contains(got, test.exp) {
+				t.Fatalf(`exp err %v to contain %v`, got, test.exp)
+			}
+			continue
+		}
+		b, err := doc.JSON()
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		got := strings.Join(strings.Fields(string(b)), ``)
+		if !strings.Contains(got, test.exp) {
+			t.Fatalf("exp document to contain:\n%v\ngot:\n%v", test.exp, got)
+		}
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp string
+	}{
+		{`get`, `get`},
+		{`/orgs/{org}`, `/orgs/{org}`},
+		{`3.1.0`, `"3.1.0"`},
+		{`true`, `"true"`},
+		{`No`, `"No"`},
+		{``, `""`},
+		{`{org}`, `"{org}"`},
+		{`^(?:[a-z]+)$`, `"^(?:[a-z]+)$"`},
+		{`a: b`, `"a: b"`},
+		{"a\"\n", `"a\"\n"`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp yamlString(%q) to return %v`, idx, test.in, test.exp)
+		if exp, got := test.exp, yamlString(test.in); exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
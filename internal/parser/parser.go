@@ -0,0 +1,481 @@
+// Package parser verifies a token stream is correct before generating one or
+// more route objects ready for analysis.
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cstockton/routepiler/internal/scanner"
+	"github.com/cstockton/routepiler/internal/token"
+)
+
+// Route is a single route pattern verified by the parser.
+type Route struct {
+	Pattern string    // source pattern
+	Method  string    // http method or empty for any method
+	Path    []Segment // each path segment, a leading FSLASH is implied
+	Query   []*Param  // query params declared after the path
+}
+
+// Params returns each path param followed by each query param.
+func (r *Route) Params() (out []*Param) {
+	for _, seg := range r.Path {
+		for _, part := range seg {
+			if part.Param != nil {
+				out = append(out, part.Param)
+			}
+		}
+	}
+	return append(out, r.Query...)
+}
+
+// Param returns the param with the given name or nil if none exists.
+func (r *Route) Param(name string) *Param {
+	for _, prm := range r.Params() {
+		if prm.Name == name {
+			return prm
+		}
+	}
+	return nil
+}
+
+// Segment is a single path segment composed of zero or more parts, the empty
+// segment only occurs for the root or a trailing FSLASH.
+type Segment []Part
+
+// Static returns true if this segment contains no params.
+func (s Segment) Static() bool {
+	for _, part := range s {
+		if part.Param != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the literal parts of this segment with each param in the
+// short form of {name}.
+func (s Segment) String() string {
+	var out string
+	for _, part := range s {
+		out += part.String()
+	}
+	return out
+}
+
+// Part is either a literal or a param within a path segment.
+type Part struct {
+	Lit   string // literal text when Param is nil
+	Param *Param
+}
+
+// String returns the literal or the param in the short form of {name}.
+func (p Part) String() string {
+	if p.Param != nil {
+		return `{` + p.Param.Name + `}`
+	}
+	return p.Lit
+}
+
+// Param is a single named path or query param.
+type Param struct {
+	Name     string
+	Regexp   string    // regular expression the value must match
+	Min, Max int       // value length bounds, zero when unset
+	Wild     bool      // matches multiple path segments
+	Depth    int       // max path segments for a wild param, zero if unbound
+	Optional bool      // path param may be absent
+	Required bool      // query param must be present
+	Default  string    // value when absent
+	Query    bool      // declared in the query rather than the path
+	Pos      token.Pos // position within the route pattern
+}
+
+// Error is returned from Parse when a pattern is invalid.
+type Error struct {
+	Off int    // byte offset within the pattern
+	End int    // byte offset following the token at Off
+	Msg string // message including the offset
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+// Parse will return the Route for a pattern, or nil and a non-nil *Error if the
+// pattern could not be scanned or is invalid.
+func Parse(pattern string) (*Route, error) {
+	toks, err := scanner.Scan(pattern)
+	if err != nil {
+		if serr, ok := err.(*scanner.Error); ok {
+			// scanner errors are at a single rune
+			_, w := utf8.DecodeRuneInString(pattern[serr.Off:])
+			return nil, &Error{Off: serr.Off, End: serr.Off + w, Msg: serr.Msg}
+		}
+		return nil, &Error{Msg: err.Error()}
+	}
+
+	p := parser{names: make(map[string]bool)}
+	for _, tok := range toks {
+		if tok.Lex != token.WHITESPACE {
+			p.toks = append(p.toks, tok)
+		}
+	}
+
+	r := &Route{Pattern: pattern}
+	if p.route(r); p.err != nil {
+		return nil, p.err
+	}
+	return r, nil
+}
+
+type parser struct {
+	toks  token.Tokens // significant tokens ending in EOF
+	idx   int          // index of the next token
+	names map[string]bool
+	opt   *Param // last optional param of the path
+	err   *Error
+}
+
+func (p *parser) route(r *Route) {
+	if p.peek().Lex == token.METHOD {
+		r.Method = p.next().Lit
+	}
+	if p.peek().Lex == token.FSLASH {
+		p.next()
+	}
+
+	var seg Segment
+	beg := p.peek()
+	for p.err == nil {
+		switch tok := p.peek(); tok.Lex {
+		case token.EOF:
+			r.Path = append(r.Path, p.segment(seg, beg))
+			return
+		case token.QUEST:
+			r.Path = append(r.Path, p.segment(seg, beg))
+			p.query(r)
+			return
+		case token.FSLASH:
+			p.next()
+			r.Path, seg = append(r.Path, p.segment(seg, beg)), nil
+			beg = p.peek()
+		case token.SEGMENT:
+			p.next()
+			seg = append(seg, Part{Lit: tok.Lit})
+		case token.COLON:
+			p.next()
+			seg = append(seg, Part{Param: p.param(tok)})
+		case token.LBRACE:
+			p.next()
+			seg = append(seg, Part{Param: p.brace(tok)})
+		default:
+			p.unexpected(tok,
+				token.FSLASH, token.SEGMENT, token.COLON, token.LBRACE, token.QUEST)
+		}
+	}
+}
+
+// segment verifies a path segment which began with the token beg once it has
+// been fully parsed. An optional param must be the only part of its segment,
+// and may only be followed by segments holding another optional param, so the
+// param may be absent by omitting each segment from its own onward.
+func (p *parser) segment(seg Segment, beg token.Token) Segment {
+	lone := len(seg) == 1 && seg[0].Param != nil && seg[0].Param.Optional
+	for _, part := range seg {
+		if prm := part.Param; prm != nil && prm.Optional && !lone {
+			p.fail(token.Token{Lex: token.IDENT, Lit: prm.Name, Beg: prm.Pos},
+				`param %q is optional so must be the only part of its segment`, prm.Name)
+		}
+	}
+	switch {
+	case lone:
+		p.opt = seg[0].Param
+	case p.opt != nil:
+		p.fail(beg, `segment following the optional param %q must be an optional param`,
+			p.opt.Name)
+	}
+	return seg
+}
+
+// param parses the remainder of a param which began with a COLON.
+func (p *parser) param(colon token.Token) *Param {
+	prm := &Param{Name: p.expect(token.IDENT).Lit, Pos: colon.Beg}
+	for p.err == nil {
+		switch tok := p.peek(); tok.Lex {
+		case token.QUEST:
+			if p.after().Lex == token.IDENT {
+				return p.declare(prm) // begins the query declaration
+			}
+			if prm.Optional {
+				p.fail(tok, `param %q is already optional`, prm.Name)
+			}
+			p.next()
+			prm.Optional = true
+		case token.REGEXP:
+			if prm.Regexp != `` {
+				p.fail(tok, `param %q already has a regexp`, prm.Name)
+			}
+			p.setRegexp(tok, prm, p.next().Lit)
+		case token.WILD:
+			if prm.Wild {
+				p.fail(tok, `param %q is already a wildcard`, prm.Name)
+			}
+			p.next()
+			if prm.Wild = true; p.peek().Lex == token.LBRACK {
+				p.next()
+				prm.Depth = p.number(p.expect(token.NUMBER))
+				p.expect(token.RBRACK)
+			}
+		case token.LBRACE:
+			p.template(prm, false)
+			return p.declare(prm)
+		default:
+			return p.declare(prm)
+		}
+	}
+	return prm
+}
+
+// brace parses a param declared entirely within a template such as {name} or
+// {name: user, max: 20}.
+func (p *parser) brace(lbrace token.Token) *Param {
+	prm := &Param{Pos: lbrace.Beg}
+	if p.peek().Lex == token.IDENT && p.after().Lex != token.COLON {
+		prm.Name = p.next().Lit
+		if p.peek().Lex == token.QUEST {
+			p.next()
+			prm.Optional = true
+		}
+		p.expect(token.RBRACE)
+		return p.declare(prm)
+	}
+
+	p.pairs(prm, true)
+	if p.err == nil && prm.Name == `` {
+		p.fail(lbrace, `template is missing a name`)
+	}
+	return p.declare(prm)
+}
+
+// template parses a template following a param name such as {7-15} or
+// {min: 7, max: 15}.
+func (p *parser) template(prm *Param, named bool) {
+	p.expect(token.LBRACE)
+	if p.peek().Lex != token.NUMBER {
+		p.pairs(prm, named)
+		return
+	}
+
+	n := p.number(p.next())
+	if p.peek().Lex == token.MINUS {
+		p.next()
+		prm.Min, prm.Max = n, p.number(p.expect(token.NUMBER))
+	} else {
+		prm.Max = n
+	}
+	p.expect(token.RBRACE)
+}
+
+// pairs parses key value pairs until the closing RBRACE of a template.
+func (p *parser) pairs(prm *Param, named bool) {
+	for i := 0; p.err == nil; i++ {
+		key := p.peek()
+		switch key.Lex {
+		case token.IDENT, token.STRING:
+			p.next()
+		default:
+			p.unexpected(key, token.IDENT, token.STRING)
+			return
+		}
+		p.expect(token.COLON)
+
+		val := p.value()
+		switch k := strings.ToLower(key.Lit); {
+		case p.err != nil:
+			return
+		case k == `name` && named:
+			prm.Name = strings.TrimSuffix(val, `?`)
+			prm.Optional = prm.Optional || strings.HasSuffix(val, `?`)
+		case k == `regex`, k == `regexp`:
+			p.setRegexp(key, prm, val)
+		case k == `min`:
+			prm.Min = p.atoi(key, val)
+		case k == `max`:
+			prm.Max = p.atoi(key, val)
+		case k == `wild`:
+			prm.Wild, prm.Depth = true, p.atoi(key, val)
+		case k == `default`:
+			prm.Default = val
+		case k == `optional`:
+			prm.Optional = p.bool(key, val)
+		case k == `required`:
+			prm.Required = p.bool(key, val)
+		case named && i == 0:
+			// short form of {name: regexp} as the first pair
+			prm.Name = key.Lit
+			p.setRegexp(key, prm, val)
+		default:
+			p.fail(key, `unknown template key %q`, key.Lit)
+		}
+
+		switch tok := p.next(); tok.Lex {
+		case token.COMMA:
+		case token.RBRACE:
+			return
+		default:
+			p.unexpected(tok, token.COMMA, token.RBRACE)
+		}
+	}
+}
+
+// value returns the literal of all tokens up to the next COMMA or RBRACE which
+// is not within a nested template.
+func (p *parser) value() string {
+	var (
+		out   string
+		depth int
+	)
+	for p.err == nil {
+		switch tok := p.peek(); {
+		case tok.Lex == token.EOF:
+			p.unexpected(tok, token.COMMA, token.RBRACE)
+		case depth == 0 && (tok.Lex == token.COMMA || tok.Lex == token.RBRACE):
+			if out == `` {
+				p.fail(tok, `template key is missing a value`)
+			}
+			return out
+		default:
+			switch tok.Lex {
+			case token.LBRACE:
+				depth++
+			case token.RBRACE:
+				depth--
+			}
+			out += p.next().Lit
+		}
+	}
+	return out
+}
+
+func (p *parser) query(r *Route) {
+	p.expect(token.QUEST)
+	for p.err == nil {
+		tok := p.expect(token.IDENT)
+		prm := &Param{Name: tok.Lit, Query: true, Pos: tok.Beg}
+		if p.peek().Lex == token.LBRACE {
+			p.template(prm, false)
+		}
+		r.Query = append(r.Query, p.declare(prm))
+
+		switch tok := p.next(); tok.Lex {
+		case token.AMPER:
+		case token.EOF:
+			return
+		default:
+			p.unexpected(tok, token.AMPER, token.EOF)
+		}
+	}
+}
+
+// setRegexp sets the regexp of a param, which may not be empty or only
+// whitespace since it would otherwise read as a param without one.
+func (p *parser) setRegexp(tok token.Token, prm *Param, expr string) {
+	if strings.TrimSpace(expr) == `` {
+		p.fail(tok, `param %q has an empty regexp`, prm.Name)
+		return
+	}
+	prm.Regexp = expr
+}
+
+// declare verifies a param once it has been fully parsed.
+func (p *parser) declare(prm *Param) *Param {
+	tok := token.Token{Lex: token.IDENT, Lit: prm.Name, Beg: prm.Pos}
+	switch {
+	case p.err != nil:
+	case p.names[prm.Name]:
+		p.fail(tok, `param %q is declared more than once`, prm.Name)
+	case prm.Max > 0 && prm.Min > prm.Max:
+		p.fail(tok, `param %q has a min of %d which exceeds the max of %d`,
+			prm.Name, prm.Min, prm.Max)
+	case prm.Optional && prm.Wild:
+		p.fail(tok, `param %q is a wildcard which may not be optional`, prm.Name)
+	case prm.Default != `` && !prm.Optional && !prm.Query:
+		p.fail(tok, `param %q has a default but is not optional`, prm.Name)
+	case prm.Required && !prm.Query:
+		p.fail(tok, `param %q is a path param which are always required`, prm.Name)
+	case prm.Regexp != ``:
+		if _, err := regexp.Compile(prm.Regexp); err != nil {
+			p.fail(tok, `param %q has an invalid regexp: %v`, prm.Name, err)
+		}
+	}
+	p.names[prm.Name] = true
+	return prm
+}
+
+func (p *parser) number(tok token.Token) int {
+	return p.atoi(tok, tok.Lit)
+}
+
+func (p *parser) atoi(tok token.Token, s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil && p.err == nil {
+		p.fail(tok, `expected number for %v, got %q`, tok.Lit, s)
+	}
+	return n
+}
+
+func (p *parser) bool(tok token.Token, s string) bool {
+	v, err := strconv.ParseBool(s)
+	if err != nil && p.err == nil {
+		p.fail(tok, `expected true or false for %v, got %q`, tok.Lit, s)
+	}
+	return v
+}
+
+func (p *parser) peek() token.Token {
+	return p.toks[p.idx]
+}
+
+// after returns the token following the next token.
+func (p *parser) after() token.Token {
+	if p.idx+1 < len(p.toks) {
+		return p.toks[p.idx+1]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) next() token.Token {
+	tok := p.toks[p.idx]
+	if p.idx < len(p.toks)-1 {
+		p.idx++
+	}
+	return tok
+}
+
+func (p *parser) expect(l token.Lexeme) token.Token {
+	tok := p.next()
+	if tok.Lex != l {
+		p.unexpected(tok, l)
+	}
+	return tok
+}
+
+func (p *parser) unexpected(tok token.Token, exp ...token.Lexeme) {
+	p.fail(tok, `unexpected %v, expecting %v`,
+		tok.Lex, token.Lexemes(exp).Join(` or `))
+}
+
+func (p *parser) fail(tok token.Token, msg string, args ...interface{}) {
+	if p.err == nil {
+		// tokens built by declare have no end
+		off, end := tok.Beg.Offset(), tok.End.Offset()
+		if end < off {
+			end = off
+		}
+		p.err = &Error{Off: off, End: end,
+			Msg: fmt.Sprintf(msg, args...) + ` at byte ` + strconv.Itoa(off)}
+	}
+}
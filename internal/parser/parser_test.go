@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lit and prm return parts for table tests.
+func lit(s string) Part      { return Part{Lit: s} }
+func prm(p *Param) Part      { return Part{Param: p} }
+func seg(ps ...Part) Segment { return Segment(ps) }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		pat   string
+		meth  string
+		path  []Segment
+		query []*Param
+	}{
+		// static
+		{`/`, ``, []Segment{nil}, nil},
+		{`/users`, ``, []Segment{seg(lit(`users`))}, nil},
+		{`users`, ``, []Segment{seg(lit(`users`))}, nil},
+		{`/users/`, ``, []Segment{seg(lit(`users`)), nil}, nil},
+		{`GET /users/me`, `GET`,
+			[]Segment{seg(lit(`users`)), seg(lit(`me`))}, nil},
+
+		// named
+		{`/users/:user`, ``, []Segment{
+			seg(lit(`users`)), seg(prm(&Param{Name: `user`}))}, nil},
+		{`/users/{user}`, ``, []Segment{
+			seg(lit(`users`)), seg(prm(&Param{Name: `user`}))}, nil},
+		{`/users/:user([a-z]{6,20})`, ``, []Segment{
+			seg(lit(`users`)), seg(prm(&Param{Name: `user`, Regexp: `[a-z]{6,20}`}))},
+			nil},
+
+		// templates
+		{`/:user{20}`, ``, []Segment{
+			seg(prm(&Param{Name: `user`, Max: 20}))}, nil},
+		{`/:user{3-20}`, ``, []Segment{
+			seg(prm(&Param{Name: `user`, Min: 3, Max: 20}))}, nil},
+		{`/:user{min: 3, 'max': 20}`, ``, []Segment{
+			seg(prm(&Param{Name: `user`, Min: 3, Max: 20}))}, nil},
+		{`/teams/{regex: "[a-z]{4}", name: team}`, ``, []Segment{
+			seg(lit(`teams`)), seg(prm(&Param{Name: `team`, Regexp: `[a-z]{4}`}))},
+			nil},
+		{`/teams/{team: '[a-z]{4}', max: 4}`, ``, []Segment{
+			seg(lit(`teams`)),
+			seg(prm(&Param{Name: `team`, Regexp: `[a-z]{4}`, Max: 4}))}, nil},
+		{`/:a{regex: .+?}`, ``, []Segment{
+			seg(prm(&Param{Name: `a`, Regexp: `.+?`}))}, nil},
+		{`GET /teams/:team([a-z]{4}){7-15}`, `GET`, []Segment{
+			seg(lit(`teams`)),
+			seg(prm(&Param{Name: `team`, Regexp: `[a-z]{4}`, Min: 7, Max: 15}))}, nil},
+
+		// wildcards
+		{`/static/:file*`, ``, []Segment{
+			seg(lit(`static`)), seg(prm(&Param{Name: `file`, Wild: true}))}, nil},
+		{`/static/:file*[3]`, ``, []Segment{
+			seg(lit(`static`)),
+			seg(prm(&Param{Name: `file`, Wild: true, Depth: 3}))}, nil},
+		{`/static/:file*{2-3}`, ``, []Segment{
+			seg(lit(`static`)),
+			seg(prm(&Param{Name: `file`, Wild: true, Min: 2, Max: 3}))}, nil},
+
+		// optional
+		{`/reports/:year/:month?`, ``, []Segment{
+			seg(lit(`reports`)), seg(prm(&Param{Name: `year`})),
+			seg(prm(&Param{Name: `month`, Optional: true}))}, nil},
+		{`/reports/{month?}`, ``, []Segment{
+			seg(lit(`reports`)),
+			seg(prm(&Param{Name: `month`, Optional: true}))}, nil},
+		{`/reports/:month?{default: 1}`, ``, []Segment{
+			seg(lit(`reports`)),
+			seg(prm(&Param{Name: `month`, Optional: true, Default: `1`}))}, nil},
+		{`/reports/{name: month?, default: 'jan'}`, ``, []Segment{
+			seg(lit(`reports`)),
+			seg(prm(&Param{Name: `month`, Optional: true, Default: `jan`}))}, nil},
+
+		// mixed
+		{`/files/:name.:ext`, ``, []Segment{
+			seg(lit(`files`)),
+			seg(prm(&Param{Name: `name`}), lit(`.`), prm(&Param{Name: `ext`}))}, nil},
+		{`/v{version}`, ``, []Segment{
+			seg(lit(`v`), prm(&Param{Name: `version`}))}, nil},
+		{`/{from}-{to}`, ``, []Segment{
+			seg(prm(&Param{Name: `from`}), lit(`-`), prm(&Param{Name: `to`}))}, nil},
+
+		// query
+		{`/users?since`, ``, []Segment{seg(lit(`users`))},
+			[]*Param{{Name: `since`, Query: true}}},
+		{`/users/:user?since&age{18-120}`, ``, []Segment{
+			seg(lit(`users`)), seg(prm(&Param{Name: `user`}))},
+			[]*Param{
+				{Name: `since`, Query: true},
+				{Name: `age`, Query: true, Min: 18, Max: 120}}},
+		{`/users?age{required: true}&page{default: 1}`, ``,
+			[]Segment{seg(lit(`users`))},
+			[]*Param{
+				{Name: `age`, Query: true, Required: true},
+				{Name: `page`, Query: true, Default: `1`}}},
+		{`/reports/:month??since`, ``, []Segment{
+			seg(lit(`reports`)), seg(prm(&Param{Name: `month`, Optional: true}))},
+			[]*Param{{Name: `since`, Query: true}}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from pat %q`, idx, test.pat)
+		r, err := Parse(test.pat)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp, got := test.pat, r.Pattern; exp != got {
+			t.Fatalf(`exp Pattern %q; got %q`, exp, got)
+		}
+		if exp, got := test.meth, r.Method; exp != got {
+			t.Fatalf(`exp Method %q; got %q`, exp, got)
+		}
+
+		// positions are covered by TestParsePos
+		for _, prm := range r.Params() {
+			prm.Pos = 0
+		}
+		if exp, got := test.path, r.Path; !reflect.DeepEqual(exp, got) {
+			t.Fatalf("unexpected Path:\nexp: %v\ngot: %v\n", exp, got)
+		}
+		if exp, got := test.query, r.Query; !reflect.DeepEqual(exp, got) {
+			t.Fatalf("unexpected Query:\nexp: %v\ngot: %v\n", exp, got)
+		}
+	}
+}
+
+func TestParsePos(t *testing.T) {
+	r, err := Parse(`GET /orgs/:org/users/{user}?since`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	params := r.Params()
+	if exp, got := 3, len(params); exp != got {
+		t.Fatalf(`exp %d params; got %d`, exp, got)
+	}
+	for i, exp := range []int{10, 21, 28} {
+		if got := params[i].Pos.Offset(); exp != got {
+			t.Fatalf(`exp param %v at byte %v; got %v`, params[i].Name, exp, got)
+		}
+	}
+	if exp, got := params[1], r.Param(`user`); exp != got {
+		t.Fatalf(`exp Param to return %v; got %v`, exp, got)
+	}
+	if got := r.Param(`team`); got != nil {
+		t.Fatalf(`exp nil Param; got %v`, got)
+	}
+}
+
+func TestSegment(t *testing.T) {
+	r, err := Parse(`/files/{name}.:ext/v:version/a%3Ab`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if exp, got := 4, len(r.Path); exp != got {
+		t.Fatalf(`exp %d segments; got %d`, exp, got)
+	}
+	tests := []struct {
+		static bool
+		str    string
+	}{
+		{true, `files`},
+		{false, `{name}.{ext}`},
+
+		// a colon after a leading literal begins a param
+		{false, `v{version}`},
+
+		// an escaped colon is a literal
+		{true, `a%3Ab`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp segment %q`, idx, test.str)
+		if exp, got := test.static, r.Path[idx].Static(); exp != got {
+			t.Fatalf(`exp Static() to return %v; got %v`, exp, got)
+		}
+		if exp, got := test.str, r.Path[idx].String(); exp != got {
+			t.Fatalf(`exp String() %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestParseNegative(t *testing.T) {
+	tests := []struct {
+		pat string
+		off int
+		exp string
+	}{
+		// scanner errors
+		{`GET`, 2, `ambiguous`},
+		{`/:a:b`, 3, `adjacent`},
+
+		// parser errors
+		{`/:a/:a`, 4, `param "a" is declared more than once`},
+		{`/:a?b&a`, 6, `param "a" is declared more than once`},
+		{`/:a{20-3}`, 1, `min of 20 which exceeds the max of 3`},
+		{`/:a{default: 1}`, 1, `param "a" has a default but is not optional`},
+		{`/:a{required: true}`, 1, `always required`},
+		{`/:a([a-z)`, 1, `invalid regexp`},
+		{`/:a()`, 3, `param "a" has an empty regexp`},
+		{"/:a(\n \n)", 3, `param "a" has an empty regexp`},
+		{`/:a{regex: ' '}`, 4, `param "a" has an empty regexp`},
+		{`/{a: ' '}`, 2, `param "a" has an empty regexp`},
+		{`/:a{name: b}`, 4, `unknown template key "name"`},
+		{`/:a{bogus: b}`, 4, `unknown template key "bogus"`},
+		{`/:a{min: b}`, 4, `expected number for min, got "b"`},
+		{`/:a{optional: b}`, 4, `expected true or false for optional, got "b"`},
+		{`/:a{min}`, 7, `unexpected RBRACE, expecting "COLON"`},
+		{`/:a{min:}`, 8, `template key is missing a value`},
+		{`/{max: 3}`, 1, `template is missing a name`},
+		{`/:a*[b]`, 5, `unexpected IDENT, expecting "NUMBER"`},
+		{`/:a??`, 4, `param "a" is already optional`},
+		{`/:a?/:b`, 5, `segment following the optional param "a" must be an optional param`},
+		{`/:a?/b/:c?`, 5, `segment following the optional param "a" must be an optional param`},
+		{`/:a?/`, 5, `segment following the optional param "a" must be an optional param`},
+		{`/:a?-x`, 1, `param "a" is optional so must be the only part of its segment`},
+		{`/{a?}.json`, 1, `param "a" is optional so must be the only part of its segment`},
+		{`/:a*?`, 1, `param "a" is a wildcard which may not be optional`},
+		{`/{name: a?, wild: 2}`, 1, `param "a" is a wildcard which may not be optional`},
+		{`/:a([a-z])?([a-z])`, 11, `param "a" already has a regexp`},
+		{`/:a**`, 4, `param "a" is already a wildcard`},
+		{`/?`, 2, `unexpected EOF, expecting "IDENT"`},
+		{`/?a?`, 3, `unexpected QUEST, expecting "AMPER" or "EOF"`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from pat %q exp err %q`, idx, test.pat, test.exp)
+		_, err := Parse(test.pat)
+		if err == nil {
+			t.Fatal(`exp non-nil err`)
+		}
+		perr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf(`exp *Error; got %T`, err)
+		}
+		if exp, got := test.exp, perr.Error(); !strings.Contains(got, exp) {
+			t.Fatalf(`exp err %v to contain %v`, got, exp)
+		}
+		if exp, got := test.off, perr.Off; exp != got {
+			t.Fatalf(`exp err %v at byte %v; got %v`, perr, exp, got)
+		}
+	}
+}
+
+func TestErrorEnd(t *testing.T) {
+	tests := []struct {
+		pat      string
+		off, end int
+	}{
+		{`/:a:b`, 3, 4},                // scanner errors span a rune
+		{`/:a(é`, 6, 6},                // at EOF
+		{`/:a/:a`, 4, 4},               // declared params have no end
+		{`/:a{min: x}`, 4, 7},          // the key token
+		{`/:a([a-z])?([a-z])`, 11, 18}, // the regexp token
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from pat %q exp err at bytes %v-%v`,
+			idx, test.pat, test.off, test.end)
+		_, err := Parse(test.pat)
+		perr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf(`exp *Error; got %T`, err)
+		}
+		if exp, got := [2]int{test.off, test.end}, [2]int{perr.Off, perr.End}; exp != got {
+			t.Fatalf(`exp err %v at bytes %v; got %v`, perr, exp, got)
+		}
+	}
+}
@@ -509,11 +509,158 @@ func init() {
 			tk(REGEXP, "[a-z]{3,10}", At(1, 7, 7), At(3, 23, 23))),
 	)
 
+	// pattern: multiple params within a segment separated by literals
+	tcs("mixed",
+		tc(":a.:bb", tk(COLON, ":"), tk(IDENT, "a"), tk(SEGMENT, "."),
+			tk(COLON, ":"), tk(IDENT, "bb")),
+		tc(":a-:bb", tk(COLON, ":"), tk(IDENT, "a"), tk(SEGMENT, "-"),
+			tk(COLON, ":"), tk(IDENT, "bb")),
+		tc("/aaa/:a.:bb/ccc", tk(FSLASH, "/"), tk(SEGMENT, "aaa"),
+			tk(FSLASH, "/"), tk(COLON, ":"), tk(IDENT, "a"), tk(SEGMENT, "."),
+			tk(COLON, ":"), tk(IDENT, "bb"), tk(FSLASH, "/"), tk(SEGMENT, "ccc")),
+		tc(":a-and-:bb-post",
+			tk(COLON, ":"), tk(IDENT, "a"),
+			tk(SEGMENT, "-and-"), tk(COLON, ":"), tk(IDENT, "bb"),
+			tk(SEGMENT, "-post")),
+
+		// templates and shorthand may be mixed
+		tc("{a}-:bb", tk(LBRACE, "{"), tk(IDENT, "a"), tk(RBRACE, "}"),
+			tk(SEGMENT, "-"), tk(COLON, ":"), tk(IDENT, "bb")),
+		tc(":a-{bb}", tk(COLON, ":"), tk(IDENT, "a"), tk(SEGMENT, "-"),
+			tk(LBRACE, "{"), tk(IDENT, "bb"), tk(RBRACE, "}")),
+		tc("pre-{a}-and-:bb-post",
+			tk(SEGMENT, "pre-"), tk(LBRACE, "{"), tk(IDENT, "a"), tk(RBRACE, "}"),
+			tk(SEGMENT, "-and-"), tk(COLON, ":"), tk(IDENT, "bb"),
+			tk(SEGMENT, "-post")),
+		tc(":a{7-15}.:bb",
+			tk(COLON, ":"), tk(IDENT, "a"),
+			tk(LBRACE, "{"), tk(NUMBER, "7"), tk(MINUS, "-"), tk(NUMBER, "15"),
+			tk(RBRACE, "}"),
+			tk(SEGMENT, "."), tk(COLON, ":"), tk(IDENT, "bb")),
+
+		// literal following a regexp, wildcard or optional marker
+		tc(":aa([0-9_]).json", tk(COLON, ":"), tk(IDENT, "aa"),
+			tk(REGEXP, "[0-9_]", At(1, 3, 3), At(1, 11, 11)),
+			tk(SEGMENT, ".json")),
+		tc(":aaa*.tar", tk(COLON, ":"), tk(IDENT, "aaa"), tk(WILD, "*"),
+			tk(SEGMENT, ".tar")),
+		tc(":aaa*[3].tar", tk(COLON, ":"), tk(IDENT, "aaa"), tk(WILD, "*"),
+			tk(LBRACK, "["), tk(NUMBER, "3"), tk(RBRACK, "]"),
+			tk(SEGMENT, ".tar")),
+		tc(":aaa?.json", tk(COLON, ":"), tk(IDENT, "aaa"), tk(QUEST, "?"),
+			tk(SEGMENT, ".json")),
+
+		// a colon after a literal that begins a segment starts a param
+		tc("/a:bb", tk(FSLASH, "/"), tk(SEGMENT, "a"), tk(COLON, ":"),
+			tk(IDENT, "bb")),
+		tc("/pre-:bb", tk(FSLASH, "/"), tk(SEGMENT, "pre-"), tk(COLON, ":"),
+			tk(IDENT, "bb")),
+		tc("/v:version/aaa", tk(FSLASH, "/"), tk(SEGMENT, "v"), tk(COLON, ":"),
+			tk(IDENT, "version"), tk(FSLASH, "/"), tk(SEGMENT, "aaa")),
+	)
+
+	// pattern: query params declared after the path
+	tcs("query",
+		tc("?a", tk(QUEST, "?"), tk(IDENT, "a")),
+		tc("/?a", tk(FSLASH, "/"), tk(QUEST, "?"), tk(IDENT, "a")),
+		tc("/aaa?a", tk(FSLASH, "/"), tk(SEGMENT, "aaa"),
+			tk(QUEST, "?"), tk(IDENT, "a")),
+		tc("/aaa/?a", tk(FSLASH, "/"), tk(SEGMENT, "aaa"), tk(FSLASH, "/"),
+			tk(QUEST, "?"), tk(IDENT, "a")),
+
+		// multiple params are separated by AMPER
+		tc("/aaa?a&bb", tk(FSLASH, "/"), tk(SEGMENT, "aaa"),
+			tk(QUEST, "?"), tk(IDENT, "a"), tk(AMPER, "&"), tk(IDENT, "bb")),
+		tc("/aaa?a&bb&ccc", tk(FSLASH, "/"), tk(SEGMENT, "aaa"),
+			tk(QUEST, "?"), tk(IDENT, "a"), tk(AMPER, "&"), tk(IDENT, "bb"),
+			tk(AMPER, "&"), tk(IDENT, "ccc")),
+
+		// following a named path param
+		tc(":aaa?a&bb", tk(COLON, ":"), tk(IDENT, "aaa"),
+			tk(QUEST, "?"), tk(IDENT, "a"), tk(AMPER, "&"), tk(IDENT, "bb")),
+		tc("{aaa}?a&bb", tk(LBRACE, "{"), tk(IDENT, "aaa"), tk(RBRACE, "}"),
+			tk(QUEST, "?"), tk(IDENT, "a"), tk(AMPER, "&"), tk(IDENT, "bb")),
+		tc(":aa([0-9_])?a",
+			tk(COLON, ":"), tk(IDENT, "aa"),
+			tk(REGEXP, "[0-9_]", At(1, 3, 3), At(1, 11, 11)),
+			tk(QUEST, "?"), tk(IDENT, "a")),
+
+		// query params share the template syntax of path params
+		tc("/aaa?a{7-15}&bb",
+			tk(FSLASH, "/"), tk(SEGMENT, "aaa"), tk(QUEST, "?"), tk(IDENT, "a"),
+			tk(LBRACE, "{"), tk(NUMBER, "7"), tk(MINUS, "-"), tk(NUMBER, "15"),
+			tk(RBRACE, "}"), tk(AMPER, "&"), tk(IDENT, "bb")),
+		tc("/aaa?a{min:7,default:9}&bb{max:15}",
+			tk(FSLASH, "/"), tk(SEGMENT, "aaa"), tk(QUEST, "?"), tk(IDENT, "a"),
+			tk(LBRACE, "{"),
+			tk(IDENT, "min"), tk(COLON, ":"), tk(NUMBER, "7"), tk(COMMA, ","),
+			tk(IDENT, "default"), tk(COLON, ":"), tk(NUMBER, "9"),
+			tk(RBRACE, "}"),
+			tk(AMPER, "&"), tk(IDENT, "bb"),
+			tk(LBRACE, "{"),
+			tk(IDENT, "max"), tk(COLON, ":"), tk(NUMBER, "15"),
+			tk(RBRACE, "}")),
+
+		// literals may still contain a QUEST or AMPER
+		tc("/aaa?a{'regex': .+?&}",
+			tk(FSLASH, "/"), tk(SEGMENT, "aaa"), tk(QUEST, "?"), tk(IDENT, "a"),
+			tk(LBRACE, "{"),
+			tk(STRING, "regex"), tk(COLON, ":"), tk(WHITESPACE, " "), tk(LIT, ".+?&"),
+			tk(RBRACE, "}")),
+	)
+
+	// pattern: optional params are marked by a QUEST not followed by a name
+	tcs("optional",
+		tc(":aaa?", tk(COLON, ":"), tk(IDENT, "aaa"), tk(QUEST, "?")),
+		tc("{aaa?}", tk(LBRACE, "{"), tk(IDENT, "aaa"), tk(QUEST, "?"),
+			tk(RBRACE, "}")),
+		tc("/aaa/:a/:bb?",
+			tk(FSLASH, "/"), tk(SEGMENT, "aaa"),
+			tk(FSLASH, "/"), tk(COLON, ":"), tk(IDENT, "a"),
+			tk(FSLASH, "/"), tk(COLON, ":"), tk(IDENT, "bb"), tk(QUEST, "?")),
+		tc("/aaa/:a?/bbb",
+			tk(FSLASH, "/"), tk(SEGMENT, "aaa"),
+			tk(FSLASH, "/"), tk(COLON, ":"), tk(IDENT, "a"), tk(QUEST, "?"),
+			tk(FSLASH, "/"), tk(SEGMENT, "bbb")),
+		tc("/aaa/{a?}/bbb",
+			tk(FSLASH, "/"), tk(SEGMENT, "aaa"),
+			tk(FSLASH, "/"), tk(LBRACE, "{"), tk(IDENT, "a"), tk(QUEST, "?"),
+			tk(RBRACE, "}"), tk(FSLASH, "/"), tk(SEGMENT, "bbb")),
+
+		// default values use the template syntax
+		tc(":aaa?{default:7}", tk(COLON, ":"), tk(IDENT, "aaa"), tk(QUEST, "?"),
+			tk(LBRACE, "{"),
+			tk(IDENT, "default"), tk(COLON, ":"), tk(NUMBER, "7"),
+			tk(RBRACE, "}")),
+		tc("{name: aaa?, default: `bbb`}-post",
+			tk(LBRACE, "{"),
+			tk(IDENT, "name"), tk(COLON, ":"), tk(WHITESPACE, " "),
+			tk(IDENT, "aaa"), tk(QUEST, "?"), tk(COMMA, ","), tk(WHITESPACE, " "),
+			tk(IDENT, "default"), tk(COLON, ":"), tk(WHITESPACE, " "),
+			tk(STRING, "bbb"),
+			tk(RBRACE, "}"),
+			tk(SEGMENT, "-post")),
+
+		// optional param followed by a query declaration
+		tc(":aaa??a&bb", tk(COLON, ":"), tk(IDENT, "aaa"), tk(QUEST, "?"),
+			tk(QUEST, "?"), tk(IDENT, "a"), tk(AMPER, "&"), tk(IDENT, "bb")),
+		tc("{aaa?}?a", tk(LBRACE, "{"), tk(IDENT, "aaa"), tk(QUEST, "?"),
+			tk(RBRACE, "}"), tk(QUEST, "?"), tk(IDENT, "a")),
+	)
+
 	// negative tests
 	tcs("negative",
 
 		// ambiguous pattern, is path "/GET" or method "GET /"
 		te("GET", `ambiguous`, tk(COLON, ":"), tk(IDENT, "aaa"), tk(WILD, "*")),
+
+		// adjacent params have no literal to separate them
+		te(":a:bb", `adjacent COLON at byte 2`),
+		te("/aaa/:a:bb", `adjacent COLON at byte 7`),
+		te("{a}{bb}", `adjacent LBRACE at byte 3`),
+		te("{a}:bb", `adjacent COLON at byte 3`),
+		te(":a([0-9]):bb", `adjacent COLON at byte 9`),
+		te(":a{7-15}{bb}", `adjacent LBRACE at byte 8`),
 	)
 }
 
@@ -22,6 +22,7 @@ func ExampleScan() {
 		`GET /users/:user`,
 		`DELETE /users/:user([a-zA-Z]{6,20})`,
 		`GET /teams/:team([a-z]{4}){7-15}/static/:path*{3}`,
+		`GET /users/:user?since&age{18-120}`,
 	}
 	for _, pattern := range patterns {
 		toks, err := Scan(pattern)
@@ -165,6 +166,23 @@ func ExampleScan() {
 	//   20: token "3" (NUMBER) at rune 47 (byte 47)
 	//   21: token "}" (RBRACE) at rune 48 (byte 48)
 	//   22: token (EOF) at rune 49 (byte 49)
+	// Pattern: GET /users/:user?since&age{18-120}
+	//    1: token "GET" (METHOD) at rune 1
+	//    2: token "/" (FSLASH) at rune 4 (byte 4)
+	//    3: token "users" (SEGMENT) at rune 5 (byte 5)
+	//    4: token "/" (FSLASH) at rune 10 (byte 10)
+	//    5: token ":" (COLON) at rune 11 (byte 11)
+	//    6: token "user" (IDENT) at rune 12 (byte 12)
+	//    7: token "?" (QUEST) at rune 16 (byte 16)
+	//    8: token "since" (IDENT) at rune 17 (byte 17)
+	//    9: token "&" (AMPER) at rune 22 (byte 22)
+	//   10: token "age" (IDENT) at rune 23 (byte 23)
+	//   11: token "{" (LBRACE) at rune 26 (byte 26)
+	//   12: token "18" (NUMBER) at rune 27 (byte 27)
+	//   13: token "-" (MINUS) at rune 29 (byte 29)
+	//   14: token "120" (NUMBER) at rune 30 (byte 30)
+	//   15: token "}" (RBRACE) at rune 33 (byte 33)
+	//   16: token (EOF) at rune 34 (byte 34)
 }
 
 func ExampleTrace() {
@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/cstockton/routepiler/internal/token"
+)
+
+func FuzzScan(f *testing.F) {
+	for _, test := range Tests(`valid`, `invalid`) {
+		f.Add(test.Pat)
+	}
+	f.Add(":(\n") // a whitespace-only multi-line regexp once panicked
+	f.Fuzz(func(t *testing.T, p string) {
+		if len(p) > 0xfff || strings.Count(p, "\n") >= 0xff {
+			t.Skip(`position exceeds the bounds of a token.Pos`)
+		}
+
+		var s Scanner
+		s.Reset(p)
+		var toks []token.Token
+		for s.More() {
+			if len(toks) > len(p)+1 {
+				t.Fatalf(`exp at most %d tokens; got %v`, len(p)+1, toks)
+			}
+			toks = append(toks, s.Scan())
+		}
+
+		// positions never move backwards and stay within the pattern
+		var prev token.Pos
+		for i, tok := range toks {
+			beg, end := tok.Beg, tok.End
+			if !beg.Valid() || !end.Valid() {
+				t.Fatalf(`token #%d %v: exp valid positions`, i, tok)
+			}
+			if beg.Offset() < prev.Offset() || end.Offset() < beg.Offset() ||
+				end.Offset() > len(p) {
+				t.Fatalf(`token #%d %v: exp %d <= beg %d <= end %d <= %d`,
+					i, tok, prev.Offset(), beg.Offset(), end.Offset(), len(p))
+			}
+			if beg.Column() < prev.Column() || end.Column() < beg.Column() ||
+				end.Column() > utf8.RuneCountInString(p)+1 {
+				t.Fatalf(`token #%d %v: exp column %d <= beg %d <= end %d`,
+					i, tok, prev.Column(), beg.Column(), end.Column())
+			}
+			prev = end
+		}
+	})
+}
@@ -106,6 +106,12 @@ func lex(r rune) (l token.Lexeme) {
 	case '*':
 		l = token.WILD
 
+	// Query string
+	case '?':
+		l = token.QUEST
+	case '&':
+		l = token.AMPER
+
 	// String literal
 	case '`':
 		l = token.BQUOTE
@@ -29,6 +29,10 @@ func TestLex(t *testing.T) {
 		{'-', MINUS},
 		{'*', WILD},
 
+		// Query string
+		{'?', QUEST},
+		{'&', AMPER},
+
 		// Balanced lhs & rhs
 		{'(', LPAREN},
 		{')', RPAREN},
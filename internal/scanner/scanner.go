@@ -85,6 +85,14 @@ func Trace(w io.Writer, p string) (toks []token.Token, err error) {
 	return toks, s.Err()
 }
 
+// Error is returned from Err when a pattern could not be scanned.
+type Error struct {
+	Off int    // byte offset within the pattern
+	Msg string // message including the offset
+}
+
+func (e *Error) Error() string { return e.Msg }
+
 // Scanner will produce tokens from patterns.
 type Scanner struct {
 	pat   string      // source pattern
@@ -94,6 +102,8 @@ type Scanner struct {
 	rdOff int         // read offset within pat (off + utf8.RuneLen(ch))
 	ch1   rune        // cur rune decoded from s.pat[s.off:s.rdOff]
 	ch2   rune        // 1 rune lookahead
+	tpl   int         // depth of template braces
+	qry   bool        // true once a QUEST begins the query declaration
 	err   error
 }
 
@@ -152,6 +162,17 @@ func (s *Scanner) Scan() token.Token {
 		s.unexpected(s.ch1,
 			token.METHOD, token.FSLASH, token.SEGMENT, token.COLON, token.LBRACE)
 	}
+
+	switch tok.Lex {
+	case token.LBRACE:
+		s.tpl++
+	case token.RBRACE:
+		s.tpl--
+	case token.QUEST:
+		// A QUEST followed by a param name begins the query declaration, otherwise
+		// it marks the preceding param as optional as in `:month?` or `{month?}`.
+		s.qry = s.qry || isIdentStart(s.peek())
+	}
 	s.tok = tok
 	return tok
 }
@@ -165,12 +186,17 @@ func (s *Scanner) scan(tok *token.Token) {
 	switch s.tok.Lex {
 	case scanRST:
 		s.scanReset(tok)
-	case token.RBRACE:
-		// continuation of a multi-template segment, here we want
-		// to scan until we come to a path sep or additional lbrace.
-		s.scanPath(tok)
 	case token.FSLASH, token.SEGMENT, token.METHOD:
 		s.scanPath(tok)
+	case token.RBRACE, token.IDENT, token.REGEXP, token.WILD, token.RBRACK,
+		token.QUEST:
+		if s.tpl > 0 || s.qry {
+			// templates and the query declaration have no path segments, the
+			// latter being query params separated by AMPER.
+			s.scanPattern(tok)
+			break
+		}
+		s.scanParam(tok)
 	default:
 		s.scanPattern(tok)
 	}
@@ -207,6 +233,27 @@ func (s *Scanner) scanReset(tok *token.Token) {
 	}
 }
 
+// scanParam is called after each part of a path param. It allows the param to
+// continue with a regexp, wildcard, template or optional marker, otherwise it
+// assumes a literal continuing the path segment such as the "." in
+// `:name.:ext`. Two params with no literal between them are rejected since
+// there is no way to tell where one ends and the next begins.
+func (s *Scanner) scanParam(tok *token.Token) {
+	switch l := lex(s.ch1); {
+	case l == token.COLON, l == token.LBRACE && s.tok.Lex == token.RBRACE:
+		s.adjacent(s.ch1)
+	case s.tok.Lex == token.RBRACE:
+		// continuation of a multi-template segment, here we want
+		// to scan until we come to a path sep or additional lbrace.
+		s.scanPath(tok)
+	case l == token.LPAREN, l == token.LBRACE, l == token.LBRACK,
+		l == token.WILD, l == token.QUEST, l == token.WHITESPACE:
+		s.scanPattern(tok)
+	default:
+		s.scanPath(tok)
+	}
+}
+
 // scanPath is called at the start of each path segment. It allows leading white
 // space and requires a colon to indicate the begining of a pattern or assumes a
 // path segment literal or partial tpl set.
@@ -214,13 +261,15 @@ func (s *Scanner) scanPath(tok *token.Token) {
 	s.scanWhitespace(tok)
 
 	switch l := lex(s.ch1); l {
-	case token.COLON, token.FSLASH, token.LBRACE:
+	case token.COLON, token.FSLASH, token.LBRACE, token.QUEST:
 		tok.Lex, tok.Lit = l, string(s.ch1)
 	case token.EOF:
 		tok.Lex = l
 	default:
+		// a literal ends at the next param, whether it begins the segment as in
+		// `v:version` or continues it after a param as in `:name.:ext`
 		tok.Lex, tok.Lit = token.SEGMENT, scanPred(s, func(r rune) bool {
-			return isSegment(r) && '{' != r
+			return isSegment(r) && '{' != r && '?' != r && ':' != r
 		})
 	}
 }
@@ -245,6 +294,10 @@ func (s *Scanner) scanPattern(tok *token.Token) {
 			lit := scanBalanced(s, '(', ')')
 			lhi := strings.IndexFunc(lit, isInverse(isWhitespace))
 			rhi := strings.LastIndexFunc(lit, isInverse(isWhitespace))
+			if lhi < 0 {
+				tok.Lex, tok.Lit = token.REGEXP, ``
+				break
+			}
 
 			tb, te := tok.Beg, tok.End
 			tb.Set(tb.Line(), tb.Column()+lhi, tb.Offset()+lhi)
@@ -260,8 +313,10 @@ func (s *Scanner) scanPattern(tok *token.Token) {
 	case token.IDENT:
 		tok.Lex, tok.Lit = l, scanPred(s, isIdentStart, isIdent)
 	case token.LIT:
+		// QUEST and AMPER within a literal such as the lazy quantifier in `.+?`
+		// belong to the literal rather than starting a query declaration.
 		tok.Lex, tok.Lit = l, scanPred(s, func(r rune) bool {
-			return lex(r) == token.LIT
+			return isAny(r, '?', '&') || lex(r) == token.LIT
 		})
 	case token.DIGIT:
 		tok.Lex, tok.Lit = token.NUMBER, scanPred(s, isDigit)
@@ -290,12 +345,12 @@ func (s *Scanner) decode(off int) (rune, int) {
 	case r == utf8.RuneError && w == 0:
 		r = scanEOF
 	case r == utf8.RuneError && w == 1:
-		s.fail(`illegal UTF-8 encoding at byte %v`, off)
+		s.failAt(off, `illegal UTF-8 encoding at byte %v`, off)
 	case r == runeNUL:
-		s.fail(`illegal NUL character at byte %v`, off)
+		s.failAt(off, `illegal NUL character at byte %v`, off)
 	case r == runeBOM:
 		if off != 0 {
-			s.fail(`illegal byte order marker at byte %v`, off)
+			s.failAt(off, `illegal byte order marker at byte %v`, off)
 		} else {
 			r, w = s.decode(3)
 			w += 3
@@ -372,6 +427,11 @@ func (s *Scanner) unbalanced(got, lhs, rhs rune, depth int) bool {
 		lex(lhs), depth, lex(rhs), lex(got), s.off)
 }
 
+func (s *Scanner) adjacent(got rune) bool {
+	return s.fail(`adjacent %v at byte %v, params within a path segment must be `+
+		`separated by a literal`, lex(got), s.off)
+}
+
 func (s *Scanner) ambiguous(got rune, suggestions ...string) bool {
 	if s.err != nil {
 		return false
@@ -392,8 +452,13 @@ func (s *Scanner) ambiguous(got rune, suggestions ...string) bool {
 }
 
 func (s *Scanner) fail(msg string, args ...interface{}) bool {
+	return s.failAt(s.off, msg, args...)
+}
+
+func (s *Scanner) failAt(off int, msg string, args ...interface{}) bool {
 	if s.err == nil {
-		s.ch1, s.ch2, s.err = scanEOF, scanRST, fmt.Errorf(msg, args...)
+		s.ch1, s.ch2 = scanEOF, scanRST
+		s.err = &Error{Off: off, Msg: fmt.Sprintf(msg, args...)}
 	}
 	return false
 }
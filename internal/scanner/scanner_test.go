@@ -2,8 +2,10 @@ package scanner
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -328,11 +330,11 @@ func TestScannerAdvancement(t *testing.T) {
 				}
 				if exp, got := expOff, s.off; exp != got {
 					t.Fatalf("unexpected scanner off:\n%v",
-						unibox.MarkExp(test.pat, exp, got))
+						unibox.MarkExp(test.pat, strconv.Itoa(exp), got))
 				}
 				if exp, got := expRdOff, s.rdOff; exp != got {
 					t.Fatalf("unexpected scanner rdOff:\n%v",
-						unibox.MarkExp(test.pat, exp, got))
+						unibox.MarkExp(test.pat, strconv.Itoa(exp), got))
 				}
 				if exp != s.ch1 {
 					t.Fatalf("exp next() to set s.ch1 to %v; got %v", exp, got)
@@ -546,3 +548,60 @@ func TestScannerFailures(t *testing.T) {
 		}
 	}
 }
+
+func TestLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		pat string
+		exp []Lexeme
+	}{
+		{`/v:version`, []Lexeme{FSLASH, SEGMENT, COLON, IDENT, EOF}},
+		{`/files/:name.:ext`, []Lexeme{FSLASH, SEGMENT, FSLASH, COLON, IDENT,
+			SEGMENT, COLON, IDENT, EOF}},
+		{`/v%3Aversion`, []Lexeme{FSLASH, SEGMENT, EOF}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from %q exp lexemes %v`, idx, test.pat, test.exp)
+		toks, err := Scan(test.pat)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		var got []Lexeme
+		for _, tok := range toks {
+			got = append(got, tok.Lex)
+		}
+		if exp := test.exp; fmt.Sprint(exp) != fmt.Sprint(got) {
+			t.Fatalf(`exp lexemes %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		pat string
+		off int
+	}{
+		{`GET`, 2},
+		{`/:a:bb`, 3},
+		{`/v:a:bb`, 4},
+		{`/:aa([0-9]`, 10},
+		{sw1x4 + "\x00", 4},
+		{sw4 + "\uFEFF", 4},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from %q exp err at byte %v`, idx, test.pat, test.off)
+		_, err := Scan(test.pat)
+		if err == nil {
+			t.Fatal(`exp non-nil err`)
+		}
+		serr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf(`exp *Error; got %T`, err)
+		}
+		if exp, got := test.off, serr.Off; exp != got {
+			t.Fatalf(`exp Off %v; got %v`, exp, got)
+		}
+		if exp, got := strconv.Itoa(test.off), serr.Error(); !strings.Contains(got, exp) {
+			t.Fatalf(`exp Error() %v to contain %v`, got, exp)
+		}
+	}
+}
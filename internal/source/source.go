@@ -0,0 +1,443 @@
+// Package source indexes the declarations of a Go package which route struct
+// tags refer to, such as the struct types bound to params and the handlers
+// named by func tags.
+package source
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cstockton/routepiler/internal/scanner"
+	"github.com/cstockton/routepiler/internal/tag"
+	"github.com/cstockton/routepiler/internal/token"
+)
+
+// Methods are the http methods in the form of route tag keys, in the order
+// handlers are resolved for a path route without a method.
+var Methods = []string{
+	`get`, `head`, `post`, `put`, `patch`, `delete`, `connect`, `options`, `trace`}
+
+// Package is an index of the top level declarations within a Go package.
+type Package struct {
+	Structs    map[string]*ast.StructType
+	Interfaces map[string]*ast.InterfaceType
+	Methods    map[string]map[string]*ast.FuncDecl // by receiver type name
+	Funcs      map[string]*ast.FuncDecl
+	Vars       map[string]*ast.Ident
+}
+
+// New returns the index of the given files of a single package, nil files are
+// ignored.
+func New(files []*ast.File) *Package {
+	pkg := &Package{
+		Structs:    make(map[string]*ast.StructType),
+		Interfaces: make(map[string]*ast.InterfaceType),
+		Methods:    make(map[string]map[string]*ast.FuncDecl),
+		Funcs:      make(map[string]*ast.FuncDecl),
+		Vars:       make(map[string]*ast.Ident),
+	}
+	for _, f := range files {
+		if f != nil {
+			pkg.add(f)
+		}
+	}
+	return pkg
+}
+
+// ParseDir parses the non-test Go files within dir and returns the name and the
+// files of the package declaring the named struct, in order of position. When
+// name is empty dir must contain a single package.
+func ParseDir(fset *gotoken.FileSet, dir, name string) (string, []*ast.File, error) {
+	pkgs, err := goparser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), `_test.go`)
+	}, 0)
+	if err != nil {
+		return ``, nil, err
+	}
+	if name == `` && len(pkgs) != 1 {
+		return ``, nil, fmt.Errorf(`exp a single package in %v; got %d`, dir, len(pkgs))
+	}
+
+	var names []string
+	for pkgName := range pkgs {
+		names = append(names, pkgName)
+	}
+	sort.Strings(names)
+	for _, pkgName := range names {
+		var files []*ast.File
+		for _, f := range pkgs[pkgName].Files {
+			files = append(files, f)
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Pos() < files[j].Pos() })
+		if name == `` || New(files).Structs[name] != nil {
+			return pkgName, files, nil
+		}
+	}
+	return ``, nil, fmt.Errorf(`router struct %v not found in %v`, name, dir)
+}
+
+func (pkg *Package) add(f *ast.File) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				pkg.Funcs[d.Name.Name] = d
+				continue
+			}
+			recv := TypeName(d.Recv.List[0].Type)
+			if pkg.Methods[recv] == nil {
+				pkg.Methods[recv] = make(map[string]*ast.FuncDecl)
+			}
+			pkg.Methods[recv][d.Name.Name] = d
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						pkg.Structs[s.Name.Name] = t
+					case *ast.InterfaceType:
+						pkg.Interfaces[s.Name.Name] = t
+					}
+				case *ast.ValueSpec:
+					for _, id := range s.Names {
+						pkg.Vars[id.Name] = id
+					}
+				}
+			}
+		}
+	}
+}
+
+// Struct returns the name of the struct type declared in this package for the
+// type expression T or *T, or an empty string.
+func (pkg *Package) Struct(expr ast.Expr) string {
+	if name := TypeName(expr); pkg.Structs[name] != nil {
+		return name
+	}
+	return ``
+}
+
+// Field is a single named field of a struct.
+type Field struct {
+	Name  string
+	Field *ast.Field
+}
+
+// Fields returns each named field of a struct including the fields promoted
+// from embedded structs declared in this package. Fields of the outer struct
+// shadow promoted fields of the same name.
+func (pkg *Package) Fields(name string) (out []Field) {
+	seen := make(map[string]bool)
+	for depth := []string{name}; len(depth) > 0; {
+		var next []string
+		for _, name := range depth {
+			st := pkg.Structs[name]
+			if st == nil || seen[`type `+name] {
+				continue
+			}
+			seen[`type `+name] = true
+			for _, fd := range st.Fields.List {
+				if len(fd.Names) == 0 {
+					next = append(next, TypeName(fd.Type))
+					continue
+				}
+				for _, id := range fd.Names {
+					if !seen[id.Name] {
+						seen[id.Name] = true
+						out = append(out, Field{Name: id.Name, Field: fd})
+					}
+				}
+			}
+		}
+		depth = next
+	}
+	return
+}
+
+// Field returns the field of the named struct bound to the given param, which
+// is the first field with a name equal under Unicode case-folding.
+func (pkg *Package) Field(name, param string) (Field, bool) {
+	for _, f := range pkg.Fields(name) {
+		if strings.EqualFold(f.Name, param) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Embed is an embedded field of a struct which a promoted field is reached
+// through.
+type Embed struct {
+	Name string // name of the embedded field, which is its type name
+	Type string // name of the struct type of the embedded field
+	Ptr  bool   // the embedded field is a pointer to its type
+}
+
+// Path is a field of a struct along with the embedded fields it is promoted
+// through, outermost first.
+type Path struct {
+	Field
+	Embeds []Embed
+}
+
+// String returns the selector of the field relative to the outer struct, such
+// as Orgs.Org.
+func (p Path) String() string {
+	var b strings.Builder
+	for _, e := range p.Embeds {
+		b.WriteString(e.Name + `.`)
+	}
+	return b.String() + p.Name
+}
+
+// Candidates returns the named fields of the named struct with a name equal to
+// param under Unicode case-folding, including fields promoted from the embedded
+// structs declared in this package. As with Go selectors only the candidates
+// of the shallowest depth are returned, so a field of the outer struct shadows
+// a promoted field, and more than one candidate is ambiguous.
+func (pkg *Package) Candidates(name, param string) (out []Path) {
+	type outer struct {
+		typ    string
+		embeds []Embed
+	}
+	seen := make(map[string]bool)
+	for depth := []outer{{typ: name}}; len(depth) > 0 && len(out) == 0; {
+		var next []outer
+		for _, o := range depth {
+			st := pkg.Structs[o.typ]
+			if st == nil || seen[o.typ] {
+				continue
+			}
+			embeds := o.embeds[:len(o.embeds):len(o.embeds)]
+			for _, fd := range st.Fields.List {
+				if len(fd.Names) == 0 {
+					_, ptr := fd.Type.(*ast.StarExpr)
+					e := Embed{Name: TypeName(fd.Type), Type: TypeName(fd.Type), Ptr: ptr}
+					if pkg.Structs[e.Type] != nil && !isSelector(fd.Type) {
+						next = append(next, outer{typ: e.Type, embeds: append(embeds, e)})
+					}
+					continue
+				}
+				for _, id := range fd.Names {
+					if strings.EqualFold(id.Name, param) {
+						out = append(out, Path{Field: Field{Name: id.Name, Field: fd}, Embeds: embeds})
+					}
+				}
+			}
+		}
+
+		// types are visited once per depth so a type embedded twice at the same
+		// depth makes its fields ambiguous, as it does for Go selectors
+		for _, o := range depth {
+			seen[o.typ] = true
+		}
+		depth = next
+	}
+	return out
+}
+
+func isSelector(expr ast.Expr) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	_, ok := expr.(*ast.SelectorExpr)
+	return ok
+}
+
+// Handler is the declaration which serves the requests of a route field.
+type Handler struct {
+	Name  string        // such as GetOrg, Users.Get or the field name
+	Func  *ast.FuncDecl // the func or method, nil for a var or field
+	Field *ast.Field    // the route field when it serves itself
+	Ident *ast.Ident    // identifier of the declaration
+}
+
+// String returns the func signature, or the var or field declaration.
+func (h Handler) String() string {
+	switch {
+	case h.Field != nil:
+		return `field ` + h.Name + ` ` + types.ExprString(h.Field.Type)
+	case h.Func == nil:
+		return `var ` + h.Name
+	}
+	sig := strings.TrimPrefix(types.ExprString(h.Func.Type), `func`)
+	if h.Func.Recv == nil || len(h.Func.Recv.List) == 0 {
+		return `func ` + h.Func.Name.Name + sig
+	}
+	return `func (` + types.ExprString(h.Func.Recv.List[0].Type) + `) ` +
+		h.Func.Name.Name + sig
+}
+
+// Handler returns the handler of a route field for the given http method, or
+// any method when method is empty. Struct fields are served by the method named
+// by a func tag, otherwise the method matching the http method or ServeHTTP.
+// Any other field is served by the func or var named by a func tag, or the
+// field itself.
+func (pkg *Package) Handler(fd *ast.Field, ps tag.Pairs, method string) (Handler, error) {
+	typ := pkg.Struct(fd.Type)
+	fn, hasFunc := ps.Lookup(`func`)
+	switch {
+	case hasFunc && typ != ``:
+		if d := pkg.Methods[typ][fn.Value]; d != nil {
+			return Handler{Name: fn.Value, Func: d, Ident: d.Name}, nil
+		}
+		return Handler{}, fmt.Errorf(`func tag names %v which is not a method of %v`,
+			fn.Value, typ)
+	case hasFunc:
+		if d := pkg.Funcs[fn.Value]; d != nil {
+			return Handler{Name: fn.Value, Func: d, Ident: d.Name}, nil
+		}
+		if id := pkg.Vars[fn.Value]; id != nil {
+			return Handler{Name: fn.Value, Ident: id}, nil
+		}
+		return Handler{}, fmt.Errorf(`func tag names %v which is not a func or var`,
+			fn.Value)
+	case typ != ``:
+		var names []string
+		for _, m := range Methods {
+			if method == `` || method == m {
+				names = append(names, MethodName(m))
+			}
+		}
+		names = append(names, `ServeHTTP`)
+		for _, name := range names {
+			if d := pkg.Methods[typ][name]; d != nil {
+				return Handler{Name: typ + `.` + name, Func: d, Ident: d.Name}, nil
+			}
+		}
+		return Handler{}, fmt.Errorf(`%v has no %v method`,
+			typ, strings.Join(names, ` or `))
+	case len(fd.Names) > 0:
+		return Handler{Name: fd.Names[0].Name, Ident: fd.Names[0], Field: fd}, nil
+	}
+	return Handler{}, fmt.Errorf(`embedded field %v has no handler`,
+		types.ExprString(fd.Type))
+}
+
+// Route is a route tag resolved to the handler of a single http method.
+type Route struct {
+	Method  string // lower case http method, empty for any method
+	Handler Handler
+}
+
+// Routes returns the route for each http method served by the route tag p of a
+// field. A path route without a http method is a route for each http method its
+// struct type has a method for, along with a route for any other method when it
+// has a ServeHTTP method. A path route of a field which is not a struct or has
+// a func tag is a route for any method.
+func (pkg *Package) Routes(fd *ast.Field, ps tag.Pairs, p tag.Pair) ([]Route, error) {
+	var methods []string
+	typ := pkg.Struct(fd.Type)
+	_, hasFunc := ps.Lookup(`func`)
+	switch m := Method(ps, p); {
+	case m != ``:
+		methods = []string{m}
+	case hasFunc || typ == ``:
+		methods = []string{``}
+	default:
+		for _, m := range Methods {
+			if pkg.Methods[typ][MethodName(m)] != nil {
+				methods = append(methods, m)
+			}
+		}
+		if len(methods) == 0 || pkg.Methods[typ][`ServeHTTP`] != nil {
+			methods = append(methods, ``)
+		}
+	}
+
+	out := make([]Route, len(methods))
+	for i, m := range methods {
+		if m == `` && !hasFunc && pkg.Methods[typ][`ServeHTTP`] != nil {
+			d := pkg.Methods[typ][`ServeHTTP`]
+			out[i] = Route{Handler: Handler{Name: typ + `.ServeHTTP`, Func: d, Ident: d.Name}}
+			continue
+		}
+		h, err := pkg.Handler(fd, ps, m)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = Route{Method: m, Handler: h}
+	}
+	return out, nil
+}
+
+// Method returns the http method of a route from its key, a method tag or the
+// METHOD token of the route pattern. It returns an empty string for a path
+// route which serves any method.
+func Method(ps tag.Pairs, route tag.Pair) string {
+	if route.Key != `path` {
+		return route.Key
+	}
+	if m, ok := ps.Lookup(`method`); ok {
+		return strings.ToLower(m.Value)
+	}
+	if toks, err := scanner.Scan(route.Value); err == nil && toks[0].Lex == token.METHOD {
+		return strings.ToLower(toks[0].Lit)
+	}
+	return ``
+}
+
+// MethodName returns the name of the method which serves the given http method,
+// such as Get for get.
+func MethodName(method string) string {
+	if method == `` {
+		return ``
+	}
+	return strings.ToUpper(method[:1]) + strings.ToLower(method[1:])
+}
+
+// TypeName returns the name of a type expression T or *T.
+func TypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return TypeName(t.X)
+	}
+	return ``
+}
+
+// ParamName returns name with each rune which may not appear in a param name
+// removed, capitalizing the rune which follows, such as userId for user-id. It
+// returns an empty string when no runes remain.
+func ParamName(name string) string {
+	var (
+		out   []rune
+		upper bool
+	)
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_' || len(out) > 0 && unicode.IsDigit(r):
+			if upper && len(out) > 0 {
+				r = unicode.ToUpper(r)
+			}
+			out = append(out, r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	return string(out)
+}
+
+// ExportedName returns the exported Go identifier for the words of s, such as
+// GetUsersUser for "get /users/{user}", or an empty string.
+func ExportedName(s string) string {
+	out := []rune(ParamName(`_ ` + s))
+	for len(out) > 0 && !unicode.IsLetter(out[0]) {
+		out = out[1:]
+	}
+	if len(out) == 0 {
+		return ``
+	}
+	out[0] = unicode.ToUpper(out[0])
+	return string(out)
+}
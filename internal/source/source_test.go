@@ -0,0 +1,342 @@
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+const testSrc = `package main
+
+import "net/http"
+
+type Router struct {
+	Root  http.Handler
+	Date  func(http.ResponseWriter, *http.Request)
+	Orgs  Orgs
+	Users *Users
+}
+
+var handleTime = http.NotFoundHandler()
+
+func Echo(w http.ResponseWriter, r *http.Request) error { return nil }
+
+type Orgs struct {
+	Org  string
+	Name string
+}
+
+func (h *Orgs) Post(w http.ResponseWriter, r *http.Request) {}
+func (h Orgs) GetOrg(w http.ResponseWriter, r *http.Request) {}
+
+type Users struct {
+	*Orgs
+	User, Name string
+	db         bool
+}
+
+func (h *Users) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+`
+
+func testPackage(t *testing.T) (*Package, map[string]*ast.Field) {
+	f, err := parser.ParseFile(token.NewFileSet(), ``, testSrc, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	pkg := New([]*ast.File{f, nil})
+	fields := make(map[string]*ast.Field)
+	for _, fd := range pkg.Structs[`Router`].Fields.List {
+		fields[fd.Names[0].Name] = fd
+	}
+	return pkg, fields
+}
+
+func TestFields(t *testing.T) {
+	pkg, _ := testPackage(t)
+	tests := []struct {
+		typ string
+		exp []string
+	}{
+		{`Orgs`, []string{`Org`, `Name`}},
+		{`Users`, []string{`User`, `Name`, `db`, `Org`}},
+		{`Bogus`, nil},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp Fields(%v) to return %v`, idx, test.typ, test.exp)
+		var got []string
+		for _, f := range pkg.Fields(test.typ) {
+			got = append(got, f.Name)
+		}
+		if exp := test.exp; !reflect.DeepEqual(exp, got) {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+
+	f, ok := pkg.Field(`Users`, `ORG`)
+	if !ok {
+		t.Fatal(`exp promoted field Org`)
+	}
+	if exp, got := `Org`, f.Name; exp != got {
+		t.Fatalf(`exp %v; got %v`, exp, got)
+	}
+	if _, ok := pkg.Field(`Users`, `Orgs`); ok {
+		t.Fatal(`exp embedded type to not bind params`)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	const src = `package main
+
+type Member struct {
+	*Orgs
+	Teams
+	Org string
+}
+
+type Orgs struct {
+	Org string
+	ID  int
+}
+
+type Teams struct {
+	Inner
+	ID   int
+	Name string
+}
+
+type Inner struct {
+	Name, Role string
+}
+
+type Both struct {
+	Orgs
+	*Teams
+}
+
+type Fold struct {
+	User, USER string
+}
+
+type Loop struct {
+	*Loop
+	Next *Loop
+}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), ``, src, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	pkg := New([]*ast.File{f})
+
+	tests := []struct {
+		typ, param string
+		exp        []string
+	}{
+		{`Member`, `org`, []string{`Org`}},
+		{`Member`, `id`, []string{`Orgs.ID`, `Teams.ID`}},
+		{`Member`, `name`, []string{`Teams.Name`}},
+		{`Member`, `role`, []string{`Teams.Inner.Role`}},
+		{`Member`, `orgs`, nil},
+		{`Both`, `org`, []string{`Orgs.Org`}},
+		{`Both`, `id`, []string{`Orgs.ID`, `Teams.ID`}},
+		{`Fold`, `user`, []string{`User`, `USER`}},
+		{`Loop`, `next`, []string{`Next`}},
+		{`Loop`, `bogus`, nil},
+		{`Bogus`, `id`, nil},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp Candidates(%v, %v) to return %v`,
+			idx, test.typ, test.param, test.exp)
+		var got []string
+		for _, p := range pkg.Candidates(test.typ, test.param) {
+			got = append(got, p.String())
+		}
+		if exp := test.exp; !reflect.DeepEqual(exp, got) {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+
+	ps := pkg.Candidates(`Both`, `name`)
+	if len(ps) != 1 {
+		t.Fatalf(`exp 1 candidate; got %v`, len(ps))
+	}
+	if exp, got := []Embed{{`Teams`, `Teams`, true}}, ps[0].Embeds; !reflect.DeepEqual(exp, got) {
+		t.Fatalf(`exp %v; got %v`, exp, got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	pkg, fields := testPackage(t)
+	tests := []struct {
+		field  string
+		tag    string
+		method string
+		exp    string
+	}{
+		{`Orgs`, `get:"/orgs/:org" func:"GetOrg"`, `get`,
+			`func (Orgs) GetOrg(w http.ResponseWriter, r *http.Request)`},
+		{`Orgs`, `post:"/orgs"`, `post`,
+			`func (*Orgs) Post(w http.ResponseWriter, r *http.Request)`},
+		{`Orgs`, `path:"/orgs"`, ``,
+			`func (*Orgs) Post(w http.ResponseWriter, r *http.Request)`},
+		{`Users`, `get:"/users"`, `get`,
+			`func (*Users) ServeHTTP(w http.ResponseWriter, r *http.Request)`},
+		{`Root`, `path:"/time" func:"handleTime"`, ``, `var handleTime`},
+		{`Root`, `path:"/echo" func:"Echo"`, ``,
+			`func Echo(w http.ResponseWriter, r *http.Request) error`},
+		{`Date`, `path:"/date"`, ``,
+			`field Date func(http.ResponseWriter, *http.Request)`},
+
+		// errors
+		{`Orgs`, `get:"/orgs"`, `get`, `Orgs has no Get or ServeHTTP method`},
+		{`Orgs`, `get:"/orgs" func:"Bogus"`, `get`,
+			`func tag names Bogus which is not a method of Orgs`},
+		{`Root`, `get:"/" func:"Bogus"`, `get`,
+			`func tag names Bogus which is not a func or var`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp handler of %v %v to be %v`,
+			idx, test.field, test.tag, test.exp)
+		ps, err := tag.Parse(test.tag)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		h, err := pkg.Handler(fields[test.field], ps, test.method)
+		got := h.String()
+		if err != nil {
+			got = err.Error()
+		}
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	pkg, fields := testPackage(t)
+	tests := []struct {
+		field string
+		tag   string
+		exp   []string // method and handler name of each route
+	}{
+		{`Orgs`, `post:"/orgs"`, []string{`post Orgs.Post`}},
+		{`Orgs`, `path:"/orgs"`, []string{`post Orgs.Post`}},
+		{`Orgs`, `path:"/orgs/:org" func:"GetOrg"`, []string{` GetOrg`}},
+		{`Users`, `path:"/users"`, []string{` Users.ServeHTTP`}},
+		{`Users`, `put:"/users"`, []string{`put Users.ServeHTTP`}},
+		{`Date`, `path:"/date"`, []string{` Date`}},
+		{`Root`, `path:"GET /"`, []string{`get Root`}},
+
+		// errors
+		{`Orgs`, `delete:"/orgs"`, []string{`Orgs has no Delete or ServeHTTP method`}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp routes of %v %v to be %v`,
+			idx, test.field, test.tag, test.exp)
+		ps, err := tag.Parse(test.tag)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		routes, err := pkg.Routes(fields[test.field], ps, ps.Routes()[0])
+		var got []string
+		for _, r := range routes {
+			got = append(got, r.Method+` `+r.Handler.Name)
+		}
+		if err != nil {
+			got = []string{err.Error()}
+		}
+		if exp := test.exp; !reflect.DeepEqual(exp, got) {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		`router.go`:      testSrc,
+		`router_test.go`: "package main\n\ntype Tested struct{}\n",
+		`other.go`:       "package main\n\ntype Other struct{}\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0600); err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+	}
+
+	for _, name := range []string{`Router`, ``} {
+		pkg, got, err := ParseDir(token.NewFileSet(), dir, name)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp := `main`; exp != pkg {
+			t.Fatalf(`exp %v; got %v`, exp, pkg)
+		}
+		if exp := 2; exp != len(got) {
+			t.Fatalf(`exp %v files; got %v`, exp, len(got))
+		}
+	}
+	for _, name := range []string{`Tested`, `Bogus`} {
+		if _, _, err := ParseDir(token.NewFileSet(), dir, name); err == nil {
+			t.Fatal(`exp non-nil err`)
+		}
+	}
+}
+
+func TestMethod(t *testing.T) {
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{`get:"/"`, `get`},
+		{`delete:"/"`, `delete`},
+		{`path:"/"`, ``},
+		{`path:"/" method:"GET"`, `get`},
+		{`path:"POST /"`, `post`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp Method(%v) to return %v`, idx, test.tag, test.exp)
+		ps, err := tag.Parse(test.tag)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp, got := test.exp, Method(ps, ps.Routes()[0]); exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+	if exp, got := `Options`, MethodName(`OPTIONS`); exp != got {
+		t.Fatalf(`exp %v; got %v`, exp, got)
+	}
+}
+
+func TestParamName(t *testing.T) {
+	tests := []struct {
+		in       string
+		param    string
+		exported string
+	}{
+		{`user`, `user`, `User`},
+		{`user-id`, `userId`, `UserId`},
+		{`get /users/{user}`, `getUsersUser`, `GetUsersUser`},
+		{`_id`, `_id`, `Id`},
+		{`2fa`, `fa`, `Fa`},
+		{`v2`, `v2`, `V2`},
+		{`-`, ``, ``},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp ParamName(%q) to return %q and ExportedName %q`,
+			idx, test.in, test.param, test.exported)
+		if exp, got := test.param, ParamName(test.in); exp != got {
+			t.Fatalf(`exp %q; got %q`, exp, got)
+		}
+		if exp, got := test.exported, ExportedName(test.in); exp != got {
+			t.Fatalf(`exp %q; got %q`, exp, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package tag parses the key value pairs of Go struct tags which declare routes
+// such as `get:"/orgs/:org" func:"GetOrg"`.
+package tag
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pair is a single key value pair within a struct tag.
+type Pair struct {
+	Key   string
+	Value string // unquoted value
+	Off   int    // byte offset of the quoted value within the tag
+}
+
+// Route returns true if this pair declares a route pattern.
+func (p Pair) Route() bool {
+	return IsRoute(p.Key)
+}
+
+// String returns the string representation of this pair in the form of
+// key:"value".
+func (p Pair) String() string {
+	return p.Key + `:` + strconv.Quote(p.Value)
+}
+
+// Pairs is a slice of pairs in the order they appeared in a tag.
+type Pairs []Pair
+
+// Lookup returns the first pair with the given key.
+func (ps Pairs) Lookup(key string) (Pair, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return Pair{}, false
+}
+
+// Routes returns each pair that declares a route pattern.
+func (ps Pairs) Routes() (out Pairs) {
+	for _, p := range ps {
+		if p.Route() {
+			out = append(out, p)
+		}
+	}
+	return
+}
+
+// String returns the conventional tag form of these pairs separated by a single
+// space.
+func (ps Pairs) String() string {
+	var buf bytes.Buffer
+	for i, p := range ps {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(p.String())
+	}
+	return buf.String()
+}
+
+var routeKeys = map[string]bool{
+	`path`:    true,
+	`get`:     true,
+	`head`:    true,
+	`post`:    true,
+	`put`:     true,
+	`patch`:   true,
+	`delete`:  true,
+	`connect`: true,
+	`options`: true,
+	`trace`:   true,
+}
+
+// IsRoute returns true if the given key declares a route pattern, which is the
+// key "path" or a lower case http method.
+func IsRoute(key string) bool {
+	return routeKeys[key]
+}
+
+// Parse returns each key value pair within tag using the conventional format
+// described by reflect.StructTag.
+func Parse(tag string) (ps Pairs, err error) {
+	for off := 0; off < len(tag); {
+		for off < len(tag) && tag[off] == ' ' {
+			off++
+		}
+		if off >= len(tag) {
+			break
+		}
+
+		beg := off
+		for off < len(tag) && tag[off] > ' ' && tag[off] != ':' &&
+			tag[off] != '"' && tag[off] != 0x7f {
+			off++
+		}
+		if off == beg || off+1 >= len(tag) || tag[off] != ':' ||
+			tag[off+1] != '"' {
+			return nil, fmt.Errorf(`bad syntax for struct tag pair at byte %v`, beg)
+		}
+
+		key, voff := tag[beg:off], off+1
+		for off = voff + 1; off < len(tag) && tag[off] != '"'; off++ {
+			if tag[off] == '\\' {
+				off++
+			}
+		}
+		if off >= len(tag) {
+			return nil, fmt.Errorf(`bad syntax for struct tag value at byte %v`, voff)
+		}
+
+		off++
+		val, err := strconv.Unquote(tag[voff:off])
+		if err != nil {
+			return nil, fmt.Errorf(`bad syntax for struct tag value at byte %v`, voff)
+		}
+		ps = append(ps, Pair{Key: key, Value: val, Off: voff})
+	}
+	return ps, nil
+}
+
+// Unquote returns the tag within the given Go string literal as found in the
+// Tag field of an ast.Field.
+func Unquote(lit string) (string, error) {
+	return strconv.Unquote(lit)
+}
+
+// ValueOffset returns the byte offset of the value of p within lit, the Go
+// string literal of the tag p was parsed from. It returns -1 when the value is
+// not written verbatim within lit, such as an interpreted string literal or a
+// value containing escapes.
+func ValueOffset(lit string, p Pair) int {
+	if !strings.HasPrefix(lit, "`") || len(lit) < p.Off+2 ||
+		!strings.HasPrefix(lit[1+p.Off:], `"`+p.Value+`"`) {
+		return -1
+	}
+	return 1 + p.Off + 1
+}
+
+// Quote returns a Go string literal for tag, preferring a raw string literal
+// when tag contains no back quotes.
+func Quote(tag string) string {
+	if !strconv.CanBackquote(tag) {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
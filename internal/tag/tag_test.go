@@ -0,0 +1,167 @@
+package tag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag string
+		exp Pairs
+	}{
+		{``, nil},
+		{`   `, nil},
+		{`get:"/"`, Pairs{{`get`, `/`, 4}}},
+		{`get:"/" func:"GetOrg"`, Pairs{
+			{`get`, `/`, 4}, {`func`, `GetOrg`, 13}}},
+		{`path:"/time" method:"get" func:"handleTime"`, Pairs{
+			{`path`, `/time`, 5}, {`method`, `get`, 20},
+			{`func`, `handleTime`, 31}}},
+		{`  min:"3"   max:"20"  `, Pairs{{`min`, `3`, 6}, {`max`, `20`, 16}}},
+		{`get:"/:a(\"b\")"`, Pairs{{`get`, `/:a("b")`, 4}}},
+		{`get:"/:a{'b': c}"`, Pairs{{`get`, `/:a{'b': c}`, 4}}},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %q exp %d pairs`, idx, test.tag, len(test.exp))
+		got, err := Parse(test.tag)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp, got := len(test.exp), len(got); exp != got {
+			t.Fatalf(`exp %d pairs; got %d`, exp, got)
+		}
+		for i := range got {
+			if exp, got := test.exp[i], got[i]; exp != got {
+				t.Fatalf("pair #%d was unexpected:\nexp: %#v\ngot: %#v\n", i, exp, got)
+			}
+			if exp, got := `"`, test.tag[got[i].Off:got[i].Off+1]; exp != got {
+				t.Fatalf(`exp Off to be at opening quote; got %q`, got)
+			}
+		}
+	}
+}
+
+func TestParseNegative(t *testing.T) {
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{`get`, `bad syntax for struct tag pair at byte 0`},
+		{`get:`, `bad syntax for struct tag pair at byte 0`},
+		{`get:/`, `bad syntax for struct tag pair at byte 0`},
+		{`:"/"`, `bad syntax for struct tag pair at byte 0`},
+		{`get:"/" post`, `bad syntax for struct tag pair at byte 8`},
+		{`get:"/`, `bad syntax for struct tag value at byte 4`},
+		{`get:"/\"`, `bad syntax for struct tag value at byte 4`},
+		{`get:"\q"`, `bad syntax for struct tag value at byte 4`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - from tag %q exp err %q`, idx, test.tag, test.exp)
+		_, err := Parse(test.tag)
+		if err == nil {
+			t.Fatal(`exp non-nil err`)
+		}
+		if exp, got := test.exp, err.Error(); !strings.Contains(got, exp) {
+			t.Fatalf(`exp err %v to contain %v`, got, exp)
+		}
+	}
+}
+
+func TestPairs(t *testing.T) {
+	ps, err := Parse(`path:"/time" method:"get" get:"/v1/time" func:"handleTime"`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	if p, ok := ps.Lookup(`func`); !ok || p.Value != `handleTime` {
+		t.Fatalf(`exp Lookup to find func pair; got %v`, p)
+	}
+	if p, ok := ps.Lookup(`post`); ok {
+		t.Fatalf(`exp Lookup to not find post pair; got %v`, p)
+	}
+
+	routes := ps.Routes()
+	if exp, got := 2, len(routes); exp != got {
+		t.Fatalf(`exp %d route pairs; got %d`, exp, got)
+	}
+	if exp, got := `path:"/time" get:"/v1/time"`, routes.String(); exp != got {
+		t.Fatalf(`exp String() %q; got %q`, exp, got)
+	}
+}
+
+func TestIsRoute(t *testing.T) {
+	tests := []struct {
+		is  bool
+		key string
+	}{
+		{true, `path`},
+		{true, `get`},
+		{true, `post`},
+		{true, `delete`},
+		{false, `GET`},
+		{false, `method`},
+		{false, `func`},
+		{false, `min`},
+		{false, ``},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp IsRoute(%q) to return %v`, idx, test.key, test.is)
+		if exp, got := test.is, IsRoute(test.key); exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		tag string
+		exp string
+	}{
+		{`get:"/"`, "`get:\"/\"`"},
+		{`get:"/:a('b')"`, "`get:\"/:a('b')\"`"},
+		{"get:\"/:a(`b`)\"", "\"get:\\\"/:a(`b`)\\\"\""},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp Quote(%q) to return %v`, idx, test.tag, test.exp)
+		got := Quote(test.tag)
+		if exp := test.exp; exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+		tag, err := Unquote(got)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		if exp, got := test.tag, tag; exp != got {
+			t.Fatalf(`exp Unquote to return %v; got %v`, exp, got)
+		}
+	}
+}
+
+func TestValueOffset(t *testing.T) {
+	tests := []struct {
+		lit string
+		key string
+		exp int
+	}{
+		{"`get:\"/\"`", `get`, 6},
+		{"`json:\"-\" get:\"/:a\"`", `get`, 15},
+		{"`get:\"/\\\\:a\"`", `get`, -1},
+		{"\"get:\\\"/\\\"\"", `get`, -1},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp ValueOffset(%v, %v) to return %v`,
+			idx, test.lit, test.key, test.exp)
+		str, err := Unquote(test.lit)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		ps, err := Parse(str)
+		if err != nil {
+			t.Fatalf(`exp nil err; got %v`, err)
+		}
+		p, _ := ps.Lookup(test.key)
+		if exp, got := test.exp, ValueOffset(test.lit, p); exp != got {
+			t.Fatalf(`exp %v; got %v`, exp, got)
+		}
+	}
+}
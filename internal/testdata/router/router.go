@@ -0,0 +1,80 @@
+// Package main declares the router shared by the tests of the packages which
+// generate code or documents from a router struct, each of which checks in its
+// own golden files.
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+type Router struct {
+	Root    http.Handler                                   `get:"/"`
+	Date    func(http.ResponseWriter, *http.Request)       `path:"/date"`
+	Echo    func(http.ResponseWriter, *http.Request) error `post:"/echo"`
+	Time    http.Handler                                   `path:"/time" method:"get" func:"handleTime"`
+	Orgs    Orgs                                           `path:"/orgs"`
+	Org     Orgs                                           `get:"/orgs/:org([a-z]+){3-20}" func:"GetOrg"`
+	User    Users                                          `get:"/orgs/:org/users/:user?since&page{default: 1}" func:"GetUser"`
+	Notify  Users                                          `put:"/orgs/:org/users/:user/notify/:when" func:"Notify"`
+	Search  Search                                         `get:"/search/:kind(it's|a\\d+)?q{required: true}&new&limit"`
+	Archive Archive                                        `get:"/archive/:year/:month?/:day?"`
+	Tags    Tags                                           `get:"/tags/:tag((?i)[a-z]+)"`
+	Files   Files                                          "path:\"/files/:path*\""
+	app     *App
+}
+
+type App struct{}
+
+var handleTime = http.NotFoundHandler()
+
+type Orgs struct {
+	Org string `json:"org"`
+}
+
+func (h *Orgs) Get(w http.ResponseWriter, r *http.Request, app *App)  {}
+func (h *Orgs) Post(w http.ResponseWriter, r *http.Request, app *App) {}
+func (h *Orgs) GetOrg(w http.ResponseWriter, r *http.Request)         {}
+
+type Users struct {
+	*Orgs
+	User  string `min:"3" max:"20"`
+	When  time.Duration
+	Since time.Time `min:"01-01-1850" max:"now"`
+	Page  int       `min:"1" max:"100"`
+}
+
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request) error { return nil }
+func (h *Users) Notify(w http.ResponseWriter, r *http.Request) error  { return nil }
+
+type Search struct {
+	Kind  string
+	Q     string `max:"100"`
+	New   bool
+	Limit uint8
+}
+
+func (h *Search) Get(w http.ResponseWriter, r *http.Request) {}
+
+type Archive struct {
+	Year, Month, Day int
+}
+
+func (h *Archive) Get(w http.ResponseWriter, r *http.Request) {}
+
+type Tags struct {
+	Tag string
+}
+
+func (h *Tags) Get(w http.ResponseWriter, r *http.Request) {}
+
+type Files struct {
+	Path string
+}
+
+func (h *Files) Get(w http.ResponseWriter, r *http.Request)       {}
+func (h *Files) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
+
+func main() {
+	http.ListenAndServe(":8080", new(Router).RoutesHandler())
+}
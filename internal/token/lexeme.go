@@ -53,6 +53,10 @@ const (
 	RBRACE // }
 	LBRACK // [
 	RBRACK // ]
+
+	// Query string
+	QUEST // ?
+	AMPER // &
 	termEnd
 
 	EOF // -1 end of file
@@ -93,6 +97,9 @@ var lexemes = map[Lexeme]string{
 	RBRACE:  `RBRACE`,
 	LBRACK:  `LBRACK`,
 	RBRACK:  `RBRACK`,
+
+	QUEST:   `QUEST`,
+	AMPER:   `AMPER`,
 	termEnd: `BAD`,
 
 	EOF: `EOF`,
@@ -0,0 +1,148 @@
+// Code generated by routepiler from Router. DO NOT EDIT.
+
+export interface RequestOptions {
+  /** Prepended to the path of each request, such as https://api.example.com. */
+  baseURL?: string;
+  /** Check params against the regexp and bounds of their route before sending. */
+  validate?: boolean;
+  /** Passed to fetch, the method is set by each function not serving any method. */
+  init?: RequestInit;
+}
+
+/** Thrown when a param fails validation. */
+export class ValidationError extends Error {}
+
+function checkString(name: string, value: string, pattern?: string, min?: number, max?: number): void {
+  if (pattern !== undefined && !new RegExp(pattern).test(value)) {
+    throw new ValidationError(`${name} must match ${pattern}`);
+  }
+  // lengths are counted in UTF-8 bytes like the router counts them
+  const n = new TextEncoder().encode(value).length;
+  if (min !== undefined && n < min) {
+    throw new ValidationError(`${name} must be at least ${min} bytes`);
+  }
+  if (max !== undefined && n > max) {
+    throw new ValidationError(`${name} must be at most ${max} bytes`);
+  }
+}
+
+function checkNumber(name: string, value: number, min?: number, max?: number): void {
+  if (min !== undefined && value < min) {
+    throw new ValidationError(`${name} must be at least ${min}`);
+  }
+  if (max !== undefined && value > max) {
+    throw new ValidationError(`${name} must be at most ${max}`);
+  }
+}
+
+function format(value: unknown): string {
+  return value instanceof Date ? value.toISOString() : String(value);
+}
+
+function encode(value: unknown): string {
+  return encodeURIComponent(format(value));
+}
+
+function encodeWild(value: string): string {
+  return value.split('/').map(encodeURIComponent).join('/');
+}
+
+function send(
+  method: string,
+  path: string,
+  query: Record<string, unknown>,
+  opts: RequestOptions,
+): Promise<Response> {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries(query)) {
+    if (value !== undefined) {
+      params.append(key, format(value));
+    }
+  }
+  const search = params.toString();
+  const url = (opts.baseURL ?? '') + path + (search ? '?' + search : '');
+  return fetch(url, { ...opts.init, method });
+}
+
+/** GET / */
+export function root(opts: RequestOptions = {}): Promise<Response> {
+  return send('GET', `/`, {}, opts);
+}
+
+/** GET /archive/{year}/{month}/{day} */
+export function archiveGet(year: number, month?: number, day?: number, opts: RequestOptions = {}): Promise<Response> {
+  return send('GET', `/archive/${encode(year)}${month === undefined ? '' : `/${encode(month)}${day === undefined ? '' : `/${encode(day)}`}`}`, {}, opts);
+}
+
+/** ANY /date */
+export function date(opts: RequestOptions = {}): Promise<Response> {
+  return send(opts.init?.method ?? 'GET', `/date`, {}, opts);
+}
+
+/** POST /echo */
+export function echo(opts: RequestOptions = {}): Promise<Response> {
+  return send('POST', `/echo`, {}, opts);
+}
+
+/** GET /files/{path} */
+export function filesGet(path: string, opts: RequestOptions = {}): Promise<Response> {
+  return send('GET', `/files/${encodeWild(path)}`, {}, opts);
+}
+
+/** GET /orgs */
+export function orgsGet(opts: RequestOptions = {}): Promise<Response> {
+  return send('GET', `/orgs`, {}, opts);
+}
+
+/** POST /orgs */
+export function orgsPost(opts: RequestOptions = {}): Promise<Response> {
+  return send('POST', `/orgs`, {}, opts);
+}
+
+/** GET /orgs/{org} */
+export function getOrg(org: string, opts: RequestOptions = {}): Promise<Response> {
+  if (opts.validate) {
+    checkString('org', org, '^(?:[a-z]+)$', 3, 20);
+  }
+  return send('GET', `/orgs/${encode(org)}`, {}, opts);
+}
+
+/** GET /orgs/{org}/users/{user} */
+export function getUser(org: string, user: string, query: { since?: Date; page?: number } = {}, opts: RequestOptions = {}): Promise<Response> {
+  if (opts.validate) {
+    checkString('user', user, undefined, 3, 20);
+    if (query.page !== undefined) checkNumber('page', query.page, 1, 100);
+  }
+  return send('GET', `/orgs/${encode(org)}/users/${encode(user)}`, query, opts);
+}
+
+/** PUT /orgs/{org}/users/{user}/notify/{when} */
+export function notify(org: string, user: string, when: string, opts: RequestOptions = {}): Promise<Response> {
+  if (opts.validate) {
+    checkString('user', user, undefined, 3, 20);
+  }
+  return send('PUT', `/orgs/${encode(org)}/users/${encode(user)}/notify/${encode(when)}`, {}, opts);
+}
+
+/** GET /search/{kind} */
+export function searchGet(kind: string, query: { q: string; new?: boolean; limit?: number }, opts: RequestOptions = {}): Promise<Response> {
+  if (opts.validate) {
+    checkString('kind', kind, '^(?:it\'s|a\\d+)$');
+    checkString('q', query.q, undefined, undefined, 100);
+  }
+  return send('GET', `/search/${encode(kind)}`, query, opts);
+}
+
+/**
+ * GET /tags/{tag}
+ *
+ * The pattern of tag is not checked, JavaScript does not support (?i).
+ */
+export function tagsGet(tag: string, opts: RequestOptions = {}): Promise<Response> {
+  return send('GET', `/tags/${encode(tag)}`, {}, opts);
+}
+
+/** GET /time */
+export function handleTime(opts: RequestOptions = {}): Promise<Response> {
+  return send('GET', `/time`, {}, opts);
+}
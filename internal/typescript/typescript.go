@@ -0,0 +1,442 @@
+// Package typescript generates a TypeScript client module with a typed function
+// for each route of a router struct. Functions take path params as arguments
+// typed by their bound struct fields, bake in the http method, URL-encode each
+// param and may optionally validate params against the regexp and bounds of
+// their route before sending the request with fetch.
+//
+// Functions for routes serving any method send GET unless the method is set by
+// the fetch options, optional path params become optional arguments which omit
+// their segment and those following it, and regexps using RE2 syntax which
+// JavaScript does not support are left unchecked with a note in the doc comment
+// of the function.
+package typescript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/openapi"
+	"github.com/cstockton/routepiler/internal/source"
+)
+
+// Load parses the non-test Go files within dir and returns the module for the
+// named router struct.
+func Load(dir, router string) ([]byte, error) {
+	doc, err := openapi.Load(dir, router)
+	if err != nil {
+		return nil, err
+	}
+	return Generate(doc)
+}
+
+// Generate returns the module for the operations of an OpenAPI document
+// generated from a router struct. Each operation becomes an exported function
+// named after its operationId, taking the path params in order followed by an
+// object of query params when the route declares any.
+func Generate(doc *openapi.Document) ([]byte, error) {
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, header, doc.Info.Title)
+	names := make(map[string]string)
+	for _, path := range paths {
+		for _, method := range source.Methods {
+			op := doc.Paths[path].Operation(method)
+			if op == nil || len(op.Omits) > 0 {
+				continue // the function of the declared path omits optional params
+			}
+			name := funcName(op.OperationID)
+			if prev, ok := names[name]; ok {
+				return nil, fmt.Errorf(`%v %v: function %v is already declared by %v`,
+					strings.ToUpper(method), path, name, prev)
+			}
+			names[name] = strings.ToUpper(method) + ` ` + path
+			if err := function(&buf, name, method, path, op); err != nil {
+				return nil, fmt.Errorf(`%v %v: %v`, strings.ToUpper(method), path, err)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+const header = `// Code generated by routepiler from %v. DO NOT EDIT.
+
+export interface RequestOptions {
+  /** Prepended to the path of each request, such as https://api.example.com. */
+  baseURL?: string;
+  /** Check params against the regexp and bounds of their route before sending. */
+  validate?: boolean;
+  /** Passed to fetch, the method is set by each function not serving any method. */
+  init?: RequestInit;
+}
+
+/** Thrown when a param fails validation. */
+export class ValidationError extends Error {}
+
+function checkString(name: string, value: string, pattern?: string, min?: number, max?: number): void {
+  if (pattern !== undefined && !new RegExp(pattern).test(value)) {
+    throw new ValidationError(` + "`" + `${name} must match ${pattern}` + "`" + `);
+  }
+  // lengths are counted in UTF-8 bytes like the router counts them
+  const n = new TextEncoder().encode(value).length;
+  if (min !== undefined && n < min) {
+    throw new ValidationError(` + "`" + `${name} must be at least ${min} bytes` + "`" + `);
+  }
+  if (max !== undefined && n > max) {
+    throw new ValidationError(` + "`" + `${name} must be at most ${max} bytes` + "`" + `);
+  }
+}
+
+function checkNumber(name: string, value: number, min?: number, max?: number): void {
+  if (min !== undefined && value < min) {
+    throw new ValidationError(` + "`" + `${name} must be at least ${min}` + "`" + `);
+  }
+  if (max !== undefined && value > max) {
+    throw new ValidationError(` + "`" + `${name} must be at most ${max}` + "`" + `);
+  }
+}
+
+function format(value: unknown): string {
+  return value instanceof Date ? value.toISOString() : String(value);
+}
+
+function encode(value: unknown): string {
+  return encodeURIComponent(format(value));
+}
+
+function encodeWild(value: string): string {
+  return value.split('/').map(encodeURIComponent).join('/');
+}
+
+function send(
+  method: string,
+  path: string,
+  query: Record<string, unknown>,
+  opts: RequestOptions,
+): Promise<Response> {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries(query)) {
+    if (value !== undefined) {
+      params.append(key, format(value));
+    }
+  }
+  const search = params.toString();
+  const url = (opts.baseURL ?? '') + path + (search ? '?' + search : '');
+  return fetch(url, { ...opts.init, method });
+}
+`
+
+func function(buf *bytes.Buffer, name, method, path string, op *openapi.Operation) error {
+	var (
+		args, checks, notes, query []string
+		optional                   []int
+		required                   bool
+	)
+	prms := make(map[string]*openapi.Parameter)
+	for _, p := range op.Parameters {
+		arg := argName(p.Name)
+		if p.In == `path` {
+			prms[p.Name] = p
+			if p.Optional {
+				optional = append(optional, len(args))
+			}
+			args = append(args, arg+`: `+tsType(p.Schema))
+		} else {
+			opt := `?`
+			if p.Required {
+				opt, required = ``, true
+			}
+			query = append(query, p.Name+opt+`: `+tsType(p.Schema))
+			arg = `query.` + p.Name
+		}
+		c, note := check(p, arg)
+		if c != `` {
+			checks = append(checks, c)
+		}
+		if note != `` {
+			notes = append(notes, note)
+		}
+	}
+
+	// optional arguments may not precede the required query object
+	for _, i := range optional {
+		if required {
+			args[i] += ` | undefined`
+		} else {
+			args[i] = strings.Replace(args[i], `: `, `?: `, 1)
+		}
+	}
+
+	if len(query) > 0 {
+		def := ``
+		if !required {
+			def = ` = {}`
+		}
+		args = append(args, `query: { `+strings.Join(query, `; `)+` }`+def)
+	}
+	args = append(args, `opts: RequestOptions = {}`)
+
+	tpl, err := template(path, prms)
+	if err != nil {
+		return err
+	}
+	verb := `'` + strings.ToUpper(method) + `'`
+	if op.Any {
+		method, verb = `any`, `opts.init?.method ?? 'GET'`
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(buf, "\n/**\n * %v %v\n *\n", strings.ToUpper(method), path)
+		for _, note := range notes {
+			fmt.Fprintf(buf, " * %v\n", note)
+		}
+		buf.WriteString(" */\n")
+	} else {
+		fmt.Fprintf(buf, "\n/** %v %v */\n", strings.ToUpper(method), path)
+	}
+	fmt.Fprintf(buf, "export function %v(%v): Promise<Response> {\n",
+		name, strings.Join(args, `, `))
+	if len(checks) > 0 {
+		buf.WriteString("  if (opts.validate) {\n")
+		for _, c := range checks {
+			buf.WriteString(`    ` + c + "\n")
+		}
+		buf.WriteString("  }\n")
+	}
+	q := `{}`
+	if len(query) > 0 {
+		q = `query`
+	}
+	fmt.Fprintf(buf, "  return send(%v, %v, %v, opts);\n}\n", verb, tpl, q)
+	return nil
+}
+
+// template returns a template literal for an OpenAPI path which encodes each
+// path param. The segment of an optional param and those following it are
+// omitted when its argument is undefined.
+func template(path string, prms map[string]*openapi.Parameter) (string, error) {
+	body, err := templateBody(path, prms)
+	if err != nil {
+		return ``, err
+	}
+	return "`" + body + "`", nil
+}
+
+func templateBody(path string, prms map[string]*openapi.Parameter) (string, error) {
+	var buf bytes.Buffer
+	for path != `` {
+		beg := strings.IndexByte(path, '{')
+		if beg < 0 {
+			buf.WriteString(escape(path))
+			break
+		}
+		end := strings.IndexByte(path[beg:], '}')
+		if end < 0 {
+			return ``, fmt.Errorf(`path has an unterminated param`)
+		}
+		end += beg
+
+		name := path[beg+1 : end]
+		p := prms[name]
+		if p == nil {
+			return ``, fmt.Errorf(`path param %v has no parameter`, name)
+		}
+		arg := argName(name)
+		enc := `${encode(` + arg + `)}`
+		if p.Wild {
+			enc = `${encodeWild(` + arg + `)}`
+		}
+		if !p.Optional {
+			buf.WriteString(escape(path[:beg]) + enc)
+			path = path[end+1:]
+			continue
+		}
+
+		seg := strings.LastIndexByte(path[:beg], '/')
+		if seg < 0 {
+			seg = 0
+		}
+		rest, err := templateBody(path[end+1:], prms)
+		if err != nil {
+			return ``, err
+		}
+		buf.WriteString(escape(path[:seg]))
+		fmt.Fprintf(&buf, "${%v === undefined ? '' : `%v%v%v`}",
+			arg, escape(path[seg:beg]), enc, rest)
+		break
+	}
+	return buf.String(), nil
+}
+
+// escape returns s escaped for a template literal.
+func escape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${").Replace(s)
+}
+
+// check returns the statement validating a param, or an empty string when it
+// is unbounded, along with a note when its regexp is left unchecked.
+func check(p *openapi.Parameter, arg string) (stmt, note string) {
+	s := p.Schema
+	if s == nil {
+		return ``, ``
+	}
+
+	var (
+		fn   string
+		args []string
+	)
+	switch tsType(s) {
+	case `string`:
+		fn = `checkString`
+		args = append(args, jsString(s.Pattern), intArg(s.MinLength), intArg(s.MaxLength))
+		if feat := unsupported(s.Pattern); feat != `` {
+			note = fmt.Sprintf(`The pattern of %v is not checked, JavaScript does not support %v.`,
+				p.Name, feat)
+			args[0] = `undefined`
+		} else if s.Pattern == `` {
+			args[0] = `undefined`
+		}
+	case `number`:
+		fn = `checkNumber`
+		args = append(args, int64Arg(s.Minimum), int64Arg(s.Maximum))
+	default:
+		return ``, ``
+	}
+	for len(args) > 0 && args[len(args)-1] == `undefined` {
+		args = args[:len(args)-1]
+	}
+	if len(args) == 0 {
+		return ``, note
+	}
+
+	stmt = fmt.Sprintf(`%v(%v, %v, %v);`,
+		fn, jsString(p.Name), arg, strings.Join(args, `, `))
+	if !p.Required || p.Optional {
+		stmt = fmt.Sprintf(`if (%v !== undefined) %v`, arg, stmt)
+	}
+	return stmt, note
+}
+
+// unsupported returns the first RE2 syntax within a regexp which JavaScript
+// does not support or interprets differently, or an empty string when there is
+// none.
+func unsupported(re string) string {
+	var class bool
+	for i := 0; i < len(re); i++ {
+		rest := re[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1:
+			switch rest[1] {
+			case 'A', 'z', 'Q', 'C', 'p', 'P':
+				return rest[:2]
+			case 'x':
+				if strings.HasPrefix(rest[2:], `{`) {
+					return `\x{`
+				}
+			}
+			i++
+		case class && strings.HasPrefix(rest, `[:`):
+			if end := strings.Index(rest, `:]`); end > 0 {
+				return rest[:end+2]
+			}
+		case class:
+			class = rest[0] != ']'
+		case rest[0] == '[':
+			class = true
+			if strings.HasPrefix(rest, `[^`) {
+				i++
+			}
+			if strings.HasPrefix(re[i+1:], `]`) {
+				i++ // a leading ] is literal
+			}
+		case strings.HasPrefix(rest, `(?P<`):
+			return `(?P<`
+		case strings.HasPrefix(rest, `(?`) && len(rest) > 2 && strings.IndexByte(`imsU-`, rest[2]) >= 0:
+			end := strings.IndexAny(rest, `:)`)
+			if end < 0 {
+				return rest
+			}
+			return rest[:end+1]
+		}
+	}
+	return ``
+}
+
+func intArg(n *int) string {
+	if n == nil {
+		return `undefined`
+	}
+	return fmt.Sprint(*n)
+}
+
+func int64Arg(n *int64) string {
+	if n == nil {
+		return `undefined`
+	}
+	return fmt.Sprint(*n)
+}
+
+// jsString returns s as a single quoted JavaScript string.
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	inner := strings.Replace(string(b[1:len(b)-1]), `\"`, `"`, -1)
+	return `'` + strings.Replace(inner, `'`, `\'`, -1) + `'`
+}
+
+// tsType returns the TypeScript type of a param schema.
+func tsType(s *openapi.Schema) string {
+	switch {
+	case s == nil:
+		return `string`
+	case s.Type == `boolean`:
+		return `boolean`
+	case s.Type == `integer` || s.Type == `number`:
+		return `number`
+	case s.Format == `date-time`:
+		return `Date`
+	}
+	return `string`
+}
+
+// funcName returns the function name for an operationId, such as getOrg for
+// GetOrg.
+func funcName(id string) string {
+	name := source.ExportedName(id)
+	if name == `` {
+		return `request`
+	}
+	return argName(strings.ToLower(name[:1]) + name[1:])
+}
+
+// reserved are the words which may not be used as a TypeScript parameter name.
+var reserved = map[string]bool{
+	`break`: true, `case`: true, `catch`: true, `class`: true, `const`: true,
+	`continue`: true, `debugger`: true, `default`: true, `delete`: true, `do`: true,
+	`else`: true, `enum`: true, `export`: true, `extends`: true, `false`: true,
+	`finally`: true, `for`: true, `function`: true, `if`: true, `import`: true,
+	`in`: true, `instanceof`: true, `new`: true, `null`: true, `return`: true,
+	`super`: true, `switch`: true, `this`: true, `throw`: true, `true`: true,
+	`try`: true, `typeof`: true, `var`: true, `void`: true, `while`: true,
+	`with`: true, `implements`: true, `interface`: true, `let`: true,
+	`package`: true, `private`: true, `protected`: true, `public`: true,
+	`static`: true, `yield`: true, `await`: true,
+
+	// names declared by the module or used by each function
+	`checkString`: true, `checkNumber`: true, `format`: true, `encode`: true,
+	`encodeWild`: true, `send`: true, `query`: true, `opts`: true,
+}
+
+// argName returns the name of the argument for a param, with a trailing
+// underscore when it is a reserved word.
+func argName(name string) string {
+	if reserved[name] {
+		return name + `_`
+	}
+	return name
+}
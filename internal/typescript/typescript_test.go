@@ -0,0 +1,135 @@
+package typescript
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cstockton/routepiler/internal/openapi"
+)
+
+// testDir holds the router shared with the other generators, the golden files
+// generated from it are checked in under testdata.
+const testDir = `../testdata/router`
+
+// TestLoad compares the module generated for the test router with the checked
+// in client.ts, which must be updated along with any change to the output.
+func TestLoad(t *testing.T) {
+	got, err := Load(testDir, `Router`)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	exp, err := ioutil.ReadFile(filepath.Join(`testdata`, `client.ts`))
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	expLines, gotLines := strings.Split(string(exp), "\n"), strings.Split(string(got), "\n")
+	for i := range expLines {
+		if i >= len(gotLines) || expLines[i] != gotLines[i] {
+			t.Fatalf("exp line %d of client.ts to equal generated output:\n%s", i+1, got)
+		}
+	}
+	if len(gotLines) != len(expLines) {
+		t.Fatalf("exp %d lines; got %d lines:\n%s", len(expLines), len(gotLines), got)
+	}
+
+	if _, err := Load(testDir, `Bogus`); err == nil {
+		t.Fatal(`exp non-nil err`)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	tests := []struct {
+		re, exp string
+	}{
+		{`^[a-z]+$`, ``},
+		{`^(?:a|b)(?<c>d)\\A[\]\d]$`, ``},
+		{`[]:alpha:]`, ``},
+		{`\Aa`, `\A`},
+		{`a\z`, `\z`},
+		{`\pL`, `\p`},
+		{`\x{41}`, `\x{`},
+		{`(?i)a`, `(?i)`},
+		{`(?-s:.)`, `(?-s:`},
+		{`(?P<a>b)`, `(?P<`},
+		{`[^[:digit:]]`, `[:digit:]`},
+		{`(?i`, `(?i`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp unsupported(%q) to return %q`, idx, test.re, test.exp)
+		if exp, got := test.exp, unsupported(test.re); exp != got {
+			t.Fatalf(`exp %q; got %q`, exp, got)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	str := &openapi.Schema{Type: `string`}
+	doc := func(path, id string, ps ...*openapi.Parameter) *openapi.Document {
+		return &openapi.Document{Paths: map[string]*openapi.PathItem{
+			path: {Get: &openapi.Operation{OperationID: id, Parameters: ps}},
+		}}
+	}
+	tests := []struct {
+		doc *openapi.Document
+		exp string
+	}{
+		{doc(`/a/{delete}`, `Delete`, &openapi.Parameter{Name: `delete`, In: `path`, Schema: str}),
+			"export function delete_(delete_: string, opts: RequestOptions = {}): " +
+				"Promise<Response> {\n  return send('GET', `/a/${encode(delete_)}`, {}, opts);"},
+		{doc("/a`$\\", `Send`),
+			"export function send_(opts: RequestOptions = {}): Promise<Response> {\n" +
+				"  return send('GET', `/a\\`$\\\\`, {}, opts);"},
+		{doc(`/a`, `A`, &openapi.Parameter{Name: `b`, In: `query`, Required: true,
+			Schema: &openapi.Schema{Type: `string`, Pattern: `^'\`}}),
+			"export function a(query: { b: string }, opts: RequestOptions = {}): " +
+				"Promise<Response> {\n  if (opts.validate) {\n" +
+				"    checkString('b', query.b, '^\\'\\\\');\n  }\n" +
+				"  return send('GET', `/a`, query, opts);"},
+
+		{&openapi.Document{Paths: map[string]*openapi.PathItem{
+			`/a`: {Get: &openapi.Operation{OperationID: `A`, Any: true}},
+		}}, "/** ANY /a */\nexport function a(opts: RequestOptions = {}): Promise<Response> {\n" +
+			"  return send(opts.init?.method ?? 'GET', `/a`, {}, opts);"},
+		{doc(`/a/v{b}/{c}`, `A`,
+			&openapi.Parameter{Name: `b`, In: `path`, Optional: true,
+				Schema: &openapi.Schema{Type: `integer`, Maximum: new(int64)}},
+			&openapi.Parameter{Name: `c`, In: `path`, Optional: true, Schema: str}),
+			"export function a(b?: number, c?: string, opts: RequestOptions = {}): " +
+				"Promise<Response> {\n  if (opts.validate) {\n" +
+				"    if (b !== undefined) checkNumber('b', b, undefined, 0);\n  }\n" +
+				"  return send('GET', `/a${b === undefined ? '' : `/v${encode(b)}" +
+				"${c === undefined ? '' : `/${encode(c)}`}`}`, {}, opts);"},
+		{doc(`/a/{b}`, `A`,
+			&openapi.Parameter{Name: `b`, In: `path`, Optional: true, Schema: str},
+			&openapi.Parameter{Name: `c`, In: `query`, Required: true, Schema: str}),
+			"export function a(b: string | undefined, query: { c: string }, opts: RequestOptions = {})"},
+		{doc(`/a/{b}`, `A`, &openapi.Parameter{Name: `b`, In: `path`, Schema: &openapi.Schema{
+			Type: `string`, Pattern: `^[[:alpha:]]\pL$`}}),
+			"/**\n * GET /a/{b}\n *\n" +
+				" * The pattern of b is not checked, JavaScript does not support [:alpha:].\n */\n" +
+				"export function a(b: string, opts: RequestOptions = {}): Promise<Response> {\n" +
+				"  return send("},
+
+		// errors
+		{doc(`/{a}`, `A`), `GET /{a}: path param a has no parameter`},
+		{doc(`/{a`, `A`), `GET /{a: path has an unterminated param`},
+		{&openapi.Document{Paths: map[string]*openapi.PathItem{
+			`/a`: {Get: &openapi.Operation{OperationID: `A`}},
+			`/b`: {Get: &openapi.Operation{OperationID: `a`}},
+		}}, `GET /b: function a is already declared by GET /a`},
+	}
+	for idx, test := range tests {
+		t.Logf(`test #%.2d - exp generated module to contain %v`, idx, test.exp)
+		b, err := Generate(test.doc)
+		got := string(b)
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.Contains(got, test.exp) {
+			t.Fatalf("exp module to contain:\n%v\ngot:\n%v", test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package vet reports mistakes within the route struct tags of Go source files.
+//
+// The Analyzer type has the shape of a golang.org/x/tools/go/analysis Analyzer
+// without depending on it, adapting it only requires forwarding the fields of
+// the analysis.Pass:
+//
+//	&analysis.Analyzer{
+//		Name: vet.Routes.Name,
+//		Doc:  vet.Routes.Doc,
+//		Run: func(p *analysis.Pass) (interface{}, error) {
+//			return vet.Routes.Run(&vet.Pass{
+//				Fset:  p.Fset,
+//				Files: p.Files,
+//				Report: func(d vet.Diagnostic) {
+//					p.Report(analysis.Diagnostic{
+//						Pos: d.Pos, End: d.End, Message: d.Message})
+//				},
+//			})
+//		},
+//	}
+package vet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/cstockton/routepiler/internal/analyze"
+	"github.com/cstockton/routepiler/internal/parser"
+	"github.com/cstockton/routepiler/internal/source"
+	"github.com/cstockton/routepiler/internal/tag"
+)
+
+// Routes checks that each route tag contains a valid pattern, that a func tag
+// names a handler with a valid signature and that every param of the pattern
+// has a matching struct field.
+var Routes = &Analyzer{
+	Name: `routes`,
+	Doc: `check route struct tags
+
+The routes analyzer scans and parses the pattern within each route tag such as
+get:"/orgs/:org", reporting errors at the position within the tag. The handler
+named by a func tag must exist with a signature beginning with
+(http.ResponseWriter, *http.Request) and returning nothing or an error. When
+the field is a struct type every param must have a field of the same name.`,
+	Run: run,
+}
+
+// Analyzer describes an analysis function in the same form as analysis.Analyzer.
+type Analyzer struct {
+	Name string
+	Doc  string
+	Run  func(*Pass) (interface{}, error)
+}
+
+// Pass provides information to the Run function of an Analyzer for the files of
+// a single package.
+type Pass struct {
+	Fset   *token.FileSet
+	Files  []*ast.File
+	Report func(Diagnostic)
+}
+
+// Diagnostic is a message associated with a source range, End is the position
+// following the last byte of the range or equal to Pos when it is empty.
+type Diagnostic struct {
+	Pos     token.Pos
+	End     token.Pos
+	Message string
+}
+
+// Check runs the Routes analyzer on the given files and returns each diagnostic
+// in order of position.
+func Check(fset *token.FileSet, files []*ast.File) ([]Diagnostic, error) {
+	var out []Diagnostic
+	pass := &Pass{
+		Fset:   fset,
+		Files:  files,
+		Report: func(d Diagnostic) { out = append(out, d) },
+	}
+	if _, err := Routes.Run(pass); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Pos < out[j].Pos })
+	return out, nil
+}
+
+func run(pass *Pass) (interface{}, error) {
+	pkg := source.New(pass.Files)
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if st, ok := n.(*ast.StructType); ok {
+				for _, fd := range st.Fields.List {
+					check(pass, pkg, fd)
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// check reports each mistake within the route tags of a single field.
+func check(pass *Pass, pkg *source.Package, fd *ast.Field) {
+	if fd.Tag == nil {
+		return
+	}
+	str, err := tag.Unquote(fd.Tag.Value)
+	if err != nil {
+		return
+	}
+	ps, err := tag.Parse(str)
+	if err != nil || len(ps.Routes()) == 0 {
+		return // malformed struct tags are reported by go vet
+	}
+
+	typ := pkg.Struct(fd.Type)
+	for _, p := range ps.Routes() {
+		r, err := parser.Parse(p.Value)
+		if err != nil {
+			off, end := 0, 0
+			if perr, ok := err.(*parser.Error); ok {
+				off, end = perr.Off, perr.End
+			}
+			pos, epos := valueRange(fd.Tag, p, off, end)
+			pass.Report(Diagnostic{
+				Pos:     pos,
+				End:     epos,
+				Message: fmt.Sprintf(`invalid %v pattern: %v`, p.Key, err),
+			})
+			continue
+		}
+		if typ == `` {
+			continue
+		}
+
+		for _, prm := range r.Params() {
+			if _, ok := pkg.Field(typ, prm.Name); !ok {
+				off := prm.Pos.Offset()
+				pos, end := valueRange(fd.Tag, p, off, off+nameLen(p.Value[off:], prm.Name))
+				pass.Report(Diagnostic{
+					Pos: pos,
+					End: end,
+					Message: fmt.Sprintf(`param %q of %v pattern has no matching field in %v`,
+						prm.Name, p.Key, typ),
+				})
+			}
+		}
+	}
+
+	p, ok := ps.Lookup(`func`)
+	if !ok {
+		return
+	}
+	h, err := pkg.Handler(fd, ps, ``)
+	if err == nil && h.Func != nil {
+		name := h.Name
+		if typ != `` {
+			name = typ + `.` + name
+		}
+		err = analyze.Signature(name, h.Func.Type)
+	}
+	if err != nil {
+		pos, end := valueRange(fd.Tag, p, 0, len(p.Value))
+		pass.Report(Diagnostic{Pos: pos, End: end, Message: err.Error()})
+	}
+}
+
+// nameLen returns the length of the param declared at the start of s, covering
+// the leading colon or brace when it is followed directly by the name.
+func nameLen(s, name string) int {
+	if len(s) > 0 && (s[0] == ':' || s[0] == '{') && strings.HasPrefix(s[1:], name) {
+		return 1 + len(name)
+	}
+	if strings.HasPrefix(s, name) {
+		return len(name)
+	}
+	return 0
+}
+
+// valueRange returns the positions of the bytes at off and end within the value
+// of the given pair, falling back to the whole tag when the value is not
+// written verbatim within the tag literal.
+func valueRange(lit *ast.BasicLit, p tag.Pair, off, end int) (token.Pos, token.Pos) {
+	voff := tag.ValueOffset(lit.Value, p)
+	if voff < 0 {
+		return lit.Pos(), lit.End()
+	}
+	return lit.Pos() + token.Pos(voff+off), lit.Pos() + token.Pos(voff+end)
+}
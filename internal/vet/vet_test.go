@@ -0,0 +1,94 @@
+package vet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const testSrc = "package main\n" + `
+import "net/http"
+
+type Router struct {
+	Root  http.Handler                                  ` + "`get:\"/\"`" + `
+	Date  func(http.ResponseWriter, *http.Request)       ` + "`path:\"/date\"`" + `
+	Time  http.Handler                                  ` + "`path:\"/time\" func:\"handleTime\"`" + `
+	Echo  http.Handler                                  ` + "`get:\"/echo\" func:\"Echo\"`" + `
+	Org   Orgs                                          ` + "`get:\"/orgs/:org\" func:\"GetOrg\"`" + `
+	User  Users                                         ` + "`get:\"/orgs/:org/users/:user\" func:\"GetUser\"`" + `
+	Bad   http.Handler                                  ` + "`get:\"/:a:bb\"`" + `
+	Team  Orgs                                          ` + "`get:\"/orgs/:org/teams/{team}\"`" + `
+	Query Users                                         ` + "`get:\"/users?since&page\"`" + `
+	Miss  http.Handler                                  ` + "`get:\"/miss\" func:\"missing\"`" + `
+	Meth  Orgs                                          ` + "`get:\"/orgs\" func:\"Missing\"`" + `
+	Sig   Users                                         ` + "`get:\"/users\" func:\"Notify\"`" + `
+	Esc   Orgs                                          ` + "\"get:\\\"/:a:bb\\\"\"" + `
+	Other string                                        ` + "`json:\"other\"`" + `
+}
+
+var handleTime = http.NotFoundHandler()
+
+func Echo(w http.ResponseWriter, r *http.Request) error { return nil }
+
+type Orgs struct {
+	Org string
+}
+
+func (h *Orgs) GetOrg(w http.ResponseWriter, r *http.Request) {}
+
+type Users struct {
+	*Orgs
+	User  string
+	Since string
+}
+
+func (h *Users) GetUser(w http.ResponseWriter, r *http.Request, o *Orgs) error {
+	return nil
+}
+
+func (h Users) Notify(r *http.Request) (int, error) { return 0, nil }
+`
+
+func TestCheck(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, `router.go`, testSrc, 0)
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+	diags, err := Check(fset, []*ast.File{f})
+	if err != nil {
+		t.Fatalf(`exp nil err; got %v`, err)
+	}
+
+	exp := []string{
+		`router.go:12:63-64: invalid get pattern: adjacent COLON at byte 3, ` +
+			`params within a path segment must be separated by a literal`,
+		`router.go:13:77-82: param "team" of get pattern has no matching field in Orgs`,
+		`router.go:14:73-77: param "page" of get pattern has no matching field in Users`,
+		`router.go:15:73-80: func tag names missing which is not a func or var`,
+		`router.go:16:73-80: func tag names Missing which is not a method of Orgs`,
+		`router.go:17:74-80: handler Users.Notify has signature ` +
+			`func(*http.Request) (int, error), want func(http.ResponseWriter, ` +
+			`*http.Request, ...) with no result or error`,
+
+		// escaped tags are reported over the whole tag
+		`router.go:18:54-70: invalid get pattern: adjacent COLON at byte 3, ` +
+			`params within a path segment must be separated by a literal`,
+	}
+	if len(exp) != len(diags) {
+		for _, d := range diags {
+			t.Logf(`got %v: %v`, fset.Position(d.Pos), d.Message)
+		}
+		t.Fatalf(`exp %d diagnostics; got %d`, len(exp), len(diags))
+	}
+	for idx, d := range diags {
+		t.Logf(`test #%.2d - exp diagnostic %q`, idx, exp[idx])
+		got := fmt.Sprintf(`%v-%v: %v`,
+			fset.Position(d.Pos), fset.Position(d.End).Column, d.Message)
+		if exp[idx] != got {
+			t.Fatalf("unexpected diagnostic:\nexp: %v\ngot: %v\n", exp[idx], got)
+		}
+	}
+}